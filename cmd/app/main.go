@@ -1,10 +1,13 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"os"
 
 	"github.com/idoyudha/eshop-order/config"
 	"github.com/idoyudha/eshop-order/internal/app"
+	"github.com/idoyudha/eshop-order/internal/entity"
 )
 
 func main() {
@@ -13,5 +16,16 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// app rebuild-projection [-shadow] [-batch n]
+	if len(os.Args) > 1 && os.Args[1] == "rebuild-projection" {
+		fs := flag.NewFlagSet("rebuild-projection", flag.ExitOnError)
+		shadow := fs.Bool("shadow", false, "build into shadow tables and swap them in when done")
+		batch := fs.Int("batch", entity.REBUILD_DEFAULT_BATCH_SIZE, "orders per batch")
+		fs.Parse(os.Args[2:])
+
+		app.RebuildProjection(cfg, *shadow, *batch)
+		return
+	}
+
 	app.Run(cfg)
 }
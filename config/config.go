@@ -1,6 +1,10 @@
 package config
 
-import "github.com/ilyakaznacheev/cleanenv"
+import (
+	"errors"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
 
 type (
 	Config struct {
@@ -16,6 +20,9 @@ type (
 		Kafka
 		Redis
 		Constant
+		Tax               `yaml:"tax"`
+		Shipping          `yaml:"shipping"`
+		ShippingRateTable `yaml:"-"`
 	}
 
 	App struct {
@@ -62,7 +69,10 @@ type (
 	}
 
 	Kafka struct {
-		Broker string `env-required:"true" env:"KAFKA_BROKER"`
+		Broker        string `env-required:"true" env:"KAFKA_BROKER"`
+		DeliveryTopic string `env-required:"true" env:"KAFKA_DELIVERY_TOPIC"`
+		// how long a synchronous publish waits for the broker acknowledgement
+		DeliveryTimeoutMs int `env-required:"true" env:"KAFKA_DELIVERY_TIMEOUT_MS"`
 	}
 
 	Redis struct {
@@ -73,7 +83,76 @@ type (
 	}
 
 	Constant struct {
-		OrderTimeHours int `env-required:"true" env:"ORDER_TIME_HOURS"`
+		OrderTimeHours          int    `env-required:"true" env:"ORDER_TIME_HOURS"`
+		MaxDeliveryAttempts     int    `env-required:"true" env:"MAX_DELIVERY_ATTEMPTS"`
+		AutoConfirmDeliveryDays int    `env-required:"true" env:"AUTO_CONFIRM_DELIVERY_DAYS"`
+		DeliveryReminderDays    int    `env-required:"true" env:"DELIVERY_REMINDER_DAYS"`
+		ReturnWindowDays        int    `env-required:"true" env:"RETURN_WINDOW_DAYS"`
+		SaleReportRetryMinutes  int    `env-required:"true" env:"SALE_REPORT_RETRY_MINUTES"`
+		Currency                string `env-required:"true" env:"CURRENCY"`
+		ShippingCacheMinutes    int    `env-required:"true" env:"SHIPPING_CACHE_MINUTES"`
+		OrderCacheMinutes       int    `env-required:"true" env:"ORDER_CACHE_MINUTES"`
+		// how long a read with a projection token waits for the read model before using the command database
+		ProjectionWaitMillis int `env-required:"true" env:"PROJECTION_WAIT_MILLIS"`
+		// how often the order views are compared with the orders, 0 turns it off,
+		// and how differences are repaired: none, patch or events
+		ReconcileIntervalMinutes int    `env-required:"true" env:"RECONCILE_INTERVAL_MINUTES"`
+		ReconcileRepair          string `env-required:"true" env:"RECONCILE_REPAIR"`
+		// how often the parked order events whose order view exists by now are replayed
+		ParkedEventReplayMinutes int `env-required:"true" env:"PARKED_EVENT_REPLAY_MINUTES"`
+	}
+
+	// Tax rate table, the most specific matching rule is used, empty field matches anything
+	Tax struct {
+		DefaultRate float64   `yaml:"default_rate"`
+		Rules       []TaxRule `yaml:"rules"`
+	}
+
+	TaxRule struct {
+		State      string  `yaml:"state"`
+		ZipPrefix  string  `yaml:"zip_prefix"`
+		CategoryID string  `yaml:"category_id"`
+		Rate       float64 `yaml:"rate"`
+	}
+
+	// Shipping methods offered to the customer, the cost is the base shipping cost times the multiplier
+	Shipping struct {
+		Methods []ShippingMethod `yaml:"methods"`
+		// shipping cost service call taking longer than this falls back to the rate table
+		RemoteTimeoutMs int `yaml:"remote_timeout_ms"`
+		// evaluated in order after the rate lookup, the first matching rule is applied
+		Rules []ShippingRule `yaml:"rules"`
+	}
+
+	ShippingMethod struct {
+		Method        string  `yaml:"method"`
+		Multiplier    float64 `yaml:"multiplier"`
+		EstimatedDays int     `yaml:"estimated_days"`
+	}
+
+	// ShippingRule waive, cap or override the shipping cost, empty condition matches anything.
+	// rule with category only changes the shipping of the items in the category
+	ShippingRule struct {
+		Name        string  `yaml:"name"`
+		Action      string  `yaml:"action"`
+		Amount      float64 `yaml:"amount"`
+		MinSubtotal float64 `yaml:"min_subtotal"`
+		State       string  `yaml:"state"`
+		ZipPrefix   string  `yaml:"zip_prefix"`
+		CategoryID  string  `yaml:"category_id"`
+	}
+
+	// ShippingRateTable is the local base shipping cost used when the shipping cost service is down,
+	// loaded from shipping_rates.yml
+	ShippingRateTable struct {
+		DefaultCost float64        `yaml:"default_cost"`
+		Zones       []ShippingZone `yaml:"zones"`
+	}
+
+	ShippingZone struct {
+		Name      string  `yaml:"name"`
+		ZipPrefix string  `yaml:"zip_prefix"`
+		BaseCost  float64 `yaml:"base_cost"`
 	}
 )
 
@@ -85,10 +164,40 @@ func NewConfig() (*Config, error) {
 		return nil, err
 	}
 
+	err = cleanenv.ReadConfig("./config/shipping_rates.yml", &cfg.ShippingRateTable)
+	if err != nil {
+		return nil, err
+	}
+
 	err = cleanenv.ReadEnv(cfg)
 	if err != nil {
 		return nil, err
 	}
 
+	err = cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
+
+// validate the settings that depend on each other
+func (c *Config) validate() error {
+	// the reminder is sent the given days before the auto confirm, it has to be after the pick up
+	if c.Constant.DeliveryReminderDays >= c.Constant.AutoConfirmDeliveryDays {
+		return errors.New("DELIVERY_REMINDER_DAYS must be less than AUTO_CONFIRM_DELIVERY_DAYS")
+	}
+	if c.Kafka.DeliveryTimeoutMs <= 0 {
+		return errors.New("KAFKA_DELIVERY_TIMEOUT_MS must be positive")
+	}
+	if c.Constant.ReconcileIntervalMinutes < 0 {
+		return errors.New("RECONCILE_INTERVAL_MINUTES must not be negative")
+	}
+	// parked events are only replayed by the sweep once their replay failed, it cannot be turned off
+	if c.Constant.ParkedEventReplayMinutes <= 0 {
+		return errors.New("PARKED_EVENT_REPLAY_MINUTES must be positive")
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/idoyudha/eshop-order/config"
@@ -13,6 +14,8 @@ import (
 	"github.com/idoyudha/eshop-order/internal/usecase"
 	"github.com/idoyudha/eshop-order/internal/usecase/commandrepo"
 	"github.com/idoyudha/eshop-order/internal/usecase/queryrepo"
+	"github.com/idoyudha/eshop-order/internal/usecase/shipping"
+	"github.com/idoyudha/eshop-order/internal/usecase/tax"
 	"github.com/idoyudha/eshop-order/pkg/httpserver"
 	"github.com/idoyudha/eshop-order/pkg/kafka"
 	"github.com/idoyudha/eshop-order/pkg/logger"
@@ -51,29 +54,97 @@ func Run(cfg *config.Config) {
 		l.Fatal("app - Run - redis.NewRedis: ", err)
 	}
 
+	refundCommandUseCase := usecase.NewRefundCommandUseCase(
+		commandrepo.NewRefundPostgreCommandRepo(postgreSQLCommand),
+		commandrepo.NewOrderPostgreCommandRepo(postgreSQLCommand),
+		kafkaProducer,
+	)
+
 	orderCommandUseCase := usecase.NewOrderCommandUseCase(
 		commandrepo.NewOrderPostgreCommandRepo(postgreSQLCommand),
 		queryrepo.NewOrderPostgreQueryRepo(postgreSQLQuery),
 		commandrepo.NewOrderRedisRepo(redisClient),
+		commandrepo.NewPromotionPostgreCommandRepo(postgreSQLCommand),
+		refundCommandUseCase,
+		tax.NewTableTaxCalculator(cfg.Tax),
 		kafkaProducer,
 		cfg.WarehouseService,
-		cfg.ShippingCostService,
+		cfg.ProductService,
+		shipping.NewCachedRateProvider(
+			shipping.NewFallbackRateProvider(
+				shipping.NewRemoteRateProvider(cfg.ShippingCostService, cfg.Shipping),
+				shipping.NewTableRateProvider(cfg.ShippingRateTable, cfg.Shipping),
+				cfg.Shipping.RemoteTimeoutMs,
+				l,
+			),
+			commandrepo.NewShippingRedisRepo(redisClient),
+			cfg.Shipping,
+			cfg.Constant.ShippingCacheMinutes,
+			l,
+		),
+		shipping.NewRuleEngine(cfg.Shipping),
+		cfg.Shipping,
 		cfg.Constant,
 	)
 
 	orderQueryUseCase := usecase.NewOrderQueryUseCase(
 		queryrepo.NewOrderPostgreQueryRepo(postgreSQLQuery),
+		queryrepo.NewOrderRedisQueryRepo(redisClient),
+		cfg.Constant,
+	)
+
+	refundQueryUseCase := usecase.NewRefundQueryUseCase(
+		queryrepo.NewRefundPostgreQueryRepo(postgreSQLQuery),
+	)
+
+	returnCommandUseCase := usecase.NewReturnCommandUseCase(
+		commandrepo.NewReturnPostgreCommandRepo(postgreSQLCommand),
+		commandrepo.NewOrderPostgreCommandRepo(postgreSQLCommand),
+		refundCommandUseCase,
+		kafkaProducer,
+		cfg.WarehouseService,
+		cfg.Constant,
+	)
+
+	returnQueryUseCase := usecase.NewReturnQueryUseCase(
+		queryrepo.NewReturnPostgreQueryRepo(postgreSQLQuery),
+	)
+
+	promotionCommandUseCase := usecase.NewPromotionCommandUseCase(
+		commandrepo.NewPromotionPostgreCommandRepo(postgreSQLCommand),
+	)
+
+	shippingCommandUseCase := usecase.NewShippingCommandUseCase(
+		commandrepo.NewShippingRedisRepo(redisClient),
+	)
+
+	projectionRebuildUseCase := usecase.NewProjectionRebuildUseCase(
+		commandrepo.NewOrderPostgreCommandRepo(postgreSQLCommand),
+		queryrepo.NewOrderRebuildQueryRepo(postgreSQLQuery),
+		queryrepo.NewOrderRedisQueryRepo(redisClient),
+		cfg.ProductService,
+		l,
+	)
+
+	projectionReconcileUseCase := usecase.NewProjectionReconcileUseCase(
+		commandrepo.NewOrderPostgreCommandRepo(postgreSQLCommand),
+		queryrepo.NewOrderRebuildQueryRepo(postgreSQLQuery),
+		queryrepo.NewOrderRedisQueryRepo(redisClient),
+		projectionRebuildUseCase,
+		kafkaProducer,
+		cfg.Constant,
+		l,
 	)
 
 	// HTTP Server
 	handler := gin.Default()
-	v1HTTP.NewRouter(handler, orderQueryUseCase, orderCommandUseCase, l, cfg.AuthService)
+	v1HTTP.NewRouter(handler, orderQueryUseCase, orderCommandUseCase, returnQueryUseCase, returnCommandUseCase, refundQueryUseCase, refundCommandUseCase, promotionCommandUseCase, shippingCommandUseCase, projectionRebuildUseCase, projectionReconcileUseCase, l, cfg.AuthService)
 	httpServer := httpserver.New(handler, httpserver.Port(cfg.HTTP.Port))
 
 	// Kafka Consumer
 	kafkaErrChan := make(chan error, 1)
 	go func() {
-		if err := kafkaEvent.KafkaNewRouter(orderQueryUseCase, orderCommandUseCase, l, kafkaConsumer, cfg.ProductService); err != nil {
+		if err := kafkaEvent.KafkaNewRouter(orderQueryUseCase, orderCommandUseCase, returnQueryUseCase, refundQueryUseCase, l, kafkaConsumer, cfg.ProductService, cfg.Kafka); err != nil {
 			kafkaErrChan <- err
 		}
 	}()
@@ -86,6 +157,14 @@ func Run(cfg *config.Config) {
 		}
 	}()
 
+	// Order view reconcile, 0 interval turns it off
+	if cfg.Constant.ReconcileIntervalMinutes > 0 {
+		go runOrderViewReconcile(projectionReconcileUseCase, time.Duration(cfg.Constant.ReconcileIntervalMinutes)*time.Minute, l)
+	}
+
+	// Parked order event replay
+	go runParkedEventReplay(orderQueryUseCase, time.Duration(cfg.Constant.ParkedEventReplayMinutes)*time.Minute, l)
+
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
 
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"context"
+
+	"github.com/idoyudha/eshop-order/config"
+	"github.com/idoyudha/eshop-order/internal/usecase"
+	"github.com/idoyudha/eshop-order/internal/usecase/commandrepo"
+	"github.com/idoyudha/eshop-order/internal/usecase/queryrepo"
+	"github.com/idoyudha/eshop-order/pkg/logger"
+	"github.com/idoyudha/eshop-order/pkg/postgresql/postgrecommand"
+	"github.com/idoyudha/eshop-order/pkg/postgresql/postgrequery"
+	"github.com/idoyudha/eshop-order/pkg/redis"
+)
+
+// RebuildProjection repopulate the order views from the command database and exit,
+// it does not start the http server or the consumers
+func RebuildProjection(cfg *config.Config, shadow bool, batchSize int) {
+	l := logger.New(cfg.Log.Level)
+
+	postgreSQLCommand, err := postgrecommand.NewPostgres(cfg.PostgreSQLCommand)
+	if err != nil {
+		l.Fatal("app - RebuildProjection - postgrecommand.NewPostgres: ", err)
+	}
+
+	postgreSQLQuery, err := postgrequery.NewPostgres(cfg.PostgreSQLQuery)
+	if err != nil {
+		l.Fatal("app - RebuildProjection - postgrequery.NewPostgres: ", err)
+	}
+
+	redisClient, err := redis.NewRedis(cfg.Redis)
+	if err != nil {
+		l.Fatal("app - RebuildProjection - redis.NewRedis: ", err)
+	}
+
+	projectionRebuildUseCase := usecase.NewProjectionRebuildUseCase(
+		commandrepo.NewOrderPostgreCommandRepo(postgreSQLCommand),
+		queryrepo.NewOrderRebuildQueryRepo(postgreSQLQuery),
+		queryrepo.NewOrderRedisQueryRepo(redisClient),
+		cfg.ProductService,
+		l,
+	)
+
+	rebuild, err := projectionRebuildUseCase.RebuildOrderViews(context.Background(), shadow, batchSize)
+	if err != nil {
+		l.Fatal("app - RebuildProjection - RebuildOrderViews: ", err)
+	}
+
+	l.Info("app - RebuildProjection - rebuild %s done: %d orders, %d skipped", rebuild.ID, rebuild.Processed, rebuild.Skipped)
+}
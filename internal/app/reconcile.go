@@ -0,0 +1,38 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/idoyudha/eshop-order/internal/usecase"
+	"github.com/idoyudha/eshop-order/pkg/logger"
+)
+
+// runOrderViewReconcile compare the order views with the orders on every interval,
+// a run is skipped while another one is going on this or another instance
+func runOrderViewReconcile(u usecase.ProjectionReconcile, interval time.Duration, l logger.Interface) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		_, err := u.ReconcileOrderViews(context.Background(), "")
+		if err != nil && !errors.Is(err, usecase.ErrConflict) {
+			l.Error("app - runOrderViewReconcile - ReconcileOrderViews: ", err)
+		}
+	}
+}
+
+// runParkedEventReplay sweep the parked events left behind by a failed replay on every interval,
+// it runs whether the reconcile is turned on or not
+func runParkedEventReplay(oq usecase.OrderQuery, interval time.Duration, l logger.Interface) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		_, err := oq.ReplayParkedOrderEvents(context.Background())
+		if err != nil {
+			l.Error("app - runParkedEventReplay - ReplayParkedOrderEvents: ", err)
+		}
+	}
+}
@@ -1,8 +1,26 @@
 package constant
 
 const (
-	OrderCreatedTopic       = "order-created"
-	OrderStatusUpdatedTopic = "order-status-updated"
-	PaymentUpdatedTopic     = "payment-updated"
-	SaleCreated             = "sale-created"
+	OrderCreatedTopic        = "order-created"
+	OrderStatusUpdatedTopic  = "order-status-updated"
+	PaymentUpdatedTopic      = "payment-updated"
+	SaleCreated              = "sale-created"
+	DeliveryReminderTopic    = "order-delivery-reminder"
+	ReturnRequestedTopic     = "return-requested"
+	ReturnApprovedTopic      = "return-approved"
+	ReturnRejectedTopic      = "return-rejected"
+	RefundCreatedTopic       = "refund-created"
+	SaleReversed             = "sale-reversed"
+	OrderItemsCancelledTopic = "order-items-cancelled"
+	OrderUpdatedTopic        = "order-updated"
+	OrderReadyForPickupTopic = "order-ready-for-pickup"
+	OrderPickedUpTopic       = "order-picked-up"
+	DigitalFulfilmentTopic   = "digital-fulfilment-requested"
+)
+
+// order events published again by the reconciler, only the projection of this service consumes them
+const (
+	OrderCreatedRepairTopic       = "order-created-repair"
+	OrderStatusUpdatedRepairTopic = "order-status-updated-repair"
+	OrderUpdatedRepairTopic       = "order-updated-repair"
 )
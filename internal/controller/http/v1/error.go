@@ -1,7 +1,11 @@
 package v1
 
 import (
+	"errors"
 	"net/http"
+
+	"github.com/idoyudha/eshop-order/internal/entity"
+	"github.com/idoyudha/eshop-order/internal/usecase"
 )
 
 type restError struct {
@@ -49,3 +53,36 @@ func newUnauthorizedError(message string) *restError {
 		},
 	}
 }
+
+func newForbiddenError(message string) *restError {
+	return &restError{
+		Code: http.StatusForbidden,
+		Error: errorMessage{
+			Message: message,
+		},
+	}
+}
+
+func newConflictError(message string) *restError {
+	return &restError{
+		Code: http.StatusConflict,
+		Error: errorMessage{
+			Message: message,
+		},
+	}
+}
+
+// newUseCaseError map the usecase sentinel errors to the rest error, unknown errors are internal
+func newUseCaseError(err error) *restError {
+	switch {
+	case errors.Is(err, usecase.ErrNotFound):
+		return newNotFoundError(err.Error())
+	case errors.Is(err, usecase.ErrValidation), errors.Is(err, entity.ErrInvalidPageCursor),
+		errors.Is(err, entity.ErrInvalidProjectionToken):
+		return newBadRequestError(err.Error())
+	case errors.Is(err, usecase.ErrConflict):
+		return newConflictError(err.Error())
+	default:
+		return newInternalServerError(err.Error())
+	}
+}
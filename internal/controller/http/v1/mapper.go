@@ -19,6 +19,7 @@ func CreateOrderRequestToOrderEntity(req createOrderRequest, userID uuid.UUID) (
 			OrderID:         orderID,
 			ProductID:       item.ProductID,
 			ProductQuantity: item.Quantity,
+			Price:           item.Price,
 			CreatedAt:       time.Now(),
 			UpdatedAt:       time.Now(),
 		})
@@ -26,11 +27,15 @@ func CreateOrderRequestToOrderEntity(req createOrderRequest, userID uuid.UUID) (
 	}
 
 	return entity.Order{
-		ID:         orderID,
-		UserID:     userID,
-		TotalPrice: totalPice,
-		PaymentID:  uuid.UUID{},
-		Items:      items,
+		ID:                orderID,
+		UserID:            userID,
+		TotalPrice:        totalPice,
+		PromotionCode:     req.PromotionCode,
+		ShippingMethod:    shippingMethodOrDefault(req.ShippingMethod),
+		FulfilmentType:    fulfilmentTypeOrDefault(req.FulfilmentType),
+		PickupWarehouseID: req.WarehouseID,
+		PaymentID:         uuid.UUID{},
+		Items:             items,
 		Address: entity.OrderAddress{
 			OrderID:   orderID,
 			Street:    req.Address.Street,
@@ -46,6 +51,62 @@ func CreateOrderRequestToOrderEntity(req createOrderRequest, userID uuid.UUID) (
 	}, nil
 }
 
+func shippingMethodOrDefault(method string) string {
+	if method == "" {
+		return entity.SHIPPING_STANDARD
+	}
+	return method
+}
+
+func fulfilmentTypeOrDefault(fulfilmentType string) string {
+	if fulfilmentType == "" {
+		return entity.FULFILMENT_DELIVERY
+	}
+	return fulfilmentType
+}
+
+func ConfirmPickupRequestToOrderEntity(req confirmPickupRequest, orderID uuid.UUID) entity.Order {
+	return entity.Order{
+		ID:         orderID,
+		PickupCode: req.PickupCode,
+		UpdatedAt:  time.Now(),
+	}
+}
+
+func ShippingQuoteRequestToOrderEntity(req shippingQuoteRequest) entity.Order {
+	var items []entity.OrderItem
+	for _, item := range req.Items {
+		items = append(items, entity.OrderItem{
+			ProductID:       item.ProductID,
+			ProductQuantity: item.Quantity,
+			Price:           item.Price,
+		})
+	}
+
+	return entity.Order{
+		Items: items,
+		Address: entity.OrderAddress{
+			Street:  req.Address.Street,
+			City:    req.Address.City,
+			State:   req.Address.State,
+			ZipCode: req.Address.ZipCode,
+		},
+	}
+}
+
+func ShippingRateEntityToShippingQuoteResponse(rates []entity.ShippingRate) []shippingQuoteResponse {
+	var res []shippingQuoteResponse
+	for _, rate := range rates {
+		res = append(res, shippingQuoteResponse{
+			Method:        rate.Method,
+			Cost:          rate.Cost,
+			EstimatedDays: rate.EstimatedDays,
+			Estimated:     rate.Estimated,
+		})
+	}
+	return res
+}
+
 func UpdateOrderRequestToOrderEntity(orderID uuid.UUID) entity.Order {
 	return entity.Order{
 		ID:        orderID,
@@ -53,6 +114,54 @@ func UpdateOrderRequestToOrderEntity(orderID uuid.UUID) entity.Order {
 	}
 }
 
+func CancelOrderItemsRequestToOrderEntity(req cancelOrderItemsRequest, orderID, userID uuid.UUID) entity.Order {
+	var items []entity.OrderItem
+	for _, item := range req.Items {
+		items = append(items, entity.OrderItem{
+			OrderID:         orderID,
+			ProductID:       item.ProductID,
+			ProductQuantity: item.Quantity,
+		})
+	}
+
+	return entity.Order{
+		ID:        orderID,
+		UserID:    userID,
+		Items:     items,
+		UpdatedAt: time.Now(),
+	}
+}
+
+func EditOrderRequestToOrderEntity(req updateOrderRequest, orderID, userID uuid.UUID) entity.Order {
+	var items []entity.OrderItem
+	for _, item := range req.Items {
+		items = append(items, entity.OrderItem{
+			OrderID:         orderID,
+			ProductID:       item.ProductID,
+			ProductQuantity: item.Quantity,
+		})
+	}
+
+	order := entity.Order{
+		ID:        orderID,
+		UserID:    userID,
+		Items:     items,
+		UpdatedAt: time.Now(),
+	}
+	if req.Address != nil {
+		order.Address = entity.OrderAddress{
+			Street:    req.Address.Street,
+			City:      req.Address.City,
+			State:     req.Address.State,
+			ZipCode:   req.Address.ZipCode,
+			Note:      req.Address.Note,
+			UpdatedAt: order.UpdatedAt,
+		}
+	}
+
+	return order
+}
+
 func OrderEntityToCreatedOrderResponse(order entity.Order) orderResponse {
 	var items []itemsOrderResponse
 	for _, item := range order.Items {
@@ -61,14 +170,29 @@ func OrderEntityToCreatedOrderResponse(order entity.Order) orderResponse {
 			ProductID:    item.ProductID,
 			Quantity:     item.ProductQuantity,
 			ShippingCost: item.ShippingCost,
+			Discount:     item.TotalDiscount(),
+			Tax:          item.Tax,
 			Note:         item.Note,
 		})
 	}
 
 	return orderResponse{
-		Status:     order.Status,
-		TotalPrice: order.TotalPrice,
-		Items:      items,
+		ID:                    order.ID,
+		Status:                order.Status,
+		TotalPrice:            order.TotalPrice,
+		PromotionCode:         order.PromotionCode,
+		DiscountAmount:        order.DiscountAmount,
+		TaxAmount:             order.TaxAmount,
+		ShippingMethod:        order.ShippingMethod,
+		ShippingEstimatedDays: order.ShippingEstimatedDays,
+		EstimatedShipping:     order.EstimatedShipping,
+		ShippingRule:          order.ShippingRule,
+		FulfilmentType:        order.FulfilmentType,
+		PickupWarehouseID:     order.PickupWarehouseID,
+		PickupCode:            order.PickupCode,
+		PaymentID:             order.PaymentID,
+		ProjectionToken:       order.ProjectionToken().Encode(),
+		Items:                 items,
 		Address: addressOrderResponse{
 			OrderID: order.Address.OrderID,
 			Street:  order.Address.Street,
@@ -77,9 +201,50 @@ func OrderEntityToCreatedOrderResponse(order entity.Order) orderResponse {
 			ZipCode: order.Address.ZipCode,
 			Note:    order.Address.Note,
 		},
+		CreatedAt: order.CreatedAt,
 	}
 }
 
+func PageRequestToPageEntity(req pageRequest) (entity.Page, error) {
+	after, err := entity.DecodePageCursor(req.Cursor)
+	if err != nil {
+		return entity.Page{}, err
+	}
+	return entity.NewPage(req.Limit, after), nil
+}
+
+func OrderFilterRequestToOrderFilterEntity(req orderFilterRequest) (entity.OrderFilter, error) {
+	filter := entity.OrderFilter{
+		Statuses:      req.Status,
+		CreatedFrom:   req.CreatedFrom,
+		CreatedTo:     req.CreatedTo,
+		UpdatedFrom:   req.UpdatedFrom,
+		UpdatedTo:     req.UpdatedTo,
+		MinTotalPrice: req.MinTotalPrice,
+		MaxTotalPrice: req.MaxTotalPrice,
+		PaymentStatus: req.PaymentStatus,
+		State:         req.State,
+		City:          req.City,
+		SortBy:        req.Sort,
+		SortAsc:       req.Order == "asc",
+	}
+	if req.UserID != "" {
+		userID, err := uuid.Parse(req.UserID)
+		if err != nil {
+			return entity.OrderFilter{}, err
+		}
+		filter.UserID = userID
+	}
+	if req.ProductID != "" {
+		productID, err := uuid.Parse(req.ProductID)
+		if err != nil {
+			return entity.OrderFilter{}, err
+		}
+		filter.ProductID = productID
+	}
+	return filter, nil
+}
+
 func OrderViewEntityToGetManyOrderResponse(orders []*entity.OrderView) []orderResponse {
 	var res []orderResponse
 	for _, order := range orders {
@@ -93,18 +258,30 @@ func OrderViewEntityToGetManyOrderResponse(orders []*entity.OrderView) []orderRe
 				Price:        item.ProductPrice,
 				Quantity:     item.ProductQuantity,
 				ShippingCost: item.ShippingCost,
+				Discount:     item.Discount,
+				Tax:          item.Tax,
 				Note:         item.Note,
 			})
 		}
 
 		res = append(res, orderResponse{
-			ID:              order.OrderID,
-			Status:          order.Status,
-			TotalPrice:      order.TotalPrice,
-			PaymentID:       order.PaymentID,
-			PaymentStatus:   order.PaymentStatus,
-			PaymentImageURL: order.PaymentImageURL,
-			Items:           items,
+			ID:                    order.OrderID,
+			Status:                order.Status,
+			TotalPrice:            order.TotalPrice,
+			PromotionCode:         order.PromotionCode,
+			DiscountAmount:        order.DiscountAmount,
+			TaxAmount:             order.TaxAmount,
+			ShippingMethod:        order.ShippingMethod,
+			ShippingEstimatedDays: order.ShippingEstimatedDays,
+			EstimatedShipping:     order.EstimatedShipping,
+			ShippingRule:          order.ShippingRule,
+			FulfilmentType:        order.FulfilmentType,
+			PickupWarehouseID:     order.PickupWarehouseID,
+			PickupCode:            order.PickupCode,
+			PaymentID:             order.PaymentID,
+			PaymentStatus:         order.PaymentStatus,
+			PaymentImageURL:       order.PaymentImageURL,
+			Items:                 items,
 			Address: addressOrderResponse{
 				OrderID: order.Address.OrderViewID,
 				Street:  order.Address.Street,
@@ -113,6 +290,7 @@ func OrderViewEntityToGetManyOrderResponse(orders []*entity.OrderView) []orderRe
 				ZipCode: order.Address.ZipCode,
 				Note:    order.Address.Note,
 			},
+			Timeline:  OrderTimelineViewToTimelineResponse(order.Timeline),
 			CreatedAt: order.CreatedAt,
 		})
 	}
@@ -130,18 +308,30 @@ func OrderViewEntityToGetOneOrderResponse(order *entity.OrderView) orderResponse
 			Price:        item.ProductPrice,
 			Quantity:     item.ProductQuantity,
 			ShippingCost: item.ShippingCost,
+			Discount:     item.Discount,
+			Tax:          item.Tax,
 			Note:         item.Note,
 		})
 	}
 
 	return orderResponse{
-		ID:              order.OrderID,
-		Status:          order.Status,
-		TotalPrice:      order.TotalPrice,
-		PaymentID:       order.PaymentID,
-		PaymentStatus:   order.PaymentStatus,
-		PaymentImageURL: order.PaymentImageURL,
-		Items:           items,
+		ID:                    order.OrderID,
+		Status:                order.Status,
+		TotalPrice:            order.TotalPrice,
+		PromotionCode:         order.PromotionCode,
+		DiscountAmount:        order.DiscountAmount,
+		TaxAmount:             order.TaxAmount,
+		ShippingMethod:        order.ShippingMethod,
+		ShippingEstimatedDays: order.ShippingEstimatedDays,
+		EstimatedShipping:     order.EstimatedShipping,
+		ShippingRule:          order.ShippingRule,
+		FulfilmentType:        order.FulfilmentType,
+		PickupWarehouseID:     order.PickupWarehouseID,
+		PickupCode:            order.PickupCode,
+		PaymentID:             order.PaymentID,
+		PaymentStatus:         order.PaymentStatus,
+		PaymentImageURL:       order.PaymentImageURL,
+		Items:                 items,
 		Address: addressOrderResponse{
 			OrderID: order.Address.OrderViewID,
 			Street:  order.Address.Street,
@@ -150,6 +340,261 @@ func OrderViewEntityToGetOneOrderResponse(order *entity.OrderView) orderResponse
 			ZipCode: order.Address.ZipCode,
 			Note:    order.Address.Note,
 		},
+		Timeline:  OrderTimelineViewToTimelineResponse(order.Timeline),
 		CreatedAt: order.CreatedAt,
 	}
 }
+
+// OrderEntityToGetOneOrderResponse answer a read from the command database in the order view shape,
+// the product details, payment proof and timeline only live on the read side and come from the stale view
+func OrderEntityToGetOneOrderResponse(order entity.Order, stale *entity.OrderView) orderResponse {
+	staleItems := make(map[uuid.UUID]entity.OrderItemView)
+	var timeline entity.OrderTimelineView
+	var paymentStatus, paymentImageURL string
+	if stale != nil {
+		for _, item := range stale.Items {
+			staleItems[item.ProductID] = item
+		}
+		timeline = stale.Timeline
+		paymentStatus = stale.PaymentStatus
+		paymentImageURL = stale.PaymentImageURL
+	}
+	if status := order.PaymentStatus(); status != "" {
+		paymentStatus = status
+	}
+
+	var items []itemsOrderResponse
+	for _, item := range order.Items {
+		items = append(items, itemsOrderResponse{
+			OrderID:      item.OrderID,
+			ProductID:    item.ProductID,
+			ProductName:  staleItems[item.ProductID].ProductName,
+			ImageURL:     staleItems[item.ProductID].ProductImageURL,
+			Price:        item.Price,
+			Quantity:     item.ProductQuantity,
+			ShippingCost: item.ShippingCost,
+			Discount:     item.TotalDiscount(),
+			Tax:          item.Tax,
+			Note:         item.Note,
+		})
+	}
+
+	return orderResponse{
+		ID:                    order.ID,
+		Status:                order.Status,
+		TotalPrice:            order.TotalPrice,
+		PromotionCode:         order.PromotionCode,
+		DiscountAmount:        order.DiscountAmount,
+		TaxAmount:             order.TaxAmount,
+		ShippingMethod:        order.ShippingMethod,
+		ShippingEstimatedDays: order.ShippingEstimatedDays,
+		EstimatedShipping:     order.EstimatedShipping,
+		ShippingRule:          order.ShippingRule,
+		FulfilmentType:        order.FulfilmentType,
+		PickupWarehouseID:     order.PickupWarehouseID,
+		PickupCode:            order.PickupCode,
+		PaymentID:             order.PaymentID,
+		PaymentStatus:         paymentStatus,
+		PaymentImageURL:       paymentImageURL,
+		Items:                 items,
+		Address: addressOrderResponse{
+			OrderID: order.Address.OrderID,
+			Street:  order.Address.Street,
+			City:    order.Address.City,
+			State:   order.Address.State,
+			ZipCode: order.Address.ZipCode,
+			Note:    order.Address.Note,
+		},
+		Timeline:  OrderTimelineViewToTimelineResponse(timeline),
+		CreatedAt: order.CreatedAt,
+	}
+}
+
+func OrderTimelineViewToTimelineResponse(timeline entity.OrderTimelineView) *timelineResponse {
+	return &timelineResponse{
+		ReturnCount:    timeline.ReturnCount,
+		RefundCount:    timeline.RefundCount,
+		RefundedAmount: timeline.RefundedAmount,
+	}
+}
+
+func CreateReturnRequestToOrderReturnEntity(req createReturnRequest, orderID, userID uuid.UUID) entity.OrderReturn {
+	var items []entity.OrderReturnItem
+	for _, item := range req.Items {
+		items = append(items, entity.OrderReturnItem{
+			ProductID:       item.ProductID,
+			ProductQuantity: item.Quantity,
+		})
+	}
+
+	return entity.OrderReturn{
+		OrderID:   orderID,
+		UserID:    userID,
+		Reason:    req.Reason,
+		Items:     items,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func UpdateReturnRequestToOrderReturnEntity(req updateReturnRequest, orderID, returnID uuid.UUID) entity.OrderReturn {
+	return entity.OrderReturn{
+		ID:        returnID,
+		OrderID:   orderID,
+		AdminNote: req.AdminNote,
+		UpdatedAt: time.Now(),
+	}
+}
+
+func OrderReturnEntityToReturnResponse(orderReturn entity.OrderReturn) returnResponse {
+	var items []itemsReturnResponse
+	for _, item := range orderReturn.Items {
+		items = append(items, itemsReturnResponse{
+			ProductID: item.ProductID,
+			Quantity:  item.ProductQuantity,
+			Price:     item.Price,
+		})
+	}
+
+	return returnResponse{
+		ID:           orderReturn.ID,
+		OrderID:      orderReturn.OrderID,
+		Status:       orderReturn.Status,
+		Reason:       orderReturn.Reason,
+		RefundAmount: orderReturn.RefundAmount,
+		Items:        items,
+		CreatedAt:    orderReturn.CreatedAt,
+	}
+}
+
+func OrderReturnViewEntityToGetManyReturnResponse(returns []*entity.OrderReturnView) []returnResponse {
+	var res []returnResponse
+	for _, orderReturn := range returns {
+		var items []itemsReturnResponse
+		for _, item := range orderReturn.Items {
+			items = append(items, itemsReturnResponse{
+				ProductID: item.ProductID,
+				Quantity:  item.ProductQuantity,
+				Price:     item.Price,
+			})
+		}
+
+		res = append(res, returnResponse{
+			ID:           orderReturn.ReturnID,
+			OrderID:      orderReturn.OrderID,
+			Status:       orderReturn.Status,
+			Reason:       orderReturn.Reason,
+			AdminNote:    orderReturn.AdminNote,
+			RefundAmount: orderReturn.RefundAmount,
+			Items:        items,
+			CreatedAt:    orderReturn.CreatedAt,
+		})
+	}
+	return res
+}
+
+func CreateRefundRequestToRefundEntity(req createRefundRequest, orderID uuid.UUID) entity.Refund {
+	var items []entity.RefundItem
+	for _, item := range req.Items {
+		items = append(items, entity.RefundItem{
+			ProductID:       item.ProductID,
+			ProductQuantity: item.Quantity,
+		})
+	}
+
+	return entity.Refund{
+		OrderID:   orderID,
+		Amount:    req.Amount,
+		Reason:    req.Reason,
+		Items:     items,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func RefundEntityToRefundResponse(refund entity.Refund) refundResponse {
+	var items []itemsRefundResponse
+	for _, item := range refund.Items {
+		items = append(items, itemsRefundResponse{
+			ProductID: item.ProductID,
+			Quantity:  item.ProductQuantity,
+			Price:     item.Price,
+		})
+	}
+
+	return refundResponse{
+		ID:        refund.ID,
+		OrderID:   refund.OrderID,
+		ReturnID:  refund.ReturnID,
+		Amount:    refund.Amount,
+		Reason:    refund.Reason,
+		Status:    refund.Status,
+		Items:     items,
+		CreatedAt: refund.CreatedAt,
+	}
+}
+
+func RefundViewEntityToGetManyRefundResponse(refunds []*entity.RefundView) []refundResponse {
+	var res []refundResponse
+	for _, refund := range refunds {
+		var items []itemsRefundResponse
+		for _, item := range refund.Items {
+			items = append(items, itemsRefundResponse{
+				ProductID: item.ProductID,
+				Quantity:  item.ProductQuantity,
+				Price:     item.Price,
+			})
+		}
+
+		res = append(res, refundResponse{
+			ID:        refund.RefundID,
+			OrderID:   refund.OrderID,
+			ReturnID:  refund.ReturnID,
+			Amount:    refund.Amount,
+			Reason:    refund.Reason,
+			Status:    refund.Status,
+			Items:     items,
+			CreatedAt: refund.CreatedAt,
+		})
+	}
+	return res
+}
+
+func CreatePromotionRequestToPromotionEntity(req createPromotionRequest) entity.Promotion {
+	return entity.Promotion{
+		Code:              req.Code,
+		Type:              req.Type,
+		Value:             req.Value,
+		MaxDiscount:       req.MaxDiscount,
+		MinSpend:          req.MinSpend,
+		ProductID:         req.ProductID,
+		BuyQuantity:       req.BuyQuantity,
+		GetQuantity:       req.GetQuantity,
+		StartAt:           req.StartAt,
+		EndAt:             req.EndAt,
+		UsageLimit:        req.UsageLimit,
+		UsageLimitPerUser: req.UsageLimitPerUser,
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
+	}
+}
+
+func PromotionEntityToPromotionResponse(promotion *entity.Promotion) promotionResponse {
+	return promotionResponse{
+		ID:                promotion.ID,
+		Code:              promotion.Code,
+		Type:              promotion.Type,
+		Value:             promotion.Value,
+		MaxDiscount:       promotion.MaxDiscount,
+		MinSpend:          promotion.MinSpend,
+		ProductID:         promotion.ProductID,
+		BuyQuantity:       promotion.BuyQuantity,
+		GetQuantity:       promotion.GetQuantity,
+		StartAt:           promotion.StartAt,
+		EndAt:             promotion.EndAt,
+		UsageLimit:        promotion.UsageLimit,
+		UsageLimitPerUser: promotion.UsageLimitPerUser,
+		UsageCount:        promotion.UsageCount,
+		CreatedAt:         promotion.CreatedAt,
+	}
+}
@@ -15,8 +15,11 @@ import (
 const (
 	UserIDKey = "userID"
 	TokenKey  = "token"
+	RoleKey   = "role"
 )
 
+const adminRole = "admin"
+
 type authSuccessResponse struct {
 	Code    int          `json:"code"`
 	Data    authResponse `json:"data"`
@@ -70,6 +73,27 @@ func cognitoMiddleware(auth config.AuthService) gin.HandlerFunc {
 		}
 
 		ctx.Set(UserIDKey, authSuccessResponse.Data.UserID)
+		ctx.Set(RoleKey, authSuccessResponse.Data.Role)
+		ctx.Next()
+	}
+}
+
+// adminMiddleware must be used after cognitoMiddleware
+func adminMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !isAdmin(ctx) {
+			ctx.JSON(http.StatusForbidden, newForbiddenError("forbidden"))
+			ctx.Abort()
+			return
+		}
 		ctx.Next()
 	}
 }
+
+func isAdmin(ctx *gin.Context) bool {
+	role, exist := ctx.Get(RoleKey)
+	if !exist {
+		return false
+	}
+	return role.(string) == adminRole
+}
@@ -2,15 +2,27 @@ package v1
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
 	"github.com/idoyudha/eshop-order/internal/usecase"
 	"github.com/idoyudha/eshop-order/pkg/logger"
 )
 
+const (
+	// projection token of the create response, the read waits for the read model to reach it
+	minVersionHeader = "X-Min-Version"
+	// tells the client whether the order came from the read model or the command database
+	readSourceHeader  = "X-Read-Source"
+	readSourceQuery   = "query"
+	readSourceCommand = "command"
+)
+
 type orderRoutes struct {
 	uoc usecase.OrderCommand
 	uoq usecase.OrderQuery
@@ -29,19 +41,50 @@ func newOrderRoutes(
 	h := handler.Group("/orders").Use(authMid)
 	{
 		h.POST("", r.createOrder)
+		h.POST("/shipping-quotes", r.getShippingQuotes)
 		h.GET("/user", r.getOrderByUserID)
 		h.GET("/:id", r.getOrderByID)
-		h.GET("", r.getAllOrders)
+		h.GET("", adminMiddleware(), r.getAllOrders)
+		h.PATCH("/:id", r.updateOrder)
 		h.PATCH("/:id/status", r.updateOrderStatus)
+		h.POST("/:id/items/cancel", r.cancelOrderItems)
+		h.POST("/:id/pickup", adminMiddleware(), r.confirmPickup)
 		h.GET("/:id/ttl", r.getOrderTTL)
 	}
+
+	a := handler.Group("/admin/orders").Use(authMid, adminMiddleware())
+	{
+		a.GET("/search", r.searchOrders)
+	}
 }
 
 type createOrderRequest struct {
+	Items         []createItemsOrderRequest `json:"items"`
+	Address       createAddressOrderRequest `json:"address"`
+	PromotionCode string                    `json:"promotion_code"`
+	// STANDARD when empty, checked against the configured shipping methods by the use case
+	ShippingMethod string `json:"shipping_method"`
+	// DELIVERY when empty, PICKUP needs the warehouse to collect from
+	FulfilmentType string    `json:"fulfilment_type" binding:"omitempty,oneof=DELIVERY PICKUP"`
+	WarehouseID    uuid.UUID `json:"warehouse_id"`
+}
+
+type confirmPickupRequest struct {
+	PickupCode string `json:"pickup_code" binding:"required"`
+}
+
+type shippingQuoteRequest struct {
 	Items   []createItemsOrderRequest `json:"items"`
 	Address createAddressOrderRequest `json:"address"`
 }
 
+type shippingQuoteResponse struct {
+	Method        string  `json:"method"`
+	Cost          float64 `json:"cost"`
+	EstimatedDays int     `json:"estimated_days"`
+	Estimated     bool    `json:"estimated"`
+}
+
 type createItemsOrderRequest struct {
 	ProductID uuid.UUID `json:"product_id"`
 	Quantity  int64     `json:"quantity"`
@@ -57,15 +100,27 @@ type createAddressOrderRequest struct {
 }
 
 type orderResponse struct {
-	ID              uuid.UUID            `json:"id"`
-	Status          string               `json:"status"`
-	TotalPrice      float64              `json:"total_price"`
-	PaymentID       uuid.UUID            `json:"payment_id"`
-	PaymentStatus   string               `json:"payment_status"`
-	PaymentImageURL string               `json:"payment_image_url"`
-	Items           []itemsOrderResponse `json:"items"`
-	Address         addressOrderResponse `json:"address"`
-	CreatedAt       time.Time            `json:"created_at"`
+	ID                    uuid.UUID            `json:"id"`
+	Status                string               `json:"status"`
+	TotalPrice            float64              `json:"total_price"`
+	PromotionCode         string               `json:"promotion_code"`
+	DiscountAmount        float64              `json:"discount_amount"`
+	TaxAmount             float64              `json:"tax_amount"`
+	ShippingMethod        string               `json:"shipping_method"`
+	ShippingEstimatedDays int                  `json:"shipping_estimated_days"`
+	EstimatedShipping     bool                 `json:"estimated_shipping"`
+	ShippingRule          string               `json:"shipping_rule"`
+	FulfilmentType        string               `json:"fulfilment_type"`
+	PickupWarehouseID     uuid.UUID            `json:"pickup_warehouse_id"`
+	PickupCode            string               `json:"pickup_code"`
+	PaymentID             uuid.UUID            `json:"payment_id"`
+	PaymentStatus         string               `json:"payment_status"`
+	PaymentImageURL       string               `json:"payment_image_url"`
+	ProjectionToken       string               `json:"projection_token,omitempty"`
+	Items                 []itemsOrderResponse `json:"items"`
+	Address               addressOrderResponse `json:"address"`
+	Timeline              *timelineResponse    `json:"timeline,omitempty"`
+	CreatedAt             time.Time            `json:"created_at"`
 }
 
 type itemsOrderResponse struct {
@@ -76,9 +131,18 @@ type itemsOrderResponse struct {
 	Price        float64   `json:"price"`
 	Quantity     int64     `json:"quantity"`
 	ShippingCost float64   `json:"shipping_cost"`
+	Discount     float64   `json:"discount"`
+	Tax          float64   `json:"tax"`
 	Note         string    `json:"note"`
 }
 
+// returns and refunds of the order, only on the read side
+type timelineResponse struct {
+	ReturnCount    int     `json:"return_count"`
+	RefundCount    int     `json:"refund_count"`
+	RefundedAmount float64 `json:"refunded_amount"`
+}
+
 type addressOrderResponse struct {
 	OrderID uuid.UUID `json:"order_id"`
 	Street  string    `json:"street"`
@@ -120,7 +184,8 @@ func (r *orderRoutes) createOrder(ctx *gin.Context) {
 	err = r.uoc.CreateOrder(context.Background(), &order, token.(string))
 	if err != nil {
 		r.l.Error(err, "http - v1 - orderRoutes - createOrder")
-		ctx.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
 		return
 	}
 
@@ -129,6 +194,36 @@ func (r *orderRoutes) createOrder(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, newCreateSuccess(response))
 }
 
+func (r *orderRoutes) getShippingQuotes(ctx *gin.Context) {
+	var req shippingQuoteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - getShippingQuotes")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	token, exist := ctx.Get(TokenKey)
+	if !exist {
+		r.l.Error("not exist", "http - v1 - orderRoutes - getShippingQuotes")
+		ctx.JSON(http.StatusInternalServerError, newInternalServerError("token not exist"))
+		return
+	}
+
+	order := ShippingQuoteRequestToOrderEntity(req)
+
+	quotes, err := r.uoc.GetShippingQuotes(context.Background(), &order, token.(string))
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - getShippingQuotes")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	response := ShippingRateEntityToShippingQuoteResponse(quotes)
+
+	ctx.JSON(http.StatusOK, newGetSuccess(response))
+}
+
 func (r *orderRoutes) getOrderByID(ctx *gin.Context) {
 	orderID, err := uuid.Parse(ctx.Param("id"))
 	if err != nil {
@@ -137,6 +232,11 @@ func (r *orderRoutes) getOrderByID(ctx *gin.Context) {
 		return
 	}
 
+	if minVersion := ctx.GetHeader(minVersionHeader); minVersion != "" {
+		r.getOrderByIDAtVersion(ctx, orderID, minVersion)
+		return
+	}
+
 	order, err := r.uoq.GetOrderByID(context.Background(), orderID)
 	if err != nil {
 		r.l.Error(err, "http - v1 - orderRoutes - getOrderByID")
@@ -149,6 +249,52 @@ func (r *orderRoutes) getOrderByID(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, newGetSuccess(response))
 }
 
+// getOrderByIDAtVersion read the order right after the client wrote it,
+// the command database answers when the read model does not catch up in time
+func (r *orderRoutes) getOrderByIDAtVersion(ctx *gin.Context, orderID uuid.UUID, minVersion string) {
+	token, err := entity.DecodeProjectionToken(minVersion)
+	if err == nil && token.OrderID != orderID {
+		err = fmt.Errorf("projection token is for order %s: %w", token.OrderID, entity.ErrInvalidProjectionToken)
+	}
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - getOrderByIDAtVersion")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	// the request context stops the wait for the projection when the client goes away
+	orderView, err := r.uoq.GetOrderByIDAtVersion(ctx.Request.Context(), orderID, token.Version)
+	if err == nil {
+		ctx.Header(readSourceHeader, readSourceQuery)
+		ctx.JSON(http.StatusOK, newGetSuccess(OrderViewEntityToGetOneOrderResponse(orderView)))
+		return
+	}
+	if !errors.Is(err, usecase.ErrProjectionBehind) {
+		r.l.Error(err, "http - v1 - orderRoutes - getOrderByIDAtVersion")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	order, err := r.uoc.GetOrderByID(ctx.Request.Context(), orderID)
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - getOrderByIDAtVersion")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	// the stale view is only missing before the order is first projected
+	stale, err := r.uoq.GetOrderByID(ctx.Request.Context(), orderID)
+	if err != nil {
+		stale = nil
+	}
+
+	ctx.Header(readSourceHeader, readSourceCommand)
+	ctx.JSON(http.StatusOK, newGetSuccess(OrderEntityToGetOneOrderResponse(*order, stale)))
+}
+
 func (r *orderRoutes) getOrderByUserID(ctx *gin.Context) {
 	userID, exist := ctx.Get(UserIDKey)
 	if !exist {
@@ -157,29 +303,95 @@ func (r *orderRoutes) getOrderByUserID(ctx *gin.Context) {
 		return
 	}
 
-	orders, err := r.uoq.GetOrderByUserID(context.Background(), userID.(uuid.UUID))
+	var req pageRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - getOrderByUserID")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	page, err := PageRequestToPageEntity(req)
 	if err != nil {
 		r.l.Error(err, "http - v1 - orderRoutes - getOrderByUserID")
-		ctx.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	orders, next, err := r.uoq.GetOrderByUserID(context.Background(), userID.(uuid.UUID), page)
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - getOrderByUserID")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
 		return
 	}
 
 	response := OrderViewEntityToGetManyOrderResponse(orders)
 
-	ctx.JSON(http.StatusOK, newGetSuccess(response))
+	ctx.JSON(http.StatusOK, newGetPageSuccess(response, next))
 }
 
+// getAllOrders filter and sort the orders of every user, admins only
 func (r *orderRoutes) getAllOrders(ctx *gin.Context) {
-	orders, err := r.uoq.GetAllOrders(context.Background())
+	var req orderFilterRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - getAllOrders")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	page, err := PageRequestToPageEntity(req.pageRequest)
 	if err != nil {
 		r.l.Error(err, "http - v1 - orderRoutes - getAllOrders")
-		ctx.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	filter, err := OrderFilterRequestToOrderFilterEntity(req)
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - getAllOrders")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	orders, next, err := r.uoq.ListOrders(context.Background(), filter, page)
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - getAllOrders")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
 		return
 	}
 
 	response := OrderViewEntityToGetManyOrderResponse(orders)
 
-	ctx.JSON(http.StatusOK, newGetSuccess(response))
+	ctx.JSON(http.StatusOK, newGetPageSuccess(response, next))
+}
+
+func (r *orderRoutes) searchOrders(ctx *gin.Context) {
+	var req searchOrderRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - searchOrders")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	page, err := PageRequestToPageEntity(req.pageRequest)
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - searchOrders")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	orders, next, err := r.uoq.SearchOrders(context.Background(), req.Query, page)
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - searchOrders")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	response := OrderViewEntityToGetManyOrderResponse(orders)
+
+	ctx.JSON(http.StatusOK, newGetPageSuccess(response, next))
 }
 
 type UpdateOrderStatusRequest struct {
@@ -213,6 +425,36 @@ func (r *orderRoutes) updateOrderStatus(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, newUpdateSuccess(nil))
 }
 
+func (r *orderRoutes) confirmPickup(ctx *gin.Context) {
+	orderID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - confirmPickup")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	var req confirmPickupRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - confirmPickup")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	order := ConfirmPickupRequestToOrderEntity(req, orderID)
+
+	err = r.uoc.ConfirmPickup(context.Background(), &order)
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - confirmPickup")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	response := OrderEntityToCreatedOrderResponse(order)
+
+	ctx.JSON(http.StatusOK, newUpdateSuccess(response))
+}
+
 type orderTTLResponse struct {
 	TTL int `json:"ttl_seconds"`
 }
@@ -237,3 +479,131 @@ func (r *orderRoutes) getOrderTTL(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, newGetSuccess(response))
 }
+
+type cancelOrderItemsRequest struct {
+	Items []cancelItemsOrderRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+type cancelItemsOrderRequest struct {
+	ProductID uuid.UUID `json:"product_id" binding:"required"`
+	Quantity  int64     `json:"quantity" binding:"required,gt=0"`
+}
+
+func (r *orderRoutes) cancelOrderItems(ctx *gin.Context) {
+	orderID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - cancelOrderItems")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	var req cancelOrderItemsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - cancelOrderItems")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	userID, exist := ctx.Get(UserIDKey)
+	if !exist {
+		r.l.Error("not exist", "http - v1 - orderRoutes - cancelOrderItems")
+		ctx.JSON(http.StatusInternalServerError, newInternalServerError("user id not exist"))
+		return
+	}
+
+	token, exist := ctx.Get(TokenKey)
+	if !exist {
+		r.l.Error("not exist", "http - v1 - orderRoutes - cancelOrderItems")
+		ctx.JSON(http.StatusInternalServerError, newInternalServerError("token not exist"))
+		return
+	}
+
+	// admin can cancel items of any order
+	ownerID := userID.(uuid.UUID)
+	if isAdmin(ctx) {
+		ownerID = uuid.Nil
+	}
+	order := CancelOrderItemsRequestToOrderEntity(req, orderID, ownerID)
+
+	err = r.uoc.CancelOrderItems(context.Background(), &order, token.(string))
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - cancelOrderItems")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	response := OrderEntityToCreatedOrderResponse(order)
+
+	ctx.JSON(http.StatusOK, newUpdateSuccess(response))
+}
+
+type updateOrderRequest struct {
+	Address *createAddressOrderRequest `json:"address"`
+	Items   []updateItemsOrderRequest  `json:"items" binding:"dive"`
+}
+
+type updateItemsOrderRequest struct {
+	ProductID uuid.UUID `json:"product_id" binding:"required"`
+	Quantity  int64     `json:"quantity" binding:"required,gt=0"`
+}
+
+func (r *orderRoutes) updateOrder(ctx *gin.Context) {
+	orderID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - updateOrder")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	var req updateOrderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - updateOrder")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	if req.Address == nil && len(req.Items) == 0 {
+		r.l.Error("empty update", "http - v1 - orderRoutes - updateOrder")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError("address or items is required"))
+		return
+	}
+	if req.Address != nil && req.Address.ZipCode == "" {
+		r.l.Error("empty zipcode", "http - v1 - orderRoutes - updateOrder")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError("address zipcode is required"))
+		return
+	}
+
+	userID, exist := ctx.Get(UserIDKey)
+	if !exist {
+		r.l.Error("not exist", "http - v1 - orderRoutes - updateOrder")
+		ctx.JSON(http.StatusInternalServerError, newInternalServerError("user id not exist"))
+		return
+	}
+
+	token, exist := ctx.Get(TokenKey)
+	if !exist {
+		r.l.Error("not exist", "http - v1 - orderRoutes - updateOrder")
+		ctx.JSON(http.StatusInternalServerError, newInternalServerError("token not exist"))
+		return
+	}
+
+	// admin can edit any pending order
+	ownerID := userID.(uuid.UUID)
+	if isAdmin(ctx) {
+		ownerID = uuid.Nil
+	}
+	order := EditOrderRequestToOrderEntity(req, orderID, ownerID)
+
+	err = r.uoc.EditOrder(context.Background(), &order, token.(string))
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRoutes - updateOrder")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	response := OrderEntityToCreatedOrderResponse(order)
+
+	ctx.JSON(http.StatusOK, newUpdateSuccess(response))
+}
@@ -0,0 +1,145 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/usecase"
+	"github.com/idoyudha/eshop-order/pkg/logger"
+)
+
+type orderRefundRoutes struct {
+	ufc usecase.RefundCommand
+	ufq usecase.RefundQuery
+	l   logger.Interface
+}
+
+func newOrderRefundRoutes(
+	handler *gin.RouterGroup,
+	ufc usecase.RefundCommand,
+	ufq usecase.RefundQuery,
+	l logger.Interface,
+	authMid gin.HandlerFunc,
+) {
+	r := &orderRefundRoutes{ufc: ufc, ufq: ufq, l: l}
+
+	h := handler.Group("/orders/:id/refunds").Use(authMid)
+	{
+		h.POST("", adminMiddleware(), r.createRefund)
+		h.GET("", r.getRefundsByOrderID)
+	}
+}
+
+// without items and amount the whole remaining paid amount is refunded
+type createRefundRequest struct {
+	Reason string                    `json:"reason" binding:"required"`
+	Amount float64                   `json:"amount" binding:"gte=0"`
+	Items  []createItemRefundRequest `json:"items"`
+}
+
+type createItemRefundRequest struct {
+	ProductID uuid.UUID `json:"product_id" binding:"required"`
+	Quantity  int64     `json:"quantity" binding:"required,gt=0"`
+}
+
+type refundResponse struct {
+	ID        uuid.UUID             `json:"id"`
+	OrderID   uuid.UUID             `json:"order_id"`
+	ReturnID  uuid.UUID             `json:"return_id"`
+	Amount    float64               `json:"amount"`
+	Reason    string                `json:"reason"`
+	Status    string                `json:"status"`
+	Items     []itemsRefundResponse `json:"items"`
+	CreatedAt time.Time             `json:"created_at"`
+}
+
+type itemsRefundResponse struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Quantity  int64     `json:"quantity"`
+	Price     float64   `json:"price"`
+}
+
+func (r *orderRefundRoutes) createRefund(ctx *gin.Context) {
+	orderID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRefundRoutes - createRefund")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	var req createRefundRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		r.l.Error(err, "http - v1 - orderRefundRoutes - createRefund")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	refund := CreateRefundRequestToRefundEntity(req, orderID)
+
+	err = r.ufc.IssueRefund(context.Background(), &refund)
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRefundRoutes - createRefund")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	response := RefundEntityToRefundResponse(refund)
+
+	ctx.JSON(http.StatusCreated, newCreateSuccess(response))
+}
+
+func (r *orderRefundRoutes) getRefundsByOrderID(ctx *gin.Context) {
+	orderID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRefundRoutes - getRefundsByOrderID")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	var req pageRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		r.l.Error(err, "http - v1 - orderRefundRoutes - getRefundsByOrderID")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	page, err := PageRequestToPageEntity(req)
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRefundRoutes - getRefundsByOrderID")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	userID, exist := ctx.Get(UserIDKey)
+	if !exist {
+		r.l.Error("not exist", "http - v1 - orderRefundRoutes - getRefundsByOrderID")
+		ctx.JSON(http.StatusInternalServerError, newInternalServerError("user id not exist"))
+		return
+	}
+
+	refunds, next, err := r.ufq.GetRefundsByOrderID(ctx.Request.Context(), orderID, page)
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderRefundRoutes - getRefundsByOrderID")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	// customer only see their own refunds
+	if !isAdmin(ctx) {
+		for _, refund := range refunds {
+			if refund.UserID != userID.(uuid.UUID) {
+				ctx.JSON(http.StatusNotFound, newNotFoundError("order not found"))
+				return
+			}
+		}
+	}
+
+	response := RefundViewEntityToGetManyRefundResponse(refunds)
+
+	ctx.JSON(http.StatusOK, newGetPageSuccess(response, next))
+}
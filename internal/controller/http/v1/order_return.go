@@ -0,0 +1,237 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/usecase"
+	"github.com/idoyudha/eshop-order/pkg/logger"
+)
+
+type orderReturnRoutes struct {
+	urc usecase.ReturnCommand
+	urq usecase.ReturnQuery
+	l   logger.Interface
+}
+
+func newOrderReturnRoutes(
+	handler *gin.RouterGroup,
+	urc usecase.ReturnCommand,
+	urq usecase.ReturnQuery,
+	l logger.Interface,
+	authMid gin.HandlerFunc,
+) {
+	r := &orderReturnRoutes{urc: urc, urq: urq, l: l}
+
+	h := handler.Group("/orders/:id/returns").Use(authMid)
+	{
+		h.POST("", r.createReturn)
+		h.GET("", r.getReturnsByOrderID)
+		h.PATCH("/:return_id/approve", adminMiddleware(), r.approveReturn)
+		h.PATCH("/:return_id/reject", adminMiddleware(), r.rejectReturn)
+	}
+}
+
+type createReturnRequest struct {
+	Reason string                    `json:"reason" binding:"required"`
+	Items  []createItemReturnRequest `json:"items"`
+}
+
+type createItemReturnRequest struct {
+	ProductID uuid.UUID `json:"product_id" binding:"required"`
+	Quantity  int64     `json:"quantity" binding:"required,gt=0"`
+}
+
+type updateReturnRequest struct {
+	AdminNote string `json:"admin_note"`
+}
+
+type returnResponse struct {
+	ID           uuid.UUID             `json:"id"`
+	OrderID      uuid.UUID             `json:"order_id"`
+	Status       string                `json:"status"`
+	Reason       string                `json:"reason"`
+	AdminNote    string                `json:"admin_note"`
+	RefundAmount float64               `json:"refund_amount"`
+	Items        []itemsReturnResponse `json:"items"`
+	CreatedAt    time.Time             `json:"created_at"`
+}
+
+type itemsReturnResponse struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Quantity  int64     `json:"quantity"`
+	Price     float64   `json:"price"`
+}
+
+func (r *orderReturnRoutes) createReturn(ctx *gin.Context) {
+	orderID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderReturnRoutes - createReturn")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	var req createReturnRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		r.l.Error(err, "http - v1 - orderReturnRoutes - createReturn")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	userID, exist := ctx.Get(UserIDKey)
+	if !exist {
+		r.l.Error("not exist", "http - v1 - orderReturnRoutes - createReturn")
+		ctx.JSON(http.StatusInternalServerError, newInternalServerError("user id not exist"))
+		return
+	}
+
+	orderReturn := CreateReturnRequestToOrderReturnEntity(req, orderID, userID.(uuid.UUID))
+
+	err = r.urc.CreateReturn(context.Background(), &orderReturn)
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderReturnRoutes - createReturn")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	response := OrderReturnEntityToReturnResponse(orderReturn)
+
+	ctx.JSON(http.StatusCreated, newCreateSuccess(response))
+}
+
+func (r *orderReturnRoutes) getReturnsByOrderID(ctx *gin.Context) {
+	orderID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderReturnRoutes - getReturnsByOrderID")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	var req pageRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		r.l.Error(err, "http - v1 - orderReturnRoutes - getReturnsByOrderID")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	page, err := PageRequestToPageEntity(req)
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderReturnRoutes - getReturnsByOrderID")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	userID, exist := ctx.Get(UserIDKey)
+	if !exist {
+		r.l.Error("not exist", "http - v1 - orderReturnRoutes - getReturnsByOrderID")
+		ctx.JSON(http.StatusInternalServerError, newInternalServerError("user id not exist"))
+		return
+	}
+
+	returns, next, err := r.urq.GetReturnsByOrderID(ctx.Request.Context(), orderID, page)
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderReturnRoutes - getReturnsByOrderID")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	// customer only see their own returns
+	if !isAdmin(ctx) {
+		for _, ret := range returns {
+			if ret.UserID != userID.(uuid.UUID) {
+				ctx.JSON(http.StatusNotFound, newNotFoundError("order not found"))
+				return
+			}
+		}
+	}
+
+	response := OrderReturnViewEntityToGetManyReturnResponse(returns)
+
+	ctx.JSON(http.StatusOK, newGetPageSuccess(response, next))
+}
+
+func (r *orderReturnRoutes) approveReturn(ctx *gin.Context) {
+	orderID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderReturnRoutes - approveReturn")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	returnID, err := uuid.Parse(ctx.Param("return_id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderReturnRoutes - approveReturn")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	// admin note is optional, the body can be empty
+	var req updateReturnRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		r.l.Error(err, "http - v1 - orderReturnRoutes - approveReturn")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	token, exist := ctx.Get(TokenKey)
+	if !exist {
+		r.l.Error("not exist", "http - v1 - orderReturnRoutes - approveReturn")
+		ctx.JSON(http.StatusInternalServerError, newInternalServerError("token not exist"))
+		return
+	}
+
+	orderReturn := UpdateReturnRequestToOrderReturnEntity(req, orderID, returnID)
+
+	err = r.urc.ApproveReturn(context.Background(), &orderReturn, token.(string))
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderReturnRoutes - approveReturn")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUpdateSuccess(nil))
+}
+
+func (r *orderReturnRoutes) rejectReturn(ctx *gin.Context) {
+	orderID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderReturnRoutes - rejectReturn")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	returnID, err := uuid.Parse(ctx.Param("return_id"))
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderReturnRoutes - rejectReturn")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	// admin note is optional, the body can be empty
+	var req updateReturnRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		r.l.Error(err, "http - v1 - orderReturnRoutes - rejectReturn")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	orderReturn := UpdateReturnRequestToOrderReturnEntity(req, orderID, returnID)
+
+	err = r.urc.RejectReturn(context.Background(), &orderReturn)
+	if err != nil {
+		r.l.Error(err, "http - v1 - orderReturnRoutes - rejectReturn")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUpdateSuccess(nil))
+}
@@ -0,0 +1,33 @@
+package v1
+
+import "time"
+
+// query of every list endpoint, the cursor is the next_cursor of the previous response
+type pageRequest struct {
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type searchOrderRequest struct {
+	pageRequest
+	Query string `form:"q" binding:"required"`
+}
+
+// filters of the order listing, status can be repeated and dates are RFC3339
+type orderFilterRequest struct {
+	pageRequest
+	Status        []string  `form:"status" binding:"omitempty,dive,oneof=PENDING PAYMENT_ACCEPTED ON_DELIVERY DELIVERED REJECTED EXPIRED DELIVERY_FAILED RETURNING CANCELLED READY_FOR_PICKUP PICKED_UP FULFILLED"`
+	CreatedFrom   time.Time `form:"created_from"`
+	CreatedTo     time.Time `form:"created_to"`
+	UpdatedFrom   time.Time `form:"updated_from"`
+	UpdatedTo     time.Time `form:"updated_to"`
+	MinTotalPrice float64   `form:"min_total_price" binding:"omitempty,min=0"`
+	MaxTotalPrice float64   `form:"max_total_price" binding:"omitempty,min=0"`
+	PaymentStatus string    `form:"payment_status" binding:"omitempty,oneof=PENDING APPROVED REJECTED"`
+	UserID        string    `form:"user_id" binding:"omitempty,uuid"`
+	ProductID     string    `form:"product_id" binding:"omitempty,uuid"`
+	State         string    `form:"state"`
+	City          string    `form:"city"`
+	Sort          string    `form:"sort" binding:"omitempty,oneof=created_at updated_at total_price"`
+	Order         string    `form:"order" binding:"omitempty,oneof=asc desc"`
+}
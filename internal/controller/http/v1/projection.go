@@ -0,0 +1,193 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
+	"github.com/idoyudha/eshop-order/internal/usecase"
+	"github.com/idoyudha/eshop-order/pkg/logger"
+)
+
+type projectionRoutes struct {
+	upr usecase.ProjectionRebuild
+	upn usecase.ProjectionReconcile
+	l   logger.Interface
+}
+
+func newProjectionRoutes(
+	handler *gin.RouterGroup,
+	upr usecase.ProjectionRebuild,
+	upn usecase.ProjectionReconcile,
+	l logger.Interface,
+	authMid gin.HandlerFunc,
+) {
+	r := &projectionRoutes{upr: upr, upn: upn, l: l}
+
+	h := handler.Group("/admin/projections/orders").Use(authMid, adminMiddleware())
+	{
+		h.POST("/rebuild", r.rebuildOrderViews)
+		h.GET("/rebuild", r.getOrderViewRebuild)
+		h.POST("/reconcile", r.reconcileOrderViews)
+		h.GET("/reconcile", r.getOrderViewReconcile)
+	}
+}
+
+type rebuildOrderViewsRequest struct {
+	Shadow    bool `json:"shadow"`
+	BatchSize int  `json:"batch_size" binding:"omitempty,min=1,max=5000"`
+}
+
+type projectionRebuildResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Shadow     bool       `json:"shadow"`
+	BatchSize  int        `json:"batch_size"`
+	Status     string     `json:"status"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Skipped    int        `json:"skipped"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func ProjectionRebuildEntityToResponse(rebuild entity.ProjectionRebuild) projectionRebuildResponse {
+	response := projectionRebuildResponse{
+		ID:        rebuild.ID,
+		Shadow:    rebuild.Shadow,
+		BatchSize: rebuild.BatchSize,
+		Status:    rebuild.Status,
+		Total:     rebuild.Total,
+		Processed: rebuild.Processed,
+		Skipped:   rebuild.Skipped,
+		Error:     rebuild.Error,
+		StartedAt: rebuild.StartedAt,
+	}
+	if !rebuild.FinishedAt.IsZero() {
+		response.FinishedAt = &rebuild.FinishedAt
+	}
+
+	return response
+}
+
+// rebuildOrderViews start the rebuild and answer right away, progress is polled with the GET endpoint
+func (r *projectionRoutes) rebuildOrderViews(ctx *gin.Context) {
+	var req rebuildOrderViewsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		r.l.Error(err, "http - v1 - projectionRoutes - rebuildOrderViews")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	rebuild, err := r.upr.StartOrderViewRebuild(context.Background(), req.Shadow, req.BatchSize)
+	if err != nil {
+		r.l.Error(err, "http - v1 - projectionRoutes - rebuildOrderViews")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, newAcceptedSuccess(ProjectionRebuildEntityToResponse(rebuild)))
+}
+
+func (r *projectionRoutes) getOrderViewRebuild(ctx *gin.Context) {
+	rebuild, err := r.upr.GetOrderViewRebuild(context.Background())
+	if err != nil {
+		r.l.Error(err, "http - v1 - projectionRoutes - getOrderViewRebuild")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newGetSuccess(ProjectionRebuildEntityToResponse(rebuild)))
+}
+
+type reconcileOrderViewsRequest struct {
+	Repair string `json:"repair" binding:"omitempty,oneof=none patch events"`
+}
+
+type orderDiscrepancyResponse struct {
+	OrderID      uuid.UUID `json:"order_id"`
+	Kind         string    `json:"kind"`
+	CommandValue string    `json:"command_value,omitempty"`
+	ViewValue    string    `json:"view_value,omitempty"`
+	Repaired     bool      `json:"repaired"`
+}
+
+type reconcileReportResponse struct {
+	ID            uuid.UUID                  `json:"id"`
+	Repair        string                     `json:"repair"`
+	Status        string                     `json:"status"`
+	Checked       int                        `json:"checked"`
+	Counts        map[string]int             `json:"counts"`
+	Repaired      int                        `json:"repaired"`
+	Discrepancies []orderDiscrepancyResponse `json:"discrepancies"`
+	Error         string                     `json:"error,omitempty"`
+	StartedAt     time.Time                  `json:"started_at"`
+	FinishedAt    *time.Time                 `json:"finished_at,omitempty"`
+}
+
+func ReconcileReportEntityToResponse(report entity.ReconcileReport) reconcileReportResponse {
+	discrepancies := make([]orderDiscrepancyResponse, len(report.Discrepancies))
+	for i, discrepancy := range report.Discrepancies {
+		discrepancies[i] = orderDiscrepancyResponse{
+			OrderID:      discrepancy.OrderID,
+			Kind:         discrepancy.Kind,
+			CommandValue: discrepancy.CommandValue,
+			ViewValue:    discrepancy.ViewValue,
+			Repaired:     discrepancy.Repaired,
+		}
+	}
+
+	response := reconcileReportResponse{
+		ID:            report.ID,
+		Repair:        report.Repair,
+		Status:        report.Status,
+		Checked:       report.Checked,
+		Counts:        report.Counts,
+		Repaired:      report.Repaired,
+		Discrepancies: discrepancies,
+		Error:         report.Error,
+		StartedAt:     report.StartedAt,
+	}
+	if !report.FinishedAt.IsZero() {
+		response.FinishedAt = &report.FinishedAt
+	}
+
+	return response
+}
+
+// reconcileOrderViews start the reconcile and answer right away, the report is read with the GET endpoint
+func (r *projectionRoutes) reconcileOrderViews(ctx *gin.Context) {
+	var req reconcileOrderViewsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		r.l.Error(err, "http - v1 - projectionRoutes - reconcileOrderViews")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	report, err := r.upn.StartOrderViewReconcile(context.Background(), req.Repair)
+	if err != nil {
+		r.l.Error(err, "http - v1 - projectionRoutes - reconcileOrderViews")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, newAcceptedSuccess(ReconcileReportEntityToResponse(report)))
+}
+
+func (r *projectionRoutes) getOrderViewReconcile(ctx *gin.Context) {
+	report, err := r.upn.GetOrderViewReconcile(context.Background())
+	if err != nil {
+		r.l.Error(err, "http - v1 - projectionRoutes - getOrderViewReconcile")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newGetSuccess(ReconcileReportEntityToResponse(report)))
+}
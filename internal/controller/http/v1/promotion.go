@@ -0,0 +1,119 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/usecase"
+	"github.com/idoyudha/eshop-order/pkg/logger"
+)
+
+type promotionRoutes struct {
+	upc usecase.PromotionCommand
+	l   logger.Interface
+}
+
+func newPromotionRoutes(
+	handler *gin.RouterGroup,
+	upc usecase.PromotionCommand,
+	l logger.Interface,
+	authMid gin.HandlerFunc,
+) {
+	r := &promotionRoutes{upc: upc, l: l}
+
+	h := handler.Group("/promotions").Use(authMid, adminMiddleware())
+	{
+		h.POST("", r.createPromotion)
+		h.GET("", r.getAllPromotions)
+	}
+}
+
+type createPromotionRequest struct {
+	Code              string    `json:"code" binding:"required"`
+	Type              string    `json:"type" binding:"required"`
+	Value             float64   `json:"value" binding:"gte=0"`
+	MaxDiscount       float64   `json:"max_discount" binding:"gte=0"`
+	MinSpend          float64   `json:"min_spend" binding:"gte=0"`
+	ProductID         uuid.UUID `json:"product_id"`
+	BuyQuantity       int64     `json:"buy_quantity" binding:"gte=0"`
+	GetQuantity       int64     `json:"get_quantity" binding:"gte=0"`
+	StartAt           time.Time `json:"start_at" binding:"required"`
+	EndAt             time.Time `json:"end_at" binding:"required"`
+	UsageLimit        int       `json:"usage_limit" binding:"gte=0"`
+	UsageLimitPerUser int       `json:"usage_limit_per_user" binding:"gte=0"`
+}
+
+type promotionResponse struct {
+	ID                uuid.UUID `json:"id"`
+	Code              string    `json:"code"`
+	Type              string    `json:"type"`
+	Value             float64   `json:"value"`
+	MaxDiscount       float64   `json:"max_discount"`
+	MinSpend          float64   `json:"min_spend"`
+	ProductID         uuid.UUID `json:"product_id"`
+	BuyQuantity       int64     `json:"buy_quantity"`
+	GetQuantity       int64     `json:"get_quantity"`
+	StartAt           time.Time `json:"start_at"`
+	EndAt             time.Time `json:"end_at"`
+	UsageLimit        int       `json:"usage_limit"`
+	UsageLimitPerUser int       `json:"usage_limit_per_user"`
+	UsageCount        int       `json:"usage_count"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+func (r *promotionRoutes) createPromotion(ctx *gin.Context) {
+	var req createPromotionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		r.l.Error(err, "http - v1 - promotionRoutes - createPromotion")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	promotion := CreatePromotionRequestToPromotionEntity(req)
+
+	err := r.upc.CreatePromotion(context.Background(), &promotion)
+	if err != nil {
+		r.l.Error(err, "http - v1 - promotionRoutes - createPromotion")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	response := PromotionEntityToPromotionResponse(&promotion)
+
+	ctx.JSON(http.StatusCreated, newCreateSuccess(response))
+}
+
+func (r *promotionRoutes) getAllPromotions(ctx *gin.Context) {
+	var req pageRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		r.l.Error(err, "http - v1 - promotionRoutes - getAllPromotions")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	page, err := PageRequestToPageEntity(req)
+	if err != nil {
+		r.l.Error(err, "http - v1 - promotionRoutes - getAllPromotions")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	promotions, next, err := r.upc.GetAllPromotions(context.Background(), page)
+	if err != nil {
+		r.l.Error(err, "http - v1 - promotionRoutes - getAllPromotions")
+		restErr := newUseCaseError(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	var response []promotionResponse
+	for _, promotion := range promotions {
+		response = append(response, PromotionEntityToPromotionResponse(promotion))
+	}
+
+	ctx.JSON(http.StatusOK, newGetPageSuccess(response, next))
+}
@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"expvar"
 	"net/http"
 
 	"github.com/gin-contrib/cors"
@@ -14,6 +15,14 @@ func NewRouter(
 	handler *gin.Engine,
 	ucq usecase.OrderQuery,
 	uoc usecase.OrderCommand,
+	urq usecase.ReturnQuery,
+	urc usecase.ReturnCommand,
+	ufq usecase.RefundQuery,
+	ufc usecase.RefundCommand,
+	upc usecase.PromotionCommand,
+	usc usecase.ShippingCommand,
+	upr usecase.ProjectionRebuild,
+	upn usecase.ProjectionReconcile,
 	l logger.Interface,
 	auth config.AuthService,
 ) {
@@ -30,9 +39,16 @@ func NewRouter(
 		c.Status(http.StatusOK)
 	})
 	authMid := cognitoMiddleware(auth)
+	// metrics show the process memory, command line and business counters, admins only
+	handler.GET("/debug/vars", authMid, adminMiddleware(), gin.WrapH(expvar.Handler()))
 
 	h := handler.Group("/v1")
 	{
 		newOrderRoutes(h, uoc, ucq, l, authMid)
+		newOrderReturnRoutes(h, urc, urq, l, authMid)
+		newOrderRefundRoutes(h, ufc, ufq, l, authMid)
+		newPromotionRoutes(h, upc, l, authMid)
+		newShippingRoutes(h, usc, l, authMid)
+		newProjectionRoutes(h, upr, upn, l, authMid)
 	}
 }
@@ -0,0 +1,56 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/idoyudha/eshop-order/internal/usecase"
+	"github.com/idoyudha/eshop-order/pkg/logger"
+)
+
+type shippingRoutes struct {
+	usc usecase.ShippingCommand
+	l   logger.Interface
+}
+
+func newShippingRoutes(
+	handler *gin.RouterGroup,
+	usc usecase.ShippingCommand,
+	l logger.Interface,
+	authMid gin.HandlerFunc,
+) {
+	r := &shippingRoutes{usc: usc, l: l}
+
+	h := handler.Group("/admin/shipping-cost-cache").Use(authMid, adminMiddleware())
+	{
+		h.DELETE("", r.invalidateShippingCostCache)
+	}
+}
+
+type invalidateShippingCostCacheRequest struct {
+	FromZip string `form:"from_zip"`
+	ToZip   string `form:"to_zip"`
+}
+
+type invalidateShippingCostCacheResponse struct {
+	Deleted int64 `json:"deleted"`
+}
+
+func (r *shippingRoutes) invalidateShippingCostCache(ctx *gin.Context) {
+	var req invalidateShippingCostCacheRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		r.l.Error(err, "http - v1 - shippingRoutes - invalidateShippingCostCache")
+		ctx.JSON(http.StatusBadRequest, newBadRequestError(err.Error()))
+		return
+	}
+
+	deleted, err := r.usc.InvalidateShippingCostCache(context.Background(), req.FromZip, req.ToZip)
+	if err != nil {
+		r.l.Error(err, "http - v1 - shippingRoutes - invalidateShippingCostCache")
+		ctx.JSON(http.StatusInternalServerError, newInternalServerError(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUpdateSuccess(invalidateShippingCostCacheResponse{Deleted: deleted}))
+}
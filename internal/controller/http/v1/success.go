@@ -1,11 +1,17 @@
 package v1
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/idoyudha/eshop-order/internal/entity"
+)
 
 type restSuccess struct {
 	Code    int    `json:"code"`
 	Data    any    `json:"data"`
 	Message string `json:"message"`
+	// only on list endpoints, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 func newCreateSuccess(data any) restSuccess {
@@ -16,6 +22,15 @@ func newCreateSuccess(data any) restSuccess {
 	}
 }
 
+// the request is taken and runs in the background
+func newAcceptedSuccess(data any) restSuccess {
+	return restSuccess{
+		Code:    http.StatusAccepted,
+		Data:    data,
+		Message: "success accepted",
+	}
+}
+
 func newGetSuccess(data any) restSuccess {
 	return restSuccess{
 		Code:    http.StatusOK,
@@ -24,6 +39,15 @@ func newGetSuccess(data any) restSuccess {
 	}
 }
 
+func newGetPageSuccess(data any, next entity.PageCursor) restSuccess {
+	return restSuccess{
+		Code:       http.StatusOK,
+		Data:       data,
+		Message:    "success get",
+		NextCursor: next.Encode(),
+	}
+}
+
 func newUpdateSuccess(data any) restSuccess {
 	return restSuccess{
 		Code:    http.StatusOK,
@@ -25,22 +25,31 @@ import (
 type kafkaConsumerRoutes struct {
 	ucoq usecase.OrderQuery
 	ucoc usecase.OrderCommand
+	ucrq usecase.ReturnQuery
+	ucfq usecase.RefundQuery
 	l    logger.Interface
 	p    config.ProductService
+	k    config.Kafka
 }
 
 func KafkaNewRouter(
 	ucoq usecase.OrderQuery,
 	ucoc usecase.OrderCommand,
+	ucrq usecase.ReturnQuery,
+	ucfq usecase.RefundQuery,
 	l logger.Interface,
 	c *kafkaConSrv.ConsumerServer,
 	p config.ProductService,
+	k config.Kafka,
 ) error {
 	routes := &kafkaConsumerRoutes{
 		ucoq: ucoq,
 		ucoc: ucoc,
+		ucrq: ucrq,
+		ucfq: ucfq,
 		l:    l,
 		p:    p,
+		k:    k,
 	}
 
 	// Set up a channel for handling Ctrl-C, etc
@@ -71,7 +80,7 @@ func KafkaNewRouter(
 			}
 
 			switch *ev.TopicPartition.Topic {
-			case constant.OrderCreatedTopic:
+			case constant.OrderCreatedTopic, constant.OrderCreatedRepairTopic:
 				if err := routes.handleOrderViewCreated(ev); err != nil {
 					l.Error("Failed to handle order view creted: %w", err)
 				}
@@ -79,10 +88,34 @@ func KafkaNewRouter(
 				if err := routes.handleOrderPaymentUpdated(ev); err != nil {
 					l.Error("Failed to handle order payment updated: %w", err)
 				}
-			case constant.OrderStatusUpdatedTopic:
+			case constant.OrderStatusUpdatedTopic, constant.OrderStatusUpdatedRepairTopic:
 				if err := routes.handleOrderStatusUpdated(ev); err != nil {
 					l.Error("Failed to handle order status updated: %w", err)
 				}
+			case constant.ReturnRequestedTopic:
+				if err := routes.handleReturnViewCreated(ev); err != nil {
+					l.Error("Failed to handle return view created: %w", err)
+				}
+			case constant.ReturnApprovedTopic, constant.ReturnRejectedTopic:
+				if err := routes.handleReturnStatusUpdated(ev); err != nil {
+					l.Error("Failed to handle return status updated: %w", err)
+				}
+			case constant.RefundCreatedTopic:
+				if err := routes.handleRefundViewCreated(ev); err != nil {
+					l.Error("Failed to handle refund view created: %w", err)
+				}
+			case constant.OrderItemsCancelledTopic:
+				if err := routes.handleOrderItemsCancelled(ev); err != nil {
+					l.Error("Failed to handle order items cancelled: %w", err)
+				}
+			case constant.OrderUpdatedTopic, constant.OrderUpdatedRepairTopic:
+				if err := routes.handleOrderViewUpdated(ev); err != nil {
+					l.Error("Failed to handle order view updated: %w", err)
+				}
+			case k.DeliveryTopic:
+				if err := routes.handleDeliveryUpdated(ev); err != nil {
+					l.Error("Failed to handle delivery updated: %w", err)
+				}
 			default:
 				l.Info("Unknown topic: %s", *ev.TopicPartition.Topic)
 			}
@@ -113,10 +146,27 @@ type restSuccess struct {
 }
 
 func kafkaOrderCreatedToOrderView(msg *dto.KafkaOrderCreated) entity.OrderView {
+	// events published before orders were versioned
+	version := msg.Version
+	if version == 0 {
+		version = entity.ORDER_FIRST_VERSION
+	}
+
 	return entity.OrderView{
-		OrderID:    msg.OrderID,
-		UserID:     msg.UserID,
-		TotalPrice: msg.TotalPrice,
+		OrderID:               msg.OrderID,
+		UserID:                msg.UserID,
+		TotalPrice:            msg.TotalPrice,
+		PromotionCode:         msg.PromotionCode,
+		DiscountAmount:        msg.DiscountAmount,
+		TaxAmount:             msg.TaxAmount,
+		ShippingMethod:        msg.ShippingMethod,
+		ShippingEstimatedDays: msg.ShippingEstimatedDays,
+		EstimatedShipping:     msg.EstimatedShipping,
+		ShippingRule:          msg.ShippingRule,
+		FulfilmentType:        msg.FulfilmentType,
+		PickupWarehouseID:     msg.PickupWarehouseID,
+		PickupCode:            msg.PickupCode,
+		Version:               version,
 		Address: entity.OrderAddressView{
 			Street:    msg.Address.Street,
 			City:      msg.Address.City,
@@ -166,15 +216,23 @@ func (r *kafkaConsumerRoutes) handleOrderViewCreated(msg *kafka.Message) error {
 			return fmt.Errorf("failed to decode product response: %w", err)
 		}
 
+		// product without a category is ordered with uuid.Nil
+		categoryID, err := uuid.Parse(restSuccess.Data.CategoryID)
+		if err != nil {
+			categoryID = uuid.Nil
+		}
+
 		items = append(items, entity.OrderItemView{
 			ProductID:          item.ProductID,
 			ProductName:        restSuccess.Data.Name,
 			ProductImageURL:    restSuccess.Data.ImageURL,
 			ProductDescription: restSuccess.Data.Description,
-			ProductCategoryID:  uuid.MustParse(restSuccess.Data.CategoryID),
+			ProductCategoryID:  categoryID,
 			ProductPrice:       restSuccess.Data.Price,
 			ProductQuantity:    item.ProductQuantity,
 			ShippingCost:       item.ShippingCost,
+			Discount:           item.Discount,
+			Tax:                item.Tax,
 		})
 	}
 
@@ -205,8 +263,9 @@ func (r *kafkaConsumerRoutes) handleOrderPaymentUpdated(msg *kafka.Message) erro
 		return fmt.Errorf("failed to update order payment: %w", err)
 	}
 
-	// 2. update order view
+	// 2. update order view, the payment service does not know the order version
 	orderViewEntity := dto.PaymentMessageToOrderViewEntity(message)
+	orderViewEntity.Version = orderEntity.Version
 	err = r.ucoq.UpdateOrderViewPayment(context.Background(), &orderViewEntity, message.Status)
 	if err != nil {
 		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleOrderPaymentUpdated")
@@ -234,3 +293,132 @@ func (r *kafkaConsumerRoutes) handleOrderStatusUpdated(msg *kafka.Message) error
 
 	return nil
 }
+
+func (r *kafkaConsumerRoutes) handleDeliveryUpdated(msg *kafka.Message) error {
+	r.l.Info("Order delivery updating", "http - v1 - kafkaConsumerRoutes - handleDeliveryUpdated")
+	var message dto.KafkaDeliveryUpdated
+	if err := json.Unmarshal(msg.Value, &message); err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleDeliveryUpdated")
+		return err
+	}
+
+	// order view is updated by the order-status-updated event produced by the command side
+	orderEntity := dto.DeliveryMessageToOrderEntity(message)
+	err := r.ucoc.UpdateOrderDelivery(context.Background(), &orderEntity, message.Status)
+	if err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleDeliveryUpdated")
+		return fmt.Errorf("failed to update order delivery: %w", err)
+	}
+
+	return nil
+}
+
+func (r *kafkaConsumerRoutes) handleReturnViewCreated(msg *kafka.Message) error {
+	r.l.Info("Order return creating", "http - v1 - kafkaConsumerRoutes - handleReturnViewCreated")
+	var message dto.KafkaReturnRequested
+	if err := json.Unmarshal(msg.Value, &message); err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleReturnViewCreated")
+		return err
+	}
+
+	returnViewEntity := dto.ReturnRequestedMessageToOrderReturnViewEntity(message)
+	err := r.ucrq.CreateReturnView(context.Background(), &returnViewEntity)
+	if err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleReturnViewCreated")
+		return fmt.Errorf("failed to create order return view: %w", err)
+	}
+
+	// the order timeline counts the returns
+	err = r.ucoq.InvalidateOrderView(context.Background(), returnViewEntity.OrderID)
+	if err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleReturnViewCreated")
+		return err
+	}
+
+	return nil
+}
+
+func (r *kafkaConsumerRoutes) handleReturnStatusUpdated(msg *kafka.Message) error {
+	r.l.Info("Order return status updating", "http - v1 - kafkaConsumerRoutes - handleReturnStatusUpdated")
+	var message dto.KafkaReturnStatusUpdated
+	if err := json.Unmarshal(msg.Value, &message); err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleReturnStatusUpdated")
+		return err
+	}
+
+	returnViewEntity := dto.ReturnStatusUpdatedMessageToOrderReturnViewEntity(message)
+	err := r.ucrq.UpdateReturnViewStatus(context.Background(), &returnViewEntity)
+	if err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleReturnStatusUpdated")
+		return fmt.Errorf("failed to update order return view: %w", err)
+	}
+
+	err = r.ucoq.InvalidateOrderView(context.Background(), message.OrderID)
+	if err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleReturnStatusUpdated")
+		return err
+	}
+
+	return nil
+}
+
+func (r *kafkaConsumerRoutes) handleRefundViewCreated(msg *kafka.Message) error {
+	r.l.Info("Refund creating", "http - v1 - kafkaConsumerRoutes - handleRefundViewCreated")
+	var message dto.KafkaRefundCreated
+	if err := json.Unmarshal(msg.Value, &message); err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleRefundViewCreated")
+		return err
+	}
+
+	refundViewEntity := dto.RefundCreatedMessageToRefundViewEntity(message)
+	err := r.ucfq.CreateRefundView(context.Background(), &refundViewEntity)
+	if err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleRefundViewCreated")
+		return fmt.Errorf("failed to create refund view: %w", err)
+	}
+
+	// the order timeline sums the refunds
+	err = r.ucoq.InvalidateOrderView(context.Background(), refundViewEntity.OrderID)
+	if err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleRefundViewCreated")
+		return err
+	}
+
+	return nil
+}
+
+func (r *kafkaConsumerRoutes) handleOrderItemsCancelled(msg *kafka.Message) error {
+	r.l.Info("Order items cancelling", "http - v1 - kafkaConsumerRoutes - handleOrderItemsCancelled")
+	var message dto.KafkaOrderItemsCancelled
+	if err := json.Unmarshal(msg.Value, &message); err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleOrderItemsCancelled")
+		return err
+	}
+
+	orderViewEntity := dto.OrderItemsCancelledMessageToOrderViewEntity(message)
+	err := r.ucoq.UpdateOrderViewItems(context.Background(), &orderViewEntity)
+	if err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleOrderItemsCancelled")
+		return fmt.Errorf("failed to update order view items: %w", err)
+	}
+
+	return nil
+}
+
+func (r *kafkaConsumerRoutes) handleOrderViewUpdated(msg *kafka.Message) error {
+	r.l.Info("Order view updating", "http - v1 - kafkaConsumerRoutes - handleOrderViewUpdated")
+	var message dto.KafkaOrderUpdated
+	if err := json.Unmarshal(msg.Value, &message); err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleOrderViewUpdated")
+		return err
+	}
+
+	orderViewEntity := dto.OrderUpdatedMessageToOrderViewEntity(message)
+	err := r.ucoq.UpdateOrderView(context.Background(), &orderViewEntity)
+	if err != nil {
+		r.l.Error(err, "http - v1 - kafkaConsumerRoutes - handleOrderViewUpdated")
+		return fmt.Errorf("failed to update order view: %w", err)
+	}
+
+	return nil
+}
@@ -2,11 +2,13 @@ package redis
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/idoyudha/eshop-order/config"
@@ -18,6 +20,18 @@ import (
 
 const expiredKeyEventRedis = "__keyevent@0__:expired"
 
+// prefix of the scheduled keys, must match the keys set in commandrepo.OrderRedisRepo
+const (
+	orderKey            = "order"
+	deliveryConfirmKey  = "delivery-confirm"
+	deliveryReminderKey = "delivery-reminder"
+	saleReportKey       = "sale-report"
+	// cached shipping cost and order view, and the sent reminder marker, expire without any action
+	shippingCostKey = "shipping-cost"
+	orderViewKey    = "order-view"
+	reminderSentKey = "delivery-reminder-sent"
+)
+
 type redisScheduledEvents struct {
 	r    *redis.RedisClient
 	ucoc usecase.OrderCommand
@@ -57,7 +71,7 @@ func NewRedisScheduledEvents(
 
 			for msg := range pubsub.Channel() {
 				log.Println("message received from redis", msg.Payload)
-				if err := events.handleOrderExpired(msg.Payload); err != nil {
+				if err := events.handleExpiredKey(msg.Payload); err != nil {
 					events.l.Error(err, "http - v1 - redisScheduledEvents - handleMessage")
 				}
 			}
@@ -66,12 +80,43 @@ func NewRedisScheduledEvents(
 	return nil
 }
 
-func (e *redisScheduledEvents) handleOrderExpired(expiredKey string) error {
-	e.l.Info("Order expired", "http - v1 - redisScheduledEvents - handleOrderExpired")
+// handleExpiredKey dispatch the expired key to its handler based on the key prefix
+func (e *redisScheduledEvents) handleExpiredKey(expiredKey string) error {
+	if strings.HasPrefix(expiredKey, shippingCostKey+":") || strings.HasPrefix(expiredKey, orderViewKey+":") ||
+		strings.HasPrefix(expiredKey, reminderSentKey+":") {
+		return nil
+	}
 
 	parts := strings.Split(expiredKey, ":")
+	if len(parts) != 2 {
+		return fmt.Errorf("unknown expired key: %s", expiredKey)
+	}
+
+	orderID, err := uuid.Parse(parts[1])
+	if err != nil {
+		return fmt.Errorf("failed to parse order id from expired key: %w", err)
+	}
+
+	switch parts[0] {
+	case orderKey:
+		return e.handleOrderExpired(orderID)
+	case deliveryConfirmKey:
+		return e.handleDeliveryConfirmExpired(orderID)
+	case deliveryReminderKey:
+		return e.handleDeliveryReminderExpired(orderID)
+	case saleReportKey:
+		return e.handleSaleReportExpired(orderID)
+	default:
+		return fmt.Errorf("unknown expired key: %s", expiredKey)
+	}
+}
+
+func (e *redisScheduledEvents) handleOrderExpired(orderID uuid.UUID) error {
+	e.l.Info("Order expired", "http - v1 - redisScheduledEvents - handleOrderExpired")
+
 	order := entity.Order{
-		ID: uuid.MustParse(parts[1]),
+		ID:        orderID,
+		UpdatedAt: time.Now(),
 	}
 
 	err := e.ucoc.UpdateOrderStatus(context.Background(), &order, entity.ORDER_EXPIRED)
@@ -81,3 +126,21 @@ func (e *redisScheduledEvents) handleOrderExpired(expiredKey string) error {
 
 	return nil
 }
+
+func (e *redisScheduledEvents) handleDeliveryConfirmExpired(orderID uuid.UUID) error {
+	e.l.Info("Order delivery auto confirm", "http - v1 - redisScheduledEvents - handleDeliveryConfirmExpired")
+
+	return e.ucoc.AutoConfirmDelivery(context.Background(), orderID)
+}
+
+func (e *redisScheduledEvents) handleDeliveryReminderExpired(orderID uuid.UUID) error {
+	e.l.Info("Order delivery reminder", "http - v1 - redisScheduledEvents - handleDeliveryReminderExpired")
+
+	return e.ucoc.SendDeliveryReminder(context.Background(), orderID)
+}
+
+func (e *redisScheduledEvents) handleSaleReportExpired(orderID uuid.UUID) error {
+	e.l.Info("Order sale report retry", "http - v1 - redisScheduledEvents - handleSaleReportExpired")
+
+	return e.ucoc.ReportSale(context.Background(), orderID)
+}
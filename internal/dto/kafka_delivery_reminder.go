@@ -0,0 +1,14 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sent to notify the customer before the delivery is confirmed automatically
+type KafkaDeliveryReminder struct {
+	OrderID       uuid.UUID `json:"order_id"`
+	UserID        uuid.UUID `json:"user_id"`
+	AutoConfirmAt time.Time `json:"auto_confirm_at"`
+}
@@ -0,0 +1,10 @@
+package dto
+
+import "github.com/google/uuid"
+
+type KafkaDeliveryUpdated struct {
+	OrderID        uuid.UUID `json:"orderId"`
+	TrackingNumber string    `json:"trackingNumber"`
+	Status         string    `json:"status"`
+	Note           string    `json:"note"`
+}
@@ -0,0 +1,16 @@
+package dto
+
+import "github.com/google/uuid"
+
+// digital items of a paid order, consumed by the service that delivers the licence or download
+type KafkaDigitalFulfilment struct {
+	OrderID uuid.UUID                    `json:"order_id"`
+	UserID  uuid.UUID                    `json:"user_id"`
+	Items   []KafkaDigitalFulfilmentItem `json:"items"`
+}
+
+type KafkaDigitalFulfilmentItem struct {
+	ItemID          uuid.UUID `json:"item_id"`
+	ProductID       uuid.UUID `json:"product_id"`
+	ProductQuantity int64     `json:"product_quantity"`
+}
@@ -4,11 +4,22 @@ import "github.com/google/uuid"
 
 // this dto is used to send order created event to kafka in the same service, to prevent repetitive struct
 type KafkaOrderCreated struct {
-	OrderID    uuid.UUID                `json:"order_id"`
-	UserID     uuid.UUID                `json:"user_id"`
-	TotalPrice float64                  `json:"total_price"`
-	Items      []KafkaOrderItemsCreated `json:"items"`
-	Address    KafkaOrderAddressCreated `json:"address"`
+	OrderID               uuid.UUID                `json:"order_id"`
+	UserID                uuid.UUID                `json:"user_id"`
+	TotalPrice            float64                  `json:"total_price"`
+	PromotionCode         string                   `json:"promotion_code"`
+	DiscountAmount        float64                  `json:"discount_amount"`
+	TaxAmount             float64                  `json:"tax_amount"`
+	ShippingMethod        string                   `json:"shipping_method"`
+	ShippingEstimatedDays int                      `json:"shipping_estimated_days"`
+	EstimatedShipping     bool                     `json:"estimated_shipping"`
+	ShippingRule          string                   `json:"shipping_rule"`
+	FulfilmentType        string                   `json:"fulfilment_type"`
+	PickupWarehouseID     uuid.UUID                `json:"pickup_warehouse_id"`
+	PickupCode            string                   `json:"pickup_code"`
+	Version               int                      `json:"version"`
+	Items                 []KafkaOrderItemsCreated `json:"items"`
+	Address               KafkaOrderAddressCreated `json:"address"`
 }
 
 type KafkaOrderItemsCreated struct {
@@ -16,6 +27,8 @@ type KafkaOrderItemsCreated struct {
 	ProductID       uuid.UUID `json:"product_id"`
 	ProductQuantity int64     `json:"product_quantity"`
 	ShippingCost    float64   `json:"shipping_cost"`
+	Discount        float64   `json:"discount"`
+	Tax             float64   `json:"tax"`
 	Note            string    `json:"note"`
 }
 
@@ -0,0 +1,23 @@
+package dto
+
+import "github.com/google/uuid"
+
+type KafkaOrderItemsCancelled struct {
+	OrderID        uuid.UUID                 `json:"order_id"`
+	Status         string                    `json:"status"`
+	TotalPrice     float64                   `json:"total_price"`
+	DiscountAmount float64                   `json:"discount_amount"`
+	TaxAmount      float64                   `json:"tax_amount"`
+	Version        int                       `json:"version"`
+	Items          []KafkaOrderItemCancelled `json:"items"`
+}
+
+// product quantity is the quantity left after the cancellation
+type KafkaOrderItemCancelled struct {
+	ProductID         uuid.UUID `json:"product_id"`
+	ProductQuantity   int64     `json:"product_quantity"`
+	CancelledQuantity int64     `json:"cancelled_quantity"`
+	ShippingCost      float64   `json:"shipping_cost"`
+	Discount          float64   `json:"discount"`
+	Tax               float64   `json:"tax"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+import "github.com/google/uuid"
+
+// pickup order is ready at the warehouse or collected by the customer,
+// pickup code is only sent when the order is ready so the customer can be notified
+type KafkaOrderPickup struct {
+	OrderID     uuid.UUID `json:"order_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	WarehouseID uuid.UUID `json:"warehouse_id"`
+	Status      string    `json:"status"`
+	PickupCode  string    `json:"pickup_code,omitempty"`
+}
@@ -3,6 +3,8 @@ package dto
 import "github.com/google/uuid"
 
 type KafkaOrderStatusUpdated struct {
-	OrderID uuid.UUID `json:"orderId"`
-	Status  string    `json:"status"`
+	OrderID   uuid.UUID `json:"orderId"`
+	Status    string    `json:"status"`
+	UpdatedBy string    `json:"updatedBy"`
+	Version   int       `json:"version"`
 }
@@ -0,0 +1,17 @@
+package dto
+
+import "github.com/google/uuid"
+
+// pending order edited by the user, items and address are the state after the edit
+type KafkaOrderUpdated struct {
+	OrderID               uuid.UUID                `json:"order_id"`
+	TotalPrice            float64                  `json:"total_price"`
+	DiscountAmount        float64                  `json:"discount_amount"`
+	TaxAmount             float64                  `json:"tax_amount"`
+	ShippingEstimatedDays int                      `json:"shipping_estimated_days"`
+	EstimatedShipping     bool                     `json:"estimated_shipping"`
+	ShippingRule          string                   `json:"shipping_rule"`
+	Version               int                      `json:"version"`
+	Items                 []KafkaOrderItemsCreated `json:"items"`
+	Address               KafkaOrderAddressCreated `json:"address"`
+}
@@ -0,0 +1,22 @@
+package dto
+
+import "github.com/google/uuid"
+
+// consumed by payment service to pay back the customer
+type KafkaRefundCreated struct {
+	RefundID  uuid.UUID                `json:"refund_id"`
+	OrderID   uuid.UUID                `json:"order_id"`
+	UserID    uuid.UUID                `json:"user_id"`
+	PaymentID uuid.UUID                `json:"payment_id"`
+	ReturnID  uuid.UUID                `json:"return_id"`
+	Amount    float64                  `json:"amount"`
+	Reason    string                   `json:"reason"`
+	Status    string                   `json:"status"`
+	Items     []KafkaRefundItemCreated `json:"items"`
+}
+
+type KafkaRefundItemCreated struct {
+	ProductID       uuid.UUID `json:"product_id"`
+	ProductQuantity int64     `json:"product_quantity"`
+	Price           float64   `json:"price"`
+}
@@ -0,0 +1,27 @@
+package dto
+
+import "github.com/google/uuid"
+
+type KafkaReturnRequested struct {
+	ReturnID     uuid.UUID                  `json:"return_id"`
+	OrderID      uuid.UUID                  `json:"order_id"`
+	UserID       uuid.UUID                  `json:"user_id"`
+	Reason       string                     `json:"reason"`
+	RefundAmount float64                    `json:"refund_amount"`
+	Items        []KafkaReturnItemRequested `json:"items"`
+}
+
+type KafkaReturnItemRequested struct {
+	ProductID       uuid.UUID `json:"product_id"`
+	ProductQuantity int64     `json:"product_quantity"`
+	Price           float64   `json:"price"`
+}
+
+type KafkaReturnStatusUpdated struct {
+	ReturnID     uuid.UUID `json:"return_id"`
+	OrderID      uuid.UUID `json:"order_id"`
+	UserID       uuid.UUID `json:"user_id"`
+	Status       string    `json:"status"`
+	AdminNote    string    `json:"admin_note"`
+	RefundAmount float64   `json:"refund_amount"`
+}
@@ -1,15 +1,42 @@
 package dto
 
-import "github.com/google/uuid"
+import (
+	"time"
 
+	"github.com/google/uuid"
+)
+
+// version of the sale created message, increase it when the breakdown changes
+const KafkaSaleCreatedVersion = 2
+
+// KafkaSaleCreated is the financial breakdown of a paid order.
+// version 1 only had order id, user id and the product id, quantity and price of the items,
+// those fields are kept so version 1 consumers still work.
 type KafkaSaleCreated struct {
-	OrderID uuid.UUID              `json:"order_id"`
-	UserID  uuid.UUID              `json:"user_id"`
-	Items   []KafkaSaleItemCreated `json:"items"`
+	Version        int                    `json:"version"`
+	OrderID        uuid.UUID              `json:"order_id"`
+	UserID         uuid.UUID              `json:"user_id"`
+	Currency       string                 `json:"currency"`
+	PromotionCode  string                 `json:"promotion_code"`
+	Subtotal       float64                `json:"subtotal"`
+	ShippingCost   float64                `json:"shipping_cost"`
+	DiscountAmount float64                `json:"discount_amount"`
+	TaxAmount      float64                `json:"tax_amount"`
+	TotalPrice     float64                `json:"total_price"`
+	PaidAmount     float64                `json:"paid_amount"`
+	PaidAt         time.Time              `json:"paid_at"`
+	Items          []KafkaSaleItemCreated `json:"items"`
 }
 
 type KafkaSaleItemCreated struct {
-	ProductID uuid.UUID `json:"product_id"`
-	Quantity  int64     `json:"quantity"`
-	Price     float64   `json:"price"`
+	ProductID        uuid.UUID `json:"product_id"`
+	Quantity         int64     `json:"quantity"`
+	Price            float64   `json:"price"` // same as unit price, kept for version 1
+	UnitPrice        float64   `json:"unit_price"`
+	Subtotal         float64   `json:"subtotal"`
+	Discount         float64   `json:"discount"`
+	ShippingCost     float64   `json:"shipping_cost"`
+	ShippingDiscount float64   `json:"shipping_discount"`
+	Tax              float64   `json:"tax"`
+	Total            float64   `json:"total"`
 }
@@ -0,0 +1,18 @@
+package dto
+
+import "github.com/google/uuid"
+
+// compensate the sale-created event, so the sales report does not count refunded money
+type KafkaSaleReversed struct {
+	OrderID  uuid.UUID               `json:"order_id"`
+	UserID   uuid.UUID               `json:"user_id"`
+	RefundID uuid.UUID               `json:"refund_id"`
+	Amount   float64                 `json:"amount"`
+	Items    []KafkaSaleItemReversed `json:"items"`
+}
+
+type KafkaSaleItemReversed struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Quantity  int64     `json:"quantity"`
+	Price     float64   `json:"price"`
+}
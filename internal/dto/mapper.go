@@ -9,10 +9,21 @@ import (
 
 func OrderEntityToKafkaOrderCreatedMessage(order *entity.Order) KafkaOrderCreated {
 	return KafkaOrderCreated{
-		OrderID:    order.ID,
-		UserID:     order.UserID,
-		TotalPrice: order.TotalPrice,
-		Items:      orderItemEntityToKafkaOrderItemsCreated(order.Items),
+		OrderID:               order.ID,
+		UserID:                order.UserID,
+		TotalPrice:            order.TotalPrice,
+		PromotionCode:         order.PromotionCode,
+		DiscountAmount:        order.DiscountAmount,
+		TaxAmount:             order.TaxAmount,
+		ShippingMethod:        order.ShippingMethod,
+		ShippingEstimatedDays: order.ShippingEstimatedDays,
+		EstimatedShipping:     order.EstimatedShipping,
+		ShippingRule:          order.ShippingRule,
+		FulfilmentType:        order.FulfilmentType,
+		PickupWarehouseID:     order.PickupWarehouseID,
+		PickupCode:            order.PickupCode,
+		Version:               order.Version,
+		Items:                 orderItemEntityToKafkaOrderItemsCreated(order.Items),
 		Address: KafkaOrderAddressCreated{
 			OrderID: order.Address.OrderID,
 			Street:  order.Address.Street,
@@ -32,6 +43,8 @@ func orderItemEntityToKafkaOrderItemsCreated(items []entity.OrderItem) []KafkaOr
 			ProductID:       item.ProductID,
 			ProductQuantity: item.ProductQuantity,
 			ShippingCost:    item.ShippingCost,
+			Discount:        item.TotalDiscount(),
+			Tax:             item.Tax,
 			Note:            item.Note,
 		})
 	}
@@ -59,8 +72,17 @@ func PaymentMessageToOrderViewEntity(message KafkaPaymentUpdated) entity.OrderVi
 
 func OrderEntityToKafkaOrderStatusUpdatedMessage(order *entity.Order) KafkaOrderStatusUpdated {
 	return KafkaOrderStatusUpdated{
-		OrderID: order.ID,
-		Status:  order.Status,
+		OrderID:   order.ID,
+		Status:    order.Status,
+		UpdatedBy: order.UpdatedBy,
+		Version:   order.Version,
+	}
+}
+
+func DeliveryMessageToOrderEntity(msg KafkaDeliveryUpdated) entity.Order {
+	return entity.Order{
+		ID:        msg.OrderID,
+		UpdatedAt: time.Now(),
 	}
 }
 
@@ -68,27 +90,330 @@ func OrderStatusUpdatedMessageToOrderViewEntity(msg KafkaOrderStatusUpdated) ent
 	return entity.OrderView{
 		OrderID:   msg.OrderID,
 		Status:    msg.Status,
+		Version:   msg.Version,
 		UpdatedAt: time.Now(),
 	}
 }
 
-func OrderEntityToKafkaSaleCreatedMessage(order *entity.Order, products map[uuid.UUID]float64) KafkaSaleCreated {
+func OrderEntityToKafkaDeliveryReminderMessage(order *entity.Order, autoConfirmAt time.Time) KafkaDeliveryReminder {
+	return KafkaDeliveryReminder{
+		OrderID:       order.ID,
+		UserID:        order.UserID,
+		AutoConfirmAt: autoConfirmAt,
+	}
+}
+
+func OrderEntityToKafkaSaleCreatedMessage(order *entity.Order, currency string) KafkaSaleCreated {
 	return KafkaSaleCreated{
-		OrderID: order.ID,
-		UserID:  order.UserID,
-		Items:   orderItemEntityToKafkaSaleItemsCreated(order.Items, products),
+		Version:        KafkaSaleCreatedVersion,
+		OrderID:        order.ID,
+		UserID:         order.UserID,
+		Currency:       currency,
+		PromotionCode:  order.PromotionCode,
+		Subtotal:       order.Subtotal(),
+		ShippingCost:   order.ShippingCost(),
+		DiscountAmount: order.DiscountAmount,
+		TaxAmount:      order.TaxAmount,
+		TotalPrice:     order.TotalPrice,
+		PaidAmount:     order.PaidAmount,
+		PaidAt:         order.PaidAt,
+		Items:          orderItemEntityToKafkaSaleItemsCreated(order.Items),
 	}
 }
 
-func orderItemEntityToKafkaSaleItemsCreated(items []entity.OrderItem, products map[uuid.UUID]float64) []KafkaSaleItemCreated {
+func orderItemEntityToKafkaSaleItemsCreated(items []entity.OrderItem) []KafkaSaleItemCreated {
 	var kafkaItems []KafkaSaleItemCreated
 	for _, item := range items {
+		subtotal := item.Price * float64(item.ProductQuantity)
 		kafkaItems = append(kafkaItems, KafkaSaleItemCreated{
+			ProductID:        item.ProductID,
+			Quantity:         item.ProductQuantity,
+			Price:            item.Price,
+			UnitPrice:        item.Price,
+			Subtotal:         subtotal,
+			Discount:         item.Discount,
+			ShippingCost:     item.ShippingCost,
+			ShippingDiscount: item.ShippingDiscount,
+			Tax:              item.Tax,
+			Total:            subtotal - item.Discount + item.ShippingCost - item.ShippingDiscount + item.Tax,
+		})
+	}
+
+	return kafkaItems
+}
+
+func OrderReturnEntityToKafkaReturnRequestedMessage(orderReturn *entity.OrderReturn) KafkaReturnRequested {
+	var items []KafkaReturnItemRequested
+	for _, item := range orderReturn.Items {
+		items = append(items, KafkaReturnItemRequested{
+			ProductID:       item.ProductID,
+			ProductQuantity: item.ProductQuantity,
+			Price:           item.Price,
+		})
+	}
+
+	return KafkaReturnRequested{
+		ReturnID:     orderReturn.ID,
+		OrderID:      orderReturn.OrderID,
+		UserID:       orderReturn.UserID,
+		Reason:       orderReturn.Reason,
+		RefundAmount: orderReturn.RefundAmount,
+		Items:        items,
+	}
+}
+
+func ReturnRequestedMessageToOrderReturnViewEntity(msg KafkaReturnRequested) entity.OrderReturnView {
+	var items []entity.OrderReturnItemView
+	for _, item := range msg.Items {
+		items = append(items, entity.OrderReturnItemView{
+			ProductID:       item.ProductID,
+			ProductQuantity: item.ProductQuantity,
+			Price:           item.Price,
+			CreatedAt:       time.Now(),
+			UpdatedAt:       time.Now(),
+		})
+	}
+
+	return entity.OrderReturnView{
+		ReturnID:     msg.ReturnID,
+		OrderID:      msg.OrderID,
+		UserID:       msg.UserID,
+		Reason:       msg.Reason,
+		RefundAmount: msg.RefundAmount,
+		Items:        items,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+}
+
+func OrderReturnEntityToKafkaReturnStatusUpdatedMessage(orderReturn *entity.OrderReturn) KafkaReturnStatusUpdated {
+	return KafkaReturnStatusUpdated{
+		ReturnID:     orderReturn.ID,
+		OrderID:      orderReturn.OrderID,
+		UserID:       orderReturn.UserID,
+		Status:       orderReturn.Status,
+		AdminNote:    orderReturn.AdminNote,
+		RefundAmount: orderReturn.RefundAmount,
+	}
+}
+
+func ReturnStatusUpdatedMessageToOrderReturnViewEntity(msg KafkaReturnStatusUpdated) entity.OrderReturnView {
+	return entity.OrderReturnView{
+		ReturnID:  msg.ReturnID,
+		Status:    msg.Status,
+		AdminNote: msg.AdminNote,
+		UpdatedAt: time.Now(),
+	}
+}
+
+func RefundEntityToKafkaRefundCreatedMessage(refund *entity.Refund, paymentID uuid.UUID) KafkaRefundCreated {
+	var items []KafkaRefundItemCreated
+	for _, item := range refund.Items {
+		items = append(items, KafkaRefundItemCreated{
+			ProductID:       item.ProductID,
+			ProductQuantity: item.ProductQuantity,
+			Price:           item.Price,
+		})
+	}
+
+	return KafkaRefundCreated{
+		RefundID:  refund.ID,
+		OrderID:   refund.OrderID,
+		UserID:    refund.UserID,
+		PaymentID: paymentID,
+		ReturnID:  refund.ReturnID,
+		Amount:    refund.Amount,
+		Reason:    refund.Reason,
+		Status:    refund.Status,
+		Items:     items,
+	}
+}
+
+func RefundCreatedMessageToRefundViewEntity(msg KafkaRefundCreated) entity.RefundView {
+	var items []entity.RefundItemView
+	for _, item := range msg.Items {
+		items = append(items, entity.RefundItemView{
+			ProductID:       item.ProductID,
+			ProductQuantity: item.ProductQuantity,
+			Price:           item.Price,
+			CreatedAt:       time.Now(),
+			UpdatedAt:       time.Now(),
+		})
+	}
+
+	return entity.RefundView{
+		RefundID:  msg.RefundID,
+		OrderID:   msg.OrderID,
+		UserID:    msg.UserID,
+		ReturnID:  msg.ReturnID,
+		Amount:    msg.Amount,
+		Reason:    msg.Reason,
+		Status:    msg.Status,
+		Items:     items,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func RefundEntityToKafkaSaleReversedMessage(refund *entity.Refund) KafkaSaleReversed {
+	var items []KafkaSaleItemReversed
+	for _, item := range refund.Items {
+		items = append(items, KafkaSaleItemReversed{
 			ProductID: item.ProductID,
 			Quantity:  item.ProductQuantity,
-			Price:     products[item.ProductID],
+			Price:     item.Price,
 		})
 	}
 
-	return kafkaItems
+	return KafkaSaleReversed{
+		OrderID:  refund.OrderID,
+		UserID:   refund.UserID,
+		RefundID: refund.ID,
+		Amount:   refund.Amount,
+		Items:    items,
+	}
+}
+
+func OrderEntityToKafkaOrderItemsCancelledMessage(order *entity.Order, cancelled []entity.OrderItem) KafkaOrderItemsCancelled {
+	cancelledQuantity := make(map[uuid.UUID]int64)
+	for _, item := range cancelled {
+		cancelledQuantity[item.ProductID] += item.ProductQuantity
+	}
+
+	var items []KafkaOrderItemCancelled
+	for _, item := range order.Items {
+		if _, ok := cancelledQuantity[item.ProductID]; !ok {
+			continue
+		}
+		items = append(items, KafkaOrderItemCancelled{
+			ProductID:         item.ProductID,
+			ProductQuantity:   item.ProductQuantity,
+			CancelledQuantity: cancelledQuantity[item.ProductID],
+			ShippingCost:      item.ShippingCost,
+			Discount:          item.TotalDiscount(),
+			Tax:               item.Tax,
+		})
+	}
+
+	return KafkaOrderItemsCancelled{
+		OrderID:        order.ID,
+		Status:         order.Status,
+		TotalPrice:     order.TotalPrice,
+		DiscountAmount: order.DiscountAmount,
+		TaxAmount:      order.TaxAmount,
+		Version:        order.Version,
+		Items:          items,
+	}
+}
+
+func OrderItemsCancelledMessageToOrderViewEntity(msg KafkaOrderItemsCancelled) entity.OrderView {
+	var items []entity.OrderItemView
+	for _, item := range msg.Items {
+		items = append(items, entity.OrderItemView{
+			ProductID:       item.ProductID,
+			ProductQuantity: item.ProductQuantity,
+			ShippingCost:    item.ShippingCost,
+			Discount:        item.Discount,
+			Tax:             item.Tax,
+			UpdatedAt:       time.Now(),
+		})
+	}
+
+	return entity.OrderView{
+		OrderID:        msg.OrderID,
+		Status:         msg.Status,
+		TotalPrice:     msg.TotalPrice,
+		DiscountAmount: msg.DiscountAmount,
+		TaxAmount:      msg.TaxAmount,
+		Version:        msg.Version,
+		Items:          items,
+		UpdatedAt:      time.Now(),
+	}
+}
+
+func OrderEntityToKafkaOrderPickupMessage(order *entity.Order) KafkaOrderPickup {
+	message := KafkaOrderPickup{
+		OrderID:     order.ID,
+		UserID:      order.UserID,
+		WarehouseID: order.PickupWarehouseID,
+		Status:      order.Status,
+	}
+	if order.Status == entity.ORDER_READY_FOR_PICKUP {
+		message.PickupCode = order.PickupCode
+	}
+	return message
+}
+
+func OrderEntityToKafkaDigitalFulfilmentMessage(order *entity.Order) KafkaDigitalFulfilment {
+	digitalItems := order.DigitalItems()
+	items := make([]KafkaDigitalFulfilmentItem, len(digitalItems))
+	for i, item := range digitalItems {
+		items[i] = KafkaDigitalFulfilmentItem{
+			ItemID:          item.ID,
+			ProductID:       item.ProductID,
+			ProductQuantity: item.ProductQuantity,
+		}
+	}
+	return KafkaDigitalFulfilment{
+		OrderID: order.ID,
+		UserID:  order.UserID,
+		Items:   items,
+	}
+}
+
+func OrderEntityToKafkaOrderUpdatedMessage(order *entity.Order) KafkaOrderUpdated {
+	return KafkaOrderUpdated{
+		OrderID:               order.ID,
+		TotalPrice:            order.TotalPrice,
+		DiscountAmount:        order.DiscountAmount,
+		TaxAmount:             order.TaxAmount,
+		ShippingEstimatedDays: order.ShippingEstimatedDays,
+		EstimatedShipping:     order.EstimatedShipping,
+		ShippingRule:          order.ShippingRule,
+		Version:               order.Version,
+		Items:                 orderItemEntityToKafkaOrderItemsCreated(order.Items),
+		Address: KafkaOrderAddressCreated{
+			OrderID: order.Address.OrderID,
+			Street:  order.Address.Street,
+			City:    order.Address.City,
+			State:   order.Address.State,
+			ZipCode: order.Address.ZipCode,
+			Note:    order.Address.Note,
+		},
+	}
+}
+
+func OrderUpdatedMessageToOrderViewEntity(msg KafkaOrderUpdated) entity.OrderView {
+	var items []entity.OrderItemView
+	for _, item := range msg.Items {
+		items = append(items, entity.OrderItemView{
+			ProductID:       item.ProductID,
+			ProductQuantity: item.ProductQuantity,
+			ShippingCost:    item.ShippingCost,
+			Discount:        item.Discount,
+			Tax:             item.Tax,
+			UpdatedAt:       time.Now(),
+		})
+	}
+
+	return entity.OrderView{
+		OrderID:               msg.OrderID,
+		TotalPrice:            msg.TotalPrice,
+		DiscountAmount:        msg.DiscountAmount,
+		TaxAmount:             msg.TaxAmount,
+		ShippingEstimatedDays: msg.ShippingEstimatedDays,
+		EstimatedShipping:     msg.EstimatedShipping,
+		ShippingRule:          msg.ShippingRule,
+		Version:               msg.Version,
+		Items:                 items,
+		Address: entity.OrderAddressView{
+			Street:    msg.Address.Street,
+			City:      msg.Address.City,
+			State:     msg.Address.State,
+			ZipCode:   msg.Address.ZipCode,
+			Note:      msg.Address.Note,
+			UpdatedAt: time.Now(),
+		},
+		UpdatedAt: time.Now(),
+	}
 }
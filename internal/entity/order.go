@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"crypto/rand"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,6 +14,20 @@ const (
 	ORDER_DELIVERED        = "DELIVERED"
 	ORDER_REJECTED         = "REJECTED"
 	ORDER_EXPIRED          = "EXPIRED"
+	ORDER_DELIVERY_FAILED  = "DELIVERY_FAILED"
+	ORDER_RETURNING        = "RETURNING"
+	ORDER_CANCELLED        = "CANCELLED"
+	ORDER_READY_FOR_PICKUP = "READY_FOR_PICKUP"
+	ORDER_PICKED_UP        = "PICKED_UP"
+	ORDER_FULFILLED        = "FULFILLED"
+)
+
+// how the order reaches the customer
+const (
+	FULFILMENT_DELIVERY = "DELIVERY"
+	FULFILMENT_PICKUP   = "PICKUP"
+	// every item is digital, delivered by the digital fulfilment service
+	FULFILMENT_DIGITAL = "DIGITAL"
 )
 
 const (
@@ -21,17 +36,71 @@ const (
 	ORDER_PAYMENT_REJECTED = "REJECTED"
 )
 
+// actor recorded when the order status is changed by the system
+const (
+	ACTOR_SYSTEM_AUTO_CONFIRM = "system-auto-confirm"
+)
+
+// status reported by the courier in delivery events
+const (
+	DELIVERY_PICKED_UP  = "PICKED_UP"
+	DELIVERY_IN_TRANSIT = "IN_TRANSIT"
+	DELIVERY_DELIVERED  = "DELIVERED"
+	DELIVERY_FAILED     = "FAILED_DELIVERY"
+)
+
+// order statuses a courier event is applied from, a late or repeated event for an order
+// that is delivered, cancelled or returned is ignored
+var deliveryFromStatuses = map[string][]string{
+	DELIVERY_PICKED_UP:  {ORDER_ON_DELIVERY, ORDER_DELIVERY_FAILED},
+	DELIVERY_IN_TRANSIT: {ORDER_ON_DELIVERY, ORDER_DELIVERY_FAILED},
+	DELIVERY_DELIVERED:  {ORDER_ON_DELIVERY, ORDER_DELIVERY_FAILED},
+	DELIVERY_FAILED:     {ORDER_ON_DELIVERY, ORDER_DELIVERY_FAILED},
+}
+
+// DeliveryFromStatuses is the order statuses the courier event can be applied from
+func DeliveryFromStatuses(deliveryStatus string) []string {
+	return deliveryFromStatuses[deliveryStatus]
+}
+
 type Order struct {
-	ID         uuid.UUID
-	UserID     uuid.UUID
-	Status     string
-	TotalPrice float64
-	PaymentID  uuid.UUID
-	Items      []OrderItem
-	Address    OrderAddress
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
-	DeletedAt  time.Time
+	ID                    uuid.UUID
+	UserID                uuid.UUID
+	Status                string
+	TotalPrice            float64
+	PaidAmount            float64
+	PromotionID           uuid.UUID
+	PromotionCode         string
+	DiscountAmount        float64
+	TaxAmount             float64
+	ShippingMethod        string
+	ShippingEstimatedDays int
+	EstimatedShipping     bool
+	ShippingRule          string
+	FulfilmentType        string
+	PickupWarehouseID     uuid.UUID
+	PickupCode            string
+	PaymentID             uuid.UUID
+	Version               int
+	DeliveryAttempts      int
+	UpdatedBy             string
+	PaidAt                time.Time
+	SaleReportedAt        time.Time
+	ShippedAt             time.Time
+	DeliveredAt           time.Time
+	Items                 []OrderItem
+	Address               OrderAddress
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	DeletedAt             time.Time
+}
+
+// ProjectionToken of the current version, the client use it to read its own write
+func (o *Order) ProjectionToken() ProjectionToken {
+	return ProjectionToken{
+		OrderID: o.ID,
+		Version: o.Version,
+	}
 }
 
 func (o *Order) GenerateOrderID() error {
@@ -47,6 +116,37 @@ func (o *Order) GenerateOrderID() error {
 // admin accept payment and set the order status to ON_DELIVERY
 func (o *Order) SetStatusToOnDelivery() {
 	o.Status = ORDER_ON_DELIVERY
+	o.PaidAt = time.Now()
+}
+
+// admin accept payment of a pickup order, the warehouse prepares the items for the customer
+func (o *Order) SetStatusToReadyForPickup() {
+	o.Status = ORDER_READY_FOR_PICKUP
+	o.PaidAt = time.Now()
+}
+
+// admin accept payment of a digital order, nothing is shipped
+func (o *Order) SetStatusToFulfilled() {
+	o.Status = ORDER_FULFILLED
+	o.PaidAt = time.Now()
+}
+
+// SetStatusToPaid move the order to the next status after the payment is approved
+func (o *Order) SetStatusToPaid() {
+	switch o.FulfilmentType {
+	case FULFILMENT_PICKUP:
+		o.SetStatusToReadyForPickup()
+	case FULFILMENT_DIGITAL:
+		o.SetStatusToFulfilled()
+	default:
+		o.SetStatusToOnDelivery()
+	}
+}
+
+// customer collect the items from the warehouse, return window starts like a delivered order
+func (o *Order) SetStatusToPickedUp() {
+	o.Status = ORDER_PICKED_UP
+	o.DeliveredAt = time.Now()
 }
 
 // admin reject payment and set the order status to REJECTED
@@ -62,6 +162,7 @@ func (o *Order) SetStatusToPending() {
 // user accept the delivery
 func (o *Order) SetStatusToDelivered() {
 	o.Status = ORDER_DELIVERED
+	o.DeliveredAt = time.Now()
 }
 
 // order is expired
@@ -69,6 +170,21 @@ func (o *Order) SetStatusToExpired() {
 	o.Status = ORDER_EXPIRED
 }
 
+// every item of the order is cancelled
+func (o *Order) SetStatusToCancelled() {
+	o.Status = ORDER_CANCELLED
+}
+
+// courier failed to deliver, waiting for the next delivery attempt
+func (o *Order) SetStatusToDeliveryFailed() {
+	o.Status = ORDER_DELIVERY_FAILED
+}
+
+// courier gave up delivering, the package is returned to the warehouse
+func (o *Order) SetStatusToReturning() {
+	o.Status = ORDER_RETURNING
+}
+
 func (o *Order) AddShippingCost(shippingCost float64) {
 	o.TotalPrice += shippingCost
 }
@@ -76,3 +192,196 @@ func (o *Order) AddShippingCost(shippingCost float64) {
 func (o *Order) AddTotalPrice(price float64) {
 	o.TotalPrice += price
 }
+
+func (o *Order) ReduceTotalPrice(price float64) {
+	o.TotalPrice -= price
+}
+
+// subtotal of the items price, without shipping cost and discount
+func (o *Order) Subtotal() float64 {
+	var subtotal float64
+	for _, item := range o.Items {
+		subtotal += item.Price * float64(item.ProductQuantity)
+	}
+	return subtotal
+}
+
+// ClearDiscount remove the applied promotion discount and put it back to the total price
+func (o *Order) ClearDiscount() {
+	o.TotalPrice += o.DiscountAmount
+	o.DiscountAmount = 0
+	for i := range o.Items {
+		o.Items[i].Discount = 0
+		o.Items[i].ShippingDiscount = 0
+	}
+}
+
+func (o *Order) ReduceDiscount(discount float64) {
+	o.DiscountAmount -= discount
+}
+
+func (o *Order) AddTax(tax float64) {
+	o.TaxAmount += tax
+	o.TotalPrice += tax
+}
+
+func (o *Order) ReduceTax(tax float64) {
+	o.TaxAmount -= tax
+}
+
+// ClearTax remove the tax of the order and the items from the total price
+func (o *Order) ClearTax() {
+	o.TotalPrice -= o.TaxAmount
+	o.TaxAmount = 0
+	for i := range o.Items {
+		o.Items[i].Tax = 0
+	}
+}
+
+// ApplyShippingEstimate keep the estimated days of the slowest item with the chosen shipping method,
+// and flag the order when any item is priced from the local rate table
+func (o *Order) ApplyShippingEstimate(rate ShippingRate) {
+	o.ShippingEstimatedDays = max(o.ShippingEstimatedDays, rate.EstimatedDays)
+	o.EstimatedShipping = o.EstimatedShipping || rate.Estimated
+}
+
+// DistributeShippingCost replace the shipping cost of the items at the indexes with the total,
+// split proportionally to their current shipping cost or evenly when they have none
+func (o *Order) DistributeShippingCost(indexes []int, total float64) {
+	var current float64
+	for _, i := range indexes {
+		current += o.Items[i].ShippingCost
+	}
+
+	total = roundMoney(total)
+	remaining := total
+	for n, i := range indexes {
+		item := &o.Items[i]
+		cost := total / float64(len(indexes))
+		if current > 0 {
+			cost = total * item.ShippingCost / current
+		}
+		cost = roundMoney(cost)
+		if n == len(indexes)-1 {
+			cost = roundMoney(remaining)
+		}
+		remaining -= cost
+
+		o.TotalPrice += cost - item.ShippingCost
+		item.SetShippingCost(cost)
+	}
+}
+
+// ShippingCost is the shipping cost of all items before the shipping discount
+func (o *Order) ShippingCost() float64 {
+	var shippingCost float64
+	for _, item := range o.Items {
+		shippingCost += item.ShippingCost
+	}
+	return shippingCost
+}
+
+func (o *Order) IsSaleReported() bool {
+	return !o.SaleReportedAt.IsZero()
+}
+
+func (o *Order) HasPromotion() bool {
+	return o.PromotionID != uuid.Nil
+}
+
+func (o *Order) IsAllItemsCancelled() bool {
+	for _, item := range o.Items {
+		if !item.IsCancelled() {
+			return false
+		}
+	}
+	return true
+}
+
+// items can still be changed before the courier pick up the package
+func (o *Order) IsAwaitingShipment() bool {
+	return o.Status == ORDER_PENDING || o.Status == ORDER_READY_FOR_PICKUP ||
+		(o.Status == ORDER_ON_DELIVERY && o.ShippedAt.IsZero())
+}
+
+// PaymentStatus as far as the command side knows it, it only keeps the outcome of the payment
+func (o *Order) PaymentStatus() string {
+	switch {
+	case o.Status == ORDER_REJECTED:
+		return ORDER_PAYMENT_REJECTED
+	case !o.PaidAt.IsZero():
+		return ORDER_PAYMENT_APPROVED
+	case o.PaymentID != uuid.Nil:
+		return ORDER_PAYMENT_PENDING
+	default:
+		return ""
+	}
+}
+
+func (o *Order) IsPickup() bool {
+	return o.FulfilmentType == FULFILMENT_PICKUP
+}
+
+func (o *Order) IsDigital() bool {
+	return o.FulfilmentType == FULFILMENT_DIGITAL
+}
+
+func (o *Order) IsAllItemsDigital() bool {
+	for _, item := range o.Items {
+		if !item.IsDigital() {
+			return false
+		}
+	}
+	return len(o.Items) > 0
+}
+
+// DigitalItems is the items fulfilled by the digital fulfilment service
+func (o *Order) DigitalItems() []OrderItem {
+	var items []OrderItem
+	for _, item := range o.Items {
+		if item.IsDigital() && !item.IsCancelled() {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+const pickupCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GeneratePickupCode generate the code the customer shows at the warehouse,
+// similar looking characters are left out
+func (o *Order) GeneratePickupCode() error {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	for i := range b {
+		b[i] = pickupCodeAlphabet[int(b[i])%len(pickupCodeAlphabet)]
+	}
+
+	o.PickupCode = string(b)
+	return nil
+}
+
+// IsDeliveryApplicable is true when the courier event can move the order from its current status
+func (o *Order) IsDeliveryApplicable(deliveryStatus string) bool {
+	for _, status := range deliveryFromStatuses[deliveryStatus] {
+		if o.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+// courier pick up the package from the warehouse
+func (o *Order) SetShipped() {
+	o.ShippedAt = time.Now()
+}
+
+// return window starts when the order is delivered or picked up
+func (o *Order) IsReturnable(windowDays int) bool {
+	if (o.Status != ORDER_DELIVERED && o.Status != ORDER_PICKED_UP) || o.DeliveredAt.IsZero() {
+		return false
+	}
+	return time.Now().Before(o.DeliveredAt.AddDate(0, 0, windowDays))
+}
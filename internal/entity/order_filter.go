@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ORDER_SORT_CREATED_AT  = PAGE_SORT_CREATED_AT
+	ORDER_SORT_UPDATED_AT  = "updated_at"
+	ORDER_SORT_TOTAL_PRICE = "total_price"
+	ORDER_SORT_RANK        = "rank" // search result only, not a filter sort field
+)
+
+// OrderFilter narrow the order listing, zero value fields are not filtered
+type OrderFilter struct {
+	Statuses      []string
+	CreatedFrom   time.Time
+	CreatedTo     time.Time
+	UpdatedFrom   time.Time
+	UpdatedTo     time.Time
+	MinTotalPrice float64
+	MaxTotalPrice float64
+	PaymentStatus string
+	UserID        uuid.UUID
+	ProductID     uuid.UUID
+	State         string // address state, case insensitive
+	City          string // address city, case insensitive
+	SortBy        string // created_at when empty
+	SortAsc       bool   // newest or highest first by default
+}
+
+func (f OrderFilter) SortField() string {
+	if f.SortBy == "" {
+		return ORDER_SORT_CREATED_AT
+	}
+	return f.SortBy
+}
+
+func (f OrderFilter) IsTotalPriceRangeValid() bool {
+	return f.MaxTotalPrice == 0 || f.MinTotalPrice <= f.MaxTotalPrice
+}
+
+// PageCursor of the order as the last row of a page, the value is the sort field of the order
+func (f OrderFilter) PageCursor(order *OrderView) PageCursor {
+	switch f.SortField() {
+	case ORDER_SORT_UPDATED_AT:
+		return NewTimePageCursor(ORDER_SORT_UPDATED_AT, order.UpdatedAt, order.ID)
+	case ORDER_SORT_TOTAL_PRICE:
+		return NewFloatPageCursor(ORDER_SORT_TOTAL_PRICE, order.TotalPrice, order.ID)
+	default:
+		return NewTimePageCursor(ORDER_SORT_CREATED_AT, order.CreatedAt, order.ID)
+	}
+}
@@ -6,16 +6,29 @@ import (
 	"github.com/google/uuid"
 )
 
+// product type decides whether the item needs warehouse stock and shipping
+const (
+	PRODUCT_PHYSICAL = "PHYSICAL"
+	PRODUCT_DIGITAL  = "DIGITAL"
+)
+
 type OrderItem struct {
 	ID              uuid.UUID
 	OrderID         uuid.UUID
 	ProductID       uuid.UUID
+	CategoryID      uuid.UUID
+	ProductType     string
 	ProductQuantity int64
+	Price           float64
 	Note            string
 	ShippingCost    float64
-	CreatedAt       time.Time
-	UpdatedAt       time.Time
-	DeletedAt       time.Time
+	// promotion discount allocated to the item price and to the shipping cost
+	Discount         float64
+	ShippingDiscount float64
+	Tax              float64
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	DeletedAt        time.Time
 }
 
 func (o *OrderItem) GenerateOrderItemID() error {
@@ -28,6 +41,48 @@ func (o *OrderItem) GenerateOrderItemID() error {
 	return nil
 }
 
+// gift card or downloadable product, no stock movement and no shipping
+func (o *OrderItem) IsDigital() bool {
+	return o.ProductType == PRODUCT_DIGITAL
+}
+
 func (o *OrderItem) SetShippingCost(shippingCost float64) {
 	o.ShippingCost = shippingCost
 }
+
+// price paid for one unit after the promotion discount and tax
+func (o *OrderItem) PaidUnitPrice() float64 {
+	if o.ProductQuantity <= 0 {
+		return o.Price
+	}
+	return o.Price + (o.Tax-o.Discount)/float64(o.ProductQuantity)
+}
+
+// ReduceQuantity cancel some quantity of the item, the price discount and the tax are reduced proportionally.
+// it returns the discount and the tax removed from the item
+func (o *OrderItem) ReduceQuantity(quantity int64) (float64, float64) {
+	var discount, tax float64
+	if o.ProductQuantity > 0 {
+		discount = roundMoney(o.Discount * float64(quantity) / float64(o.ProductQuantity))
+		tax = roundMoney(o.Tax * float64(quantity) / float64(o.ProductQuantity))
+	}
+	o.ProductQuantity -= quantity
+	o.Discount -= discount
+	o.Tax -= tax
+	return discount, tax
+}
+
+// CalculateTax set the tax of the item from the discounted price, shipping cost is not taxed
+func (o *OrderItem) CalculateTax(rate float64) float64 {
+	o.Tax = roundMoney((o.Price*float64(o.ProductQuantity) - o.Discount) * rate)
+	return o.Tax
+}
+
+func (o *OrderItem) TotalDiscount() float64 {
+	return o.Discount + o.ShippingDiscount
+}
+
+// item with no quantity left is cancelled
+func (o *OrderItem) IsCancelled() bool {
+	return o.ProductQuantity <= 0
+}
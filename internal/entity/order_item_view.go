@@ -18,6 +18,8 @@ type OrderItemView struct {
 	ProductCategoryID   uuid.UUID
 	ProductCategoryName string
 	ShippingCost        float64
+	Discount            float64
+	Tax                 float64
 	Note                string
 	CreatedAt           time.Time
 	UpdatedAt           time.Time
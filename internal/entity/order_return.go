@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RETURN_REQUESTED = "REQUESTED"
+	RETURN_APPROVED  = "APPROVED"
+	RETURN_REJECTED  = "REJECTED"
+)
+
+type OrderReturn struct {
+	ID           uuid.UUID
+	OrderID      uuid.UUID
+	UserID       uuid.UUID
+	Status       string
+	Reason       string
+	AdminNote    string
+	RefundAmount float64
+	Items        []OrderReturnItem
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    time.Time
+}
+
+func (o *OrderReturn) GenerateOrderReturnID() error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	o.ID = id
+	return nil
+}
+
+// user open the return request
+func (o *OrderReturn) SetStatusToRequested() {
+	o.Status = RETURN_REQUESTED
+}
+
+// admin approve the return request
+func (o *OrderReturn) SetStatusToApproved() {
+	o.Status = RETURN_APPROVED
+}
+
+// admin reject the return request
+func (o *OrderReturn) SetStatusToRejected() {
+	o.Status = RETURN_REJECTED
+}
+
+func (o *OrderReturn) AddRefundAmount(amount float64) {
+	o.RefundAmount += amount
+}
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OrderReturnItem struct {
+	ID              uuid.UUID
+	ReturnID        uuid.UUID
+	OrderItemID     uuid.UUID
+	ProductID       uuid.UUID
+	ProductQuantity int64
+	Price           float64
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       time.Time
+}
+
+func (o *OrderReturnItem) GenerateOrderReturnItemID() error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	o.ID = id
+	return nil
+}
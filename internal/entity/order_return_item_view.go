@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OrderReturnItemView struct {
+	ID              uuid.UUID
+	ReturnViewID    uuid.UUID
+	ProductID       uuid.UUID
+	ProductQuantity int64
+	Price           float64
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       time.Time
+}
+
+func (o *OrderReturnItemView) GenerateOrderReturnItemViewID() error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	o.ID = id
+	return nil
+}
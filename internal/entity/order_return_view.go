@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OrderReturnView struct {
+	ID           uuid.UUID
+	ReturnID     uuid.UUID
+	OrderID      uuid.UUID
+	UserID       uuid.UUID
+	Status       string
+	Reason       string
+	AdminNote    string
+	RefundAmount float64
+	Items        []OrderReturnItemView
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    time.Time
+}
+
+func (o *OrderReturnView) GenerateOrderReturnViewID() error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	o.ID = id
+	return nil
+}
@@ -0,0 +1,8 @@
+package entity
+
+// OrderTimelineView summarise what happened to the order after it is placed
+type OrderTimelineView struct {
+	ReturnCount    int
+	RefundCount    int
+	RefundedAmount float64
+}
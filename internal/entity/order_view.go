@@ -22,20 +22,32 @@ import (
 // )
 
 type OrderView struct {
-	ID               uuid.UUID
-	OrderID          uuid.UUID
-	UserID           uuid.UUID
-	Status           string
-	TotalPrice       float64
-	PaymentID        uuid.UUID
-	PaymentStatus    string
-	PaymentImageURL  string
-	PaymentAdminNote string
-	Items            []OrderItemView
-	Address          OrderAddressView
-	CreatedAt        time.Time
-	UpdatedAt        time.Time
-	DeletedAt        time.Time
+	ID                    uuid.UUID
+	OrderID               uuid.UUID
+	UserID                uuid.UUID
+	Status                string
+	TotalPrice            float64
+	PromotionCode         string
+	DiscountAmount        float64
+	TaxAmount             float64
+	ShippingMethod        string
+	ShippingEstimatedDays int
+	EstimatedShipping     bool
+	ShippingRule          string
+	FulfilmentType        string
+	PickupWarehouseID     uuid.UUID
+	PickupCode            string
+	PaymentID             uuid.UUID
+	PaymentStatus         string
+	PaymentImageURL       string
+	PaymentAdminNote      string
+	Version               int
+	Items                 []OrderItemView
+	Address               OrderAddressView
+	Timeline              OrderTimelineView
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	DeletedAt             time.Time
 }
 
 func (o *OrderView) GenerateOrderViewID() error {
@@ -53,6 +65,18 @@ func (o *OrderView) SetStatusToOnDelivery() {
 	o.Status = ORDER_ON_DELIVERY
 }
 
+// SetStatusToPaid move the order to the next status after the payment is approved
+func (o *OrderView) SetStatusToPaid() {
+	switch o.FulfilmentType {
+	case FULFILMENT_PICKUP:
+		o.Status = ORDER_READY_FOR_PICKUP
+	case FULFILMENT_DIGITAL:
+		o.Status = ORDER_FULFILLED
+	default:
+		o.SetStatusToOnDelivery()
+	}
+}
+
 // admin reject payment and set the order status to REJECTED
 func (o *OrderView) SetStatusToRejected() {
 	o.Status = ORDER_REJECTED
@@ -73,3 +97,18 @@ func (o *OrderView) SetStatusToDelivered() {
 func (o *OrderView) SetStatusToExpired() {
 	o.Status = ORDER_EXPIRED
 }
+
+// every item of the order is cancelled
+func (o *OrderView) SetStatusToCancelled() {
+	o.Status = ORDER_CANCELLED
+}
+
+// courier failed to deliver, waiting for the next delivery attempt
+func (o *OrderView) SetStatusToDeliveryFailed() {
+	o.Status = ORDER_DELIVERY_FAILED
+}
+
+// courier gave up delivering, the package is returned to the warehouse
+func (o *OrderView) SetStatusToReturning() {
+	o.Status = ORDER_RETURNING
+}
@@ -0,0 +1,121 @@
+package entity
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	PAGE_DEFAULT_LIMIT = 20
+	PAGE_MAX_LIMIT     = 100
+
+	PAGE_SORT_CREATED_AT = "created_at"
+)
+
+var ErrInvalidPageCursor = errors.New("invalid page cursor")
+
+// listings are ordered by the sort field then id, newest first by default,
+// the cursor is the position of the last row of the previous page
+type PageCursor struct {
+	Sort  string // sort field the cursor was made for
+	Value string // sort field value of the last row, time is in unix nano
+	ID    uuid.UUID
+}
+
+func NewTimePageCursor(sort string, value time.Time, id uuid.UUID) PageCursor {
+	return PageCursor{
+		Sort:  sort,
+		Value: strconv.FormatInt(value.UnixNano(), 10),
+		ID:    id,
+	}
+}
+
+func NewFloatPageCursor(sort string, value float64, id uuid.UUID) PageCursor {
+	return PageCursor{
+		Sort:  sort,
+		Value: strconv.FormatFloat(value, 'g', -1, 64),
+		ID:    id,
+	}
+}
+
+func (c PageCursor) IsZero() bool {
+	return c.ID == uuid.Nil
+}
+
+func (c PageCursor) TimeValue() (time.Time, error) {
+	nano, err := strconv.ParseInt(c.Value, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrInvalidPageCursor
+	}
+	return time.Unix(0, nano).UTC(), nil
+}
+
+func (c PageCursor) FloatValue() (float64, error) {
+	value, err := strconv.ParseFloat(c.Value, 64)
+	if err != nil {
+		return 0, ErrInvalidPageCursor
+	}
+	return value, nil
+}
+
+// Encode return the opaque token sent to the client, empty when there is no next page
+func (c PageCursor) Encode() string {
+	if c.IsZero() {
+		return ""
+	}
+	raw := c.Sort + "|" + c.Value + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodePageCursor(token string) (PageCursor, error) {
+	if token == "" {
+		return PageCursor{}, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return PageCursor{}, ErrInvalidPageCursor
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return PageCursor{}, ErrInvalidPageCursor
+	}
+	// every sort value is a number, the repository picks its type by the sort field
+	if _, err := strconv.ParseFloat(parts[1], 64); err != nil {
+		return PageCursor{}, ErrInvalidPageCursor
+	}
+	cursorID, err := uuid.Parse(parts[2])
+	if err != nil {
+		return PageCursor{}, ErrInvalidPageCursor
+	}
+
+	return PageCursor{
+		Sort:  parts[0],
+		Value: parts[1],
+		ID:    cursorID,
+	}, nil
+}
+
+type Page struct {
+	Limit int
+	After PageCursor // zero value is the first page
+}
+
+// NewPage bound the limit, 0 falls back to the default limit
+func NewPage(limit int, after PageCursor) Page {
+	if limit <= 0 {
+		limit = PAGE_DEFAULT_LIMIT
+	}
+	if limit > PAGE_MAX_LIMIT {
+		limit = PAGE_MAX_LIMIT
+	}
+	return Page{
+		Limit: limit,
+		After: after,
+	}
+}
@@ -0,0 +1,104 @@
+package entity
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDecodePageCursorRoundTrip(t *testing.T) {
+	id := uuid.New()
+	createdAt := time.Date(2024, 3, 1, 10, 30, 0, 123456789, time.UTC)
+
+	tests := []struct {
+		name   string
+		cursor PageCursor
+	}{
+		{"time", NewTimePageCursor(ORDER_SORT_CREATED_AT, createdAt, id)},
+		{"float", NewFloatPageCursor(ORDER_SORT_TOTAL_PRICE, 125000.75, id)},
+		{"negative float", NewFloatPageCursor(ORDER_SORT_TOTAL_PRICE, -0.5, id)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodePageCursor(tt.cursor.Encode())
+			if err != nil {
+				t.Fatalf("DecodePageCursor() error = %v", err)
+			}
+			if got != tt.cursor {
+				t.Errorf("DecodePageCursor() = %+v, want %+v", got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodePageCursorValues(t *testing.T) {
+	id := uuid.New()
+	createdAt := time.Date(2024, 3, 1, 10, 30, 0, 123456789, time.UTC)
+
+	cursor, err := DecodePageCursor(NewTimePageCursor(ORDER_SORT_CREATED_AT, createdAt, id).Encode())
+	if err != nil {
+		t.Fatalf("DecodePageCursor() error = %v", err)
+	}
+	gotTime, err := cursor.TimeValue()
+	if err != nil {
+		t.Fatalf("TimeValue() error = %v", err)
+	}
+	if !gotTime.Equal(createdAt) {
+		t.Errorf("TimeValue() = %v, want %v", gotTime, createdAt)
+	}
+
+	cursor, err = DecodePageCursor(NewFloatPageCursor(ORDER_SORT_TOTAL_PRICE, 99.95, id).Encode())
+	if err != nil {
+		t.Fatalf("DecodePageCursor() error = %v", err)
+	}
+	gotFloat, err := cursor.FloatValue()
+	if err != nil {
+		t.Fatalf("FloatValue() error = %v", err)
+	}
+	if gotFloat != 99.95 {
+		t.Errorf("FloatValue() = %v, want 99.95", gotFloat)
+	}
+}
+
+func TestDecodePageCursorEmpty(t *testing.T) {
+	cursor, err := DecodePageCursor("")
+	if err != nil {
+		t.Fatalf("DecodePageCursor() error = %v", err)
+	}
+	if !cursor.IsZero() {
+		t.Errorf("DecodePageCursor() = %+v, want zero cursor", cursor)
+	}
+	if got := cursor.Encode(); got != "" {
+		t.Errorf("Encode() of zero cursor = %q, want empty", got)
+	}
+}
+
+func TestDecodePageCursorInvalid(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+	id := uuid.New().String()
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"not base64", "not a cursor!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte("created_at|1|" + id))},
+		{"missing part", encode("created_at|1")},
+		{"extra part", encode("created_at|1|" + id + "|x")},
+		{"value not a number", encode("created_at|yesterday|" + id)},
+		{"invalid id", encode("created_at|1|not-a-uuid")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodePageCursor(tt.token)
+			if !errors.Is(err, ErrInvalidPageCursor) {
+				t.Errorf("DecodePageCursor(%q) error = %v, want %v", tt.token, err, ErrInvalidPageCursor)
+			}
+		})
+	}
+}
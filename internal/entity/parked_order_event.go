@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// order view updates that can arrive before the order-created event
+const (
+	PARKED_ORDER_STATUS  = "STATUS"
+	PARKED_ORDER_PAYMENT = "PAYMENT"
+	PARKED_ORDER_ITEMS   = "ITEMS"
+	PARKED_ORDER_UPDATE  = "UPDATE"
+)
+
+// the order view was created while the update was on its way to be parked, it is applied instead
+var ErrOrderViewCreated = errors.New("order view is created")
+
+// ParkedOrderEvent is an order view update kept until its order view is created
+type ParkedOrderEvent struct {
+	ID        uuid.UUID
+	OrderID   uuid.UUID
+	Kind      string
+	Version   int
+	Payload   []byte // order view of the update as json
+	CreatedAt time.Time
+}
+
+func (e *ParkedOrderEvent) GenerateParkedOrderEventID() error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	e.ID = id
+	return nil
+}
@@ -0,0 +1,75 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	REBUILD_RUNNING = "RUNNING"
+	REBUILD_DONE    = "DONE"
+	REBUILD_FAILED  = "FAILED"
+)
+
+const (
+	REBUILD_DEFAULT_BATCH_SIZE = 500
+	REBUILD_MAX_BATCH_SIZE     = 5000
+)
+
+// advisory lock names of the projection jobs, one of each runs across every replica and the cli
+const (
+	REBUILD_LOCK   = "order-view-rebuild"
+	RECONCILE_LOCK = "order-view-reconcile"
+)
+
+// the job lock is held by another replica or the cli
+var ErrProjectionJobLocked = errors.New("projection job is running elsewhere")
+
+// ProjectionRebuild is the progress of repopulating the order views from the command tables
+type ProjectionRebuild struct {
+	ID         uuid.UUID
+	Shadow     bool // written into shadow tables and swapped in at the end
+	BatchSize  int
+	Status     string
+	Total      int // orders in the command database when the rebuild started
+	Processed  int
+	Skipped    int // changed by the projection while the rebuild was running
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// NewProjectionRebuild bound the batch size, 0 falls back to the default batch size
+func NewProjectionRebuild(shadow bool, batchSize int) (ProjectionRebuild, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return ProjectionRebuild{}, err
+	}
+	if batchSize <= 0 {
+		batchSize = REBUILD_DEFAULT_BATCH_SIZE
+	}
+	if batchSize > REBUILD_MAX_BATCH_SIZE {
+		batchSize = REBUILD_MAX_BATCH_SIZE
+	}
+
+	return ProjectionRebuild{
+		ID:        id,
+		Shadow:    shadow,
+		BatchSize: batchSize,
+		Status:    REBUILD_RUNNING,
+		StartedAt: time.Now(),
+	}, nil
+}
+
+func (r *ProjectionRebuild) SetDone() {
+	r.Status = REBUILD_DONE
+	r.FinishedAt = time.Now()
+}
+
+func (r *ProjectionRebuild) SetFailed(err error) {
+	r.Status = REBUILD_FAILED
+	r.Error = err.Error()
+	r.FinishedAt = time.Now()
+}
@@ -0,0 +1,102 @@
+package entity
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// difference found between an order and its order view
+const (
+	DISCREPANCY_MISSING_VIEW     = "MISSING_VIEW"
+	DISCREPANCY_ORPHAN_VIEW      = "ORPHAN_VIEW" // order view without an order
+	DISCREPANCY_STATUS_MISMATCH  = "STATUS_MISMATCH"
+	DISCREPANCY_PAYMENT_MISMATCH = "PAYMENT_MISMATCH"
+	DISCREPANCY_TOTAL_MISMATCH   = "TOTAL_MISMATCH"
+)
+
+// how the reconciler fixes the read model
+const (
+	RECONCILE_REPAIR_NONE   = "none"
+	RECONCILE_REPAIR_PATCH  = "patch"  // write the order views from the command tables
+	RECONCILE_REPAIR_EVENTS = "events" // publish the events again for the projection
+)
+
+// the report keeps the first discrepancies only, the counts cover all of them
+const RECONCILE_MAX_LISTED_DISCREPANCIES = 1000
+
+type OrderDiscrepancy struct {
+	OrderID      uuid.UUID
+	Kind         string
+	CommandValue string
+	ViewValue    string
+	Repaired     bool
+}
+
+// ReconcileReport is the result of comparing the orders with the order views
+type ReconcileReport struct {
+	ID            uuid.UUID
+	Repair        string
+	Status        string // same statuses as the projection rebuild
+	Checked       int
+	Counts        map[string]int
+	Repaired      int
+	Discrepancies []OrderDiscrepancy
+	Error         string
+	StartedAt     time.Time
+	FinishedAt    time.Time
+}
+
+func NewReconcileReport(repair string) (ReconcileReport, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return ReconcileReport{}, err
+	}
+
+	return ReconcileReport{
+		ID:        id,
+		Repair:    repair,
+		Status:    REBUILD_RUNNING,
+		Counts:    make(map[string]int),
+		StartedAt: time.Now(),
+	}, nil
+}
+
+func (r *ReconcileReport) Add(discrepancy OrderDiscrepancy) {
+	r.Counts[discrepancy.Kind]++
+	if discrepancy.Repaired {
+		r.Repaired++
+	}
+	if len(r.Discrepancies) < RECONCILE_MAX_LISTED_DISCREPANCIES {
+		r.Discrepancies = append(r.Discrepancies, discrepancy)
+	}
+}
+
+// Snapshot copy the report so it can be read while the reconcile goes on
+func (r *ReconcileReport) Snapshot() ReconcileReport {
+	snapshot := *r
+	snapshot.Counts = make(map[string]int, len(r.Counts))
+	for kind, count := range r.Counts {
+		snapshot.Counts[kind] = count
+	}
+	snapshot.Discrepancies = append([]OrderDiscrepancy(nil), r.Discrepancies...)
+
+	return snapshot
+}
+
+func (r *ReconcileReport) SetDone() {
+	r.Status = REBUILD_DONE
+	r.FinishedAt = time.Now()
+}
+
+func (r *ReconcileReport) SetFailed(err error) {
+	r.Status = REBUILD_FAILED
+	r.Error = err.Error()
+	r.FinishedAt = time.Now()
+}
+
+// IsTotalEqual compare prices of the order and its view, rounding differences below a cent are ignored
+func IsTotalEqual(a, b float64) bool {
+	return math.Abs(a-b) < 0.005
+}
@@ -0,0 +1,59 @@
+package entity
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// every write on the order bumps its version, the projection copies it to the order view
+const ORDER_FIRST_VERSION = 1
+
+var (
+	ErrInvalidProjectionToken = errors.New("invalid projection token")
+	// the order view already has the version of the event, or a later one
+	ErrStaleOrderEvent = errors.New("order event is older than the order view")
+)
+
+// ProjectionToken is returned to the client after a write,
+// the read model has caught up with the write once the order view reaches the version
+type ProjectionToken struct {
+	OrderID uuid.UUID
+	Version int
+}
+
+// Encode return the opaque token sent to the client, empty when the write version is unknown
+func (t ProjectionToken) Encode() string {
+	if t.Version < ORDER_FIRST_VERSION {
+		return ""
+	}
+	raw := t.OrderID.String() + "|" + strconv.Itoa(t.Version)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeProjectionToken(token string) (ProjectionToken, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return ProjectionToken{}, ErrInvalidProjectionToken
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 2 {
+		return ProjectionToken{}, ErrInvalidProjectionToken
+	}
+	orderID, err := uuid.Parse(parts[0])
+	if err != nil {
+		return ProjectionToken{}, ErrInvalidProjectionToken
+	}
+	version, err := strconv.Atoi(parts[1])
+	if err != nil || version < ORDER_FIRST_VERSION {
+		return ProjectionToken{}, ErrInvalidProjectionToken
+	}
+
+	return ProjectionToken{
+		OrderID: orderID,
+		Version: version,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package entity
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	PROMOTION_PERCENTAGE    = "PERCENTAGE"
+	PROMOTION_FIXED         = "FIXED"
+	PROMOTION_FREE_SHIPPING = "FREE_SHIPPING"
+	PROMOTION_BUY_X_GET_Y   = "BUY_X_GET_Y"
+)
+
+type Promotion struct {
+	ID    uuid.UUID
+	Code  string
+	Type  string
+	Value float64 // percentage (0-100) or fixed amount, depends on the type
+	// maximum discount of percentage promotion, 0 means no cap
+	MaxDiscount float64
+	MinSpend    float64
+	// buy x get y promotion: every BuyQuantity of the product get GetQuantity for free
+	ProductID         uuid.UUID
+	BuyQuantity       int64
+	GetQuantity       int64
+	StartAt           time.Time
+	EndAt             time.Time
+	UsageLimit        int // 0 means unlimited
+	UsageLimitPerUser int // 0 means unlimited
+	UsageCount        int
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	DeletedAt         time.Time
+}
+
+func (p *Promotion) GeneratePromotionID() error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	p.ID = id
+	return nil
+}
+
+func (p *Promotion) IsActive(now time.Time) bool {
+	return !now.Before(p.StartAt) && now.Before(p.EndAt)
+}
+
+func (p *Promotion) IsUsageExceeded() bool {
+	return p.UsageLimit > 0 && p.UsageCount >= p.UsageLimit
+}
+
+func (p *Promotion) IsUserUsageExceeded(userUsage int) bool {
+	return p.UsageLimitPerUser > 0 && userUsage >= p.UsageLimitPerUser
+}
+
+// minimum spend is compared with the items subtotal, without shipping cost
+func (p *Promotion) IsMinSpendReached(order *Order) bool {
+	return order.Subtotal() >= p.MinSpend
+}
+
+// Apply allocate the discount of the promotion to the order items and reduce the total price.
+// fixed and capped percentage discount is spread proportionally to the item subtotal,
+// the rounding difference goes to the last discounted item.
+func (p *Promotion) Apply(order *Order) {
+	order.ClearDiscount()
+	order.PromotionID = p.ID
+	order.PromotionCode = p.Code
+
+	subtotal := order.Subtotal()
+	switch p.Type {
+	case PROMOTION_PERCENTAGE:
+		discount := subtotal * p.Value / 100
+		if p.MaxDiscount > 0 {
+			discount = math.Min(discount, p.MaxDiscount)
+		}
+		allocateDiscount(order, discount, subtotal)
+	case PROMOTION_FIXED:
+		allocateDiscount(order, math.Min(p.Value, subtotal), subtotal)
+	case PROMOTION_FREE_SHIPPING:
+		for i := range order.Items {
+			order.Items[i].ShippingDiscount = order.Items[i].ShippingCost
+		}
+	case PROMOTION_BUY_X_GET_Y:
+		for i := range order.Items {
+			item := &order.Items[i]
+			if item.ProductID != p.ProductID || p.BuyQuantity+p.GetQuantity <= 0 {
+				continue
+			}
+			freeQuantity := item.ProductQuantity / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+			item.Discount = roundMoney(item.Price * float64(freeQuantity))
+		}
+	}
+
+	for _, item := range order.Items {
+		order.DiscountAmount += item.Discount + item.ShippingDiscount
+	}
+	order.DiscountAmount = roundMoney(order.DiscountAmount)
+	order.ReduceTotalPrice(order.DiscountAmount)
+}
+
+func allocateDiscount(order *Order, discount, subtotal float64) {
+	if discount <= 0 || subtotal <= 0 {
+		return
+	}
+
+	discount = roundMoney(discount)
+	remaining := discount
+	last := -1
+	for i := range order.Items {
+		item := &order.Items[i]
+		itemSubtotal := item.Price * float64(item.ProductQuantity)
+		if itemSubtotal <= 0 {
+			continue
+		}
+		item.Discount = roundMoney(discount * itemSubtotal / subtotal)
+		remaining -= item.Discount
+		last = i
+	}
+	if last >= 0 {
+		order.Items[last].Discount = roundMoney(order.Items[last].Discount + remaining)
+	}
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
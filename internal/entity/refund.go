@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	REFUND_PENDING = "PENDING"
+)
+
+// tolerance for float rounding when comparing refund amount with the paid amount
+const REFUND_AMOUNT_TOLERANCE = 0.005
+
+// another refund of the order was saved first and left less to refund than asked
+var ErrRefundExceedsRemaining = errors.New("refund exceeds what is left to refund")
+
+type Refund struct {
+	ID        uuid.UUID
+	OrderID   uuid.UUID
+	UserID    uuid.UUID
+	ReturnID  uuid.UUID
+	Amount    float64
+	Reason    string
+	Status    string
+	Items     []RefundItem
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt time.Time
+}
+
+func (r *Refund) GenerateRefundID() error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	r.ID = id
+	return nil
+}
+
+// refund is created and waiting to be paid back by payment service
+func (r *Refund) SetStatusToPending() {
+	r.Status = REFUND_PENDING
+}
+
+func (r *Refund) AddAmount(amount float64) {
+	r.Amount += amount
+}
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RefundItem struct {
+	ID              uuid.UUID
+	RefundID        uuid.UUID
+	OrderItemID     uuid.UUID
+	ProductID       uuid.UUID
+	ProductQuantity int64
+	Price           float64
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       time.Time
+}
+
+func (r *RefundItem) GenerateRefundItemID() error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	r.ID = id
+	return nil
+}
@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RefundItemView struct {
+	ID              uuid.UUID
+	RefundViewID    uuid.UUID
+	ProductID       uuid.UUID
+	ProductQuantity int64
+	Price           float64
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       time.Time
+}
+
+func (r *RefundItemView) GenerateRefundItemViewID() error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	r.ID = id
+	return nil
+}
@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RefundView struct {
+	ID        uuid.UUID
+	RefundID  uuid.UUID
+	OrderID   uuid.UUID
+	UserID    uuid.UUID
+	ReturnID  uuid.UUID
+	Amount    float64
+	Reason    string
+	Status    string
+	Items     []RefundItemView
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt time.Time
+}
+
+func (r *RefundView) GenerateRefundViewID() error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	r.ID = id
+	return nil
+}
@@ -0,0 +1,26 @@
+package entity
+
+const (
+	SHIPPING_STANDARD = "STANDARD"
+	SHIPPING_EXPRESS  = "EXPRESS"
+	SHIPPING_SAME_DAY = "SAME_DAY"
+)
+
+// ShippingRate is the cost and estimated delivery time of a shipping method
+type ShippingRate struct {
+	Method        string
+	Cost          float64
+	EstimatedDays int
+	// true when priced from the local rate table instead of the shipping cost service
+	Estimated bool
+}
+
+// FindShippingRate return the rate of the method, false when the method is not offered
+func FindShippingRate(rates []ShippingRate, method string) (ShippingRate, bool) {
+	for _, rate := range rates {
+		if rate.Method == method {
+			return rate, true
+		}
+	}
+	return ShippingRate{}, false
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/idoyudha/eshop-order/internal/entity"
 	"github.com/idoyudha/eshop-order/pkg/postgresql/postgrecommand"
+	"github.com/lib/pq"
 )
 
 type OrderPostgreCommandRepo struct {
@@ -21,9 +22,22 @@ func NewOrderPostgreCommandRepo(conn *postgrecommand.PostgresCommand) *OrderPost
 }
 
 const (
-	queryInsertOrder        = `INSERT INTO orders (id, user_id, status, total_price, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6);`
-	queryInsertOrderItems   = `INSERT INTO order_items (id, order_id, product_id, product_quantity, shipping_cost, note, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8);`
+	queryInsertOrder        = `INSERT INTO orders (id, user_id, status, total_price, promotion_id, promotion_code, discount_amount, tax_amount, shipping_method, shipping_estimated_days, estimated_shipping, shipping_rule, fulfilment_type, pickup_warehouse_id, pickup_code, version, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18);`
+	queryInsertOrderItems   = `INSERT INTO order_items (id, order_id, product_id, category_id, product_type, product_quantity, price, shipping_cost, discount, shipping_discount, tax, note, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14);`
 	queryInsertOrderAddress = `INSERT INTO order_addresses (id, order_id, street, city, state, zip_code, note, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);`
+	// orders with the same promotion are redeemed one at a time, the redeem statement that follows
+	// the lock reads the usages committed by the order before it
+	queryLockPromotion = `SELECT id FROM promotions WHERE id = $1 FOR UPDATE;`
+	// usage limits are checked again while the promotion row is locked
+	queryRedeemPromotion = `
+		UPDATE promotions p SET usage_count = usage_count + 1, updated_at = $3
+		WHERE p.id = $1
+			AND (p.usage_limit = 0 OR p.usage_count < p.usage_limit)
+			AND (p.usage_limit_per_user = 0 OR (
+				SELECT COUNT(*) FROM promotion_usages pu WHERE pu.promotion_id = p.id AND pu.user_id = $2
+			) < p.usage_limit_per_user);
+	`
+	queryInsertPromotionUsage = `INSERT INTO promotion_usages (id, promotion_id, order_id, user_id, created_at) VALUES ($1, $2, $3, $4, $5);`
 )
 
 func (r *OrderPostgreCommandRepo) Insert(ctx context.Context, order *entity.Order) error {
@@ -36,17 +50,54 @@ func (r *OrderPostgreCommandRepo) Insert(ctx context.Context, order *entity.Orde
 	}
 	defer tx.Rollback()
 
+	// redeem promotion, no row updated means the usage limit is reached
+	if order.HasPromotion() {
+		_, err = tx.ExecContext(ctx, queryLockPromotion, order.PromotionID)
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, queryRedeemPromotion, order.PromotionID, order.UserID, order.CreatedAt)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return sql.ErrNoRows
+		}
+	}
+
 	// insert order
+	promotionID := uuid.NullUUID{UUID: order.PromotionID, Valid: order.HasPromotion()}
+	promotionCode := sql.NullString{String: order.PromotionCode, Valid: order.HasPromotion()}
+	pickupWarehouseID := uuid.NullUUID{UUID: order.PickupWarehouseID, Valid: order.IsPickup()}
 	_, err = tx.ExecContext(ctx, queryInsertOrder,
-		order.ID, order.UserID, order.Status, order.TotalPrice, order.CreatedAt, order.UpdatedAt)
+		order.ID, order.UserID, order.Status, order.TotalPrice, promotionID, promotionCode, order.DiscountAmount,
+		order.TaxAmount, order.ShippingMethod, order.ShippingEstimatedDays, order.EstimatedShipping, order.ShippingRule,
+		order.FulfilmentType, pickupWarehouseID, order.PickupCode, order.Version, order.CreatedAt, order.UpdatedAt)
 	if err != nil {
 		return err
 	}
 
+	if order.HasPromotion() {
+		usageID, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, queryInsertPromotionUsage, usageID, order.PromotionID, order.ID, order.UserID, order.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
 	// insert order items
 	for _, item := range order.Items {
 		_, err = tx.ExecContext(ctx, queryInsertOrderItems,
-			item.ID, order.ID, item.ProductID, item.ProductQuantity, item.ShippingCost, item.Note, item.CreatedAt, item.UpdatedAt)
+			item.ID, order.ID, item.ProductID, uuid.NullUUID{UUID: item.CategoryID, Valid: item.CategoryID != uuid.Nil},
+			item.ProductType, item.ProductQuantity, item.Price, item.ShippingCost, item.Discount, item.ShippingDiscount, item.Tax,
+			item.Note, item.CreatedAt, item.UpdatedAt)
 		if err != nil {
 			return err
 		}
@@ -67,7 +118,8 @@ func (r *OrderPostgreCommandRepo) Insert(ctx context.Context, order *entity.Orde
 	return nil
 }
 
-const queryUpdateStatusOrder = `UPDATE orders SET status = $1, updated_at = $2 WHERE id = $3;`
+// every write published to the read model bumps the order version, the event carries the new version
+const queryUpdateStatusOrder = `UPDATE orders SET status = $1, updated_by = $2, updated_at = $3, shipped_at = COALESCE($4, shipped_at), delivered_at = COALESCE($5, delivered_at), version = version + 1 WHERE id = $6 RETURNING version;`
 
 func (r *OrderPostgreCommandRepo) UpdateStatus(ctx context.Context, order *entity.Order) error {
 	stmt, errStmt := r.Conn.PrepareContext(ctx, queryUpdateStatusOrder)
@@ -76,16 +128,43 @@ func (r *OrderPostgreCommandRepo) UpdateStatus(ctx context.Context, order *entit
 	}
 	defer stmt.Close()
 
-	_, updateErr := stmt.ExecContext(ctx, order.Status, order.UpdatedAt, order.ID)
-	if updateErr != nil {
-		return updateErr
+	shippedAt := sql.NullTime{Time: order.ShippedAt, Valid: !order.ShippedAt.IsZero()}
+	deliveredAt := sql.NullTime{Time: order.DeliveredAt, Valid: !order.DeliveredAt.IsZero()}
+	return stmt.QueryRowContext(ctx, order.Status, order.UpdatedBy, order.UpdatedAt, shippedAt, deliveredAt, order.ID).Scan(&order.Version)
+}
+
+// the status is only changed while the order is still in one of the statuses it may move from
+const queryUpdateStatusOrderFrom = `UPDATE orders SET status = $1, updated_by = $2, updated_at = $3, shipped_at = COALESCE($4, shipped_at), delivered_at = COALESCE($5, delivered_at), version = version + 1 WHERE id = $6 AND status = ANY($7) RETURNING version;`
+
+// UpdateStatusFrom change the status like UpdateStatus, sql.ErrNoRows when the order has already moved
+// to a status outside of from
+func (r *OrderPostgreCommandRepo) UpdateStatusFrom(ctx context.Context, order *entity.Order, from []string) error {
+	stmt, errStmt := r.Conn.PrepareContext(ctx, queryUpdateStatusOrderFrom)
+	if errStmt != nil {
+		return errStmt
 	}
+	defer stmt.Close()
 
-	return nil
+	shippedAt := sql.NullTime{Time: order.ShippedAt, Valid: !order.ShippedAt.IsZero()}
+	deliveredAt := sql.NullTime{Time: order.DeliveredAt, Valid: !order.DeliveredAt.IsZero()}
+	return stmt.QueryRowContext(ctx, order.Status, order.UpdatedBy, order.UpdatedAt, shippedAt, deliveredAt, order.ID, pq.Array(from)).Scan(&order.Version)
 }
 
-const queryUpdatePaymentIDOrder = `UPDATE orders SET status = $1, payment_id = $2, updated_at = $3 WHERE id = $4;`
+// paid amount is fixed when the payment is approved, later changes of total price do not change it
+const queryUpdatePaymentIDOrder = `
+	UPDATE orders SET
+		status = $1,
+		payment_id = $2,
+		updated_at = $3,
+		paid_amount = CASE WHEN $1 IN ('ON_DELIVERY', 'READY_FOR_PICKUP', 'FULFILLED') THEN total_price ELSE paid_amount END,
+		paid_at = COALESCE($4, paid_at),
+		version = version + 1
+	WHERE id = $5 AND status = 'PENDING'
+	RETURNING version;
+`
 
+// UpdatePaymentID save the payment outcome of a pending order, sql.ErrNoRows when the order
+// is not pending anymore, like an order whose items were all cancelled before the approval
 func (r *OrderPostgreCommandRepo) UpdatePaymentID(ctx context.Context, order *entity.Order) error {
 	stmt, errStmt := r.Conn.PrepareContext(ctx, queryUpdatePaymentIDOrder)
 	if errStmt != nil {
@@ -93,7 +172,125 @@ func (r *OrderPostgreCommandRepo) UpdatePaymentID(ctx context.Context, order *en
 	}
 	defer stmt.Close()
 
-	_, updateErr := stmt.ExecContext(ctx, order.Status, order.PaymentID, order.UpdatedAt, order.ID)
+	paidAt := sql.NullTime{Time: order.PaidAt, Valid: !order.PaidAt.IsZero()}
+	return stmt.QueryRowContext(ctx, order.Status, order.PaymentID, order.UpdatedAt, paidAt, order.ID).Scan(&order.Version)
+}
+
+const (
+	// same as entity.Order.IsAwaitingShipment, and nothing else changed the order since it was read
+	queryUpdateOrderTotal = `
+		UPDATE orders SET status = $1, total_price = $2, discount_amount = $3, tax_amount = $4, updated_at = $5, version = version + 1
+		WHERE id = $6 AND version = $7 AND status IN ('PENDING', 'READY_FOR_PICKUP', 'ON_DELIVERY') AND shipped_at IS NULL
+		RETURNING version;`
+	queryUpdateOrderItem = `UPDATE order_items SET product_quantity = $1, shipping_cost = $2, discount = $3, shipping_discount = $4, tax = $5, updated_at = $6 WHERE id = $7;`
+	queryDeleteOrderItem = `UPDATE order_items SET product_quantity = 0, shipping_cost = 0, discount = 0, shipping_discount = 0, tax = 0, updated_at = $1, deleted_at = $1 WHERE id = $2;`
+)
+
+// UpdateItems save the changed quantity of the items and the recomputed total price,
+// item without quantity left is soft deleted. the refund of a paid order is saved in the same
+// transaction, nil refund for an order that is not paid. sql.ErrNoRows when the order is shipped
+// or closed, or its version moved since it was read
+func (r *OrderPostgreCommandRepo) UpdateItems(ctx context.Context, order *entity.Order, refund *entity.Refund) error {
+	tx, err := r.Conn.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, queryUpdateOrderTotal, order.Status, order.TotalPrice, order.DiscountAmount, order.TaxAmount, order.UpdatedAt, order.ID, order.Version).Scan(&order.Version)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range order.Items {
+		if item.IsCancelled() {
+			_, err = tx.ExecContext(ctx, queryDeleteOrderItem, order.UpdatedAt, item.ID)
+		} else {
+			_, err = tx.ExecContext(ctx, queryUpdateOrderItem,
+				item.ProductQuantity, item.ShippingCost, item.Discount, item.ShippingDiscount, item.Tax, order.UpdatedAt, item.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if refund != nil {
+		err = insertRefund(ctx, tx, refund)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+const (
+	// the edit is saved only when nothing changed the order since it was read, like an approved payment
+	queryUpdatePendingOrderTotal = `UPDATE orders SET total_price = $1, discount_amount = $2, tax_amount = $3, updated_at = $4, version = version + 1 WHERE id = $5 AND status = 'PENDING' AND version = $6 RETURNING version;`
+	queryUpdateOrderAddress      = `UPDATE order_addresses SET street = $1, city = $2, state = $3, zip_code = $4, note = $5, updated_at = $6 WHERE id = $7;`
+	queryUpdateOrderShipping     = `UPDATE orders SET shipping_estimated_days = $1, estimated_shipping = $2, shipping_rule = $3 WHERE id = $4;`
+)
+
+// Update save the edited items, address and total price of a pending order, sql.ErrNoRows when
+// the order is not pending anymore or its version moved since it was read
+func (r *OrderPostgreCommandRepo) Update(ctx context.Context, order *entity.Order) error {
+	tx, err := r.Conn.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, queryUpdatePendingOrderTotal, order.TotalPrice, order.DiscountAmount, order.TaxAmount, order.UpdatedAt, order.ID, order.Version).Scan(&order.Version)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range order.Items {
+		_, err = tx.ExecContext(ctx, queryUpdateOrderItem,
+			item.ProductQuantity, item.ShippingCost, item.Discount, item.ShippingDiscount, item.Tax, order.UpdatedAt, item.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, queryUpdateOrderAddress,
+		order.Address.Street, order.Address.City, order.Address.State, order.Address.ZipCode, order.Address.Note,
+		order.UpdatedAt, order.Address.ID)
+	if err != nil {
+		return err
+	}
+
+	// the estimate and shipping rule change with the address and items
+	_, err = tx.ExecContext(ctx, queryUpdateOrderShipping, order.ShippingEstimatedDays, order.EstimatedShipping, order.ShippingRule, order.ID)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+const queryUpdateSaleReported = `UPDATE orders SET sale_reported_at = $1 WHERE id = $2;`
+
+func (r *OrderPostgreCommandRepo) UpdateSaleReported(ctx context.Context, order *entity.Order) error {
+	stmt, errStmt := r.Conn.PrepareContext(ctx, queryUpdateSaleReported)
+	if errStmt != nil {
+		return errStmt
+	}
+	defer stmt.Close()
+
+	_, updateErr := stmt.ExecContext(ctx, order.SaleReportedAt, order.ID)
 	if updateErr != nil {
 		return updateErr
 	}
@@ -101,14 +298,71 @@ func (r *OrderPostgreCommandRepo) UpdatePaymentID(ctx context.Context, order *en
 	return nil
 }
 
+// attempt is counted in the same statement as the status change, an event that lost the race changes neither
+const queryFailDeliveryOrder = `
+	UPDATE orders SET
+		delivery_attempts = delivery_attempts + 1,
+		status = CASE WHEN delivery_attempts + 1 >= $1 THEN 'RETURNING' ELSE 'DELIVERY_FAILED' END,
+		updated_by = $2, updated_at = $3, version = version + 1
+	WHERE id = $4 AND status = ANY($5)
+	RETURNING delivery_attempts, status, version;
+`
+
+// FailDelivery count the failed delivery attempt and move the order to DELIVERY_FAILED, or to RETURNING
+// when the attempts reach maxAttempts. sql.ErrNoRows when the order has already left the from statuses
+func (r *OrderPostgreCommandRepo) FailDelivery(ctx context.Context, order *entity.Order, maxAttempts int, from []string) error {
+	stmt, errStmt := r.Conn.PrepareContext(ctx, queryFailDeliveryOrder)
+	if errStmt != nil {
+		return errStmt
+	}
+	defer stmt.Close()
+
+	return stmt.QueryRowContext(ctx, maxAttempts, order.UpdatedBy, order.UpdatedAt, order.ID, pq.Array(from)).Scan(&order.DeliveryAttempts, &order.Status, &order.Version)
+}
+
 const queryGetOrderByID = `
 	SELECT 
 		o.id,
 		o.user_id,
+		o.status,
+		o.total_price,
+		o.paid_amount,
+		o.promotion_id,
+		o.promotion_code,
+		o.discount_amount,
+		o.tax_amount,
+		o.shipping_method,
+		o.shipping_estimated_days,
+		o.estimated_shipping,
+		o.shipping_rule,
+		o.fulfilment_type,
+		o.pickup_warehouse_id,
+		o.pickup_code,
+		o.payment_id,
+		o.version,
+		o.paid_at,
+		o.sale_reported_at,
+		o.shipped_at,
+		o.delivered_at,
+		oa.id as address_id,
+		oa.street as address_street,
+		oa.city as address_city,
+		oa.state as address_state,
+		oa.zip_code as address_zip_code,
+		oa.note as address_note,
+		oi.id as item_id,
 		oi.product_id as item_product_id,
-		oi.product_quantity as item_product_quantity
+		oi.category_id as item_category_id,
+		oi.product_type as item_product_type,
+		oi.product_quantity as item_product_quantity,
+		oi.price as item_price,
+		oi.shipping_cost as item_shipping_cost,
+		oi.discount as item_discount,
+		oi.shipping_discount as item_shipping_discount,
+		oi.tax as item_tax
 	FROM orders o
-	LEFT JOIN order_items oi ON o.id = oi.order_id
+	LEFT JOIN order_addresses oa ON o.id = oa.order_id
+	LEFT JOIN order_items oi ON o.id = oi.order_id AND oi.deleted_at IS NULL
 	WHERE o.id = $1;
 `
 
@@ -127,11 +381,79 @@ func (r *OrderPostgreCommandRepo) GetByID(ctx context.Context, id uuid.UUID) (*e
 
 	var order entity.Order
 	for rows.Next() {
-		var item entity.OrderItem
-		if err := rows.Scan(&order.ID, &order.UserID, &item.ProductID, &item.ProductQuantity); err != nil {
+		var (
+			paymentID, promotionID uuid.NullUUID
+			pickupWarehouseID      uuid.NullUUID
+			promotionCode          sql.NullString
+			paidAt, saleReportedAt sql.NullTime
+			shippedAt, deliveredAt sql.NullTime
+			addressID              uuid.NullUUID
+			street, city, state    sql.NullString
+			zipCode, addressNote   sql.NullString
+			// item fields, null when every item is cancelled
+			itemID, productID uuid.NullUUID
+			categoryID        uuid.NullUUID
+			productType       sql.NullString
+			productQuantity   sql.NullInt64
+			price, shipping   sql.NullFloat64
+			discount          sql.NullFloat64
+			shippingDiscount  sql.NullFloat64
+			tax               sql.NullFloat64
+		)
+		if err := rows.Scan(
+			&order.ID, &order.UserID, &order.Status, &order.TotalPrice, &order.PaidAmount,
+			&promotionID, &promotionCode, &order.DiscountAmount, &order.TaxAmount,
+			&order.ShippingMethod, &order.ShippingEstimatedDays, &order.EstimatedShipping, &order.ShippingRule,
+			&order.FulfilmentType, &pickupWarehouseID, &order.PickupCode, &paymentID, &order.Version,
+			&paidAt, &saleReportedAt, &shippedAt, &deliveredAt, &addressID, &street, &city, &state, &zipCode, &addressNote,
+			&itemID, &productID, &categoryID, &productType, &productQuantity, &price, &shipping, &discount, &shippingDiscount, &tax,
+		); err != nil {
 			return nil, err
 		}
-		order.Items = append(order.Items, item)
+		if paymentID.Valid {
+			order.PaymentID = paymentID.UUID
+		}
+		order.PromotionID = promotionID.UUID
+		order.PromotionCode = promotionCode.String
+		order.PickupWarehouseID = pickupWarehouseID.UUID
+		if paidAt.Valid {
+			order.PaidAt = paidAt.Time
+		}
+		if saleReportedAt.Valid {
+			order.SaleReportedAt = saleReportedAt.Time
+		}
+		if shippedAt.Valid {
+			order.ShippedAt = shippedAt.Time
+		}
+		if deliveredAt.Valid {
+			order.DeliveredAt = deliveredAt.Time
+		}
+		order.Address = entity.OrderAddress{
+			ID:      addressID.UUID,
+			OrderID: order.ID,
+			Street:  street.String,
+			City:    city.String,
+			State:   state.String,
+			ZipCode: zipCode.String,
+			Note:    addressNote.String,
+		}
+
+		if !itemID.Valid {
+			continue
+		}
+		order.Items = append(order.Items, entity.OrderItem{
+			ID:               itemID.UUID,
+			OrderID:          order.ID,
+			ProductID:        productID.UUID,
+			CategoryID:       categoryID.UUID,
+			ProductType:      productType.String,
+			ProductQuantity:  productQuantity.Int64,
+			Price:            price.Float64,
+			ShippingCost:     shipping.Float64,
+			Discount:         discount.Float64,
+			ShippingDiscount: shippingDiscount.Float64,
+			Tax:              tax.Float64,
+		})
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -139,3 +461,163 @@ func (r *OrderPostgreCommandRepo) GetByID(ctx context.Context, id uuid.UUID) (*e
 
 	return &order, nil
 }
+
+const queryCountOrders = `SELECT count(*) FROM orders WHERE deleted_at IS NULL;`
+
+func (r *OrderPostgreCommandRepo) Count(ctx context.Context) (int, error) {
+	var count int
+	err := r.Conn.QueryRowContext(ctx, queryCountOrders).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count orders: %w", err)
+	}
+
+	return count, nil
+}
+
+const (
+	// orders are walked by id, uuid v7 keeps them in creation order
+	queryGetOrderBatch = `
+		SELECT
+			o.id, o.user_id, o.status, o.total_price, o.paid_amount, o.promotion_id, o.promotion_code,
+			o.discount_amount, o.tax_amount, o.shipping_method, o.shipping_estimated_days, o.estimated_shipping,
+			o.shipping_rule, o.fulfilment_type, o.pickup_warehouse_id, o.pickup_code, o.payment_id, o.version,
+			o.paid_at, o.created_at, o.updated_at
+		FROM orders o
+		WHERE o.deleted_at IS NULL AND o.id > $1
+		ORDER BY o.id
+		LIMIT $2;
+	`
+	queryGetOrderBatchItems = `
+		SELECT
+			oi.id, oi.order_id, oi.product_id, oi.category_id, oi.product_type, oi.product_quantity, oi.price,
+			oi.shipping_cost, oi.discount, oi.shipping_discount, oi.tax, oi.note, oi.created_at, oi.updated_at
+		FROM order_items oi
+		WHERE oi.order_id = ANY($1) AND oi.deleted_at IS NULL
+		ORDER BY oi.created_at, oi.id;
+	`
+	queryGetOrderBatchAddresses = `
+		SELECT oa.id, oa.order_id, oa.street, oa.city, oa.state, oa.zip_code, oa.note, oa.created_at, oa.updated_at
+		FROM order_addresses oa
+		WHERE oa.order_id = ANY($1);
+	`
+)
+
+// GetBatch return the next orders after the id with their items and address, uuid.Nil starts from the first order
+func (r *OrderPostgreCommandRepo) GetBatch(ctx context.Context, after uuid.UUID, limit int) ([]*entity.Order, error) {
+	rows, err := r.Conn.QueryContext(ctx, queryGetOrderBatch, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		orders   []*entity.Order
+		orderIDs []uuid.UUID
+	)
+	byID := make(map[uuid.UUID]*entity.Order)
+	for rows.Next() {
+		var (
+			order                  entity.Order
+			promotionID, paymentID uuid.NullUUID
+			pickupWarehouseID      uuid.NullUUID
+			promotionCode          sql.NullString
+			paidAt                 sql.NullTime
+		)
+		if err := rows.Scan(
+			&order.ID, &order.UserID, &order.Status, &order.TotalPrice, &order.PaidAmount, &promotionID, &promotionCode,
+			&order.DiscountAmount, &order.TaxAmount, &order.ShippingMethod, &order.ShippingEstimatedDays, &order.EstimatedShipping,
+			&order.ShippingRule, &order.FulfilmentType, &pickupWarehouseID, &order.PickupCode, &paymentID, &order.Version,
+			&paidAt, &order.CreatedAt, &order.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		order.PromotionID = promotionID.UUID
+		order.PromotionCode = promotionCode.String
+		order.PickupWarehouseID = pickupWarehouseID.UUID
+		order.PaymentID = paymentID.UUID
+		order.PaidAt = paidAt.Time
+
+		orders = append(orders, &order)
+		orderIDs = append(orderIDs, order.ID)
+		byID[order.ID] = &order
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating order rows: %w", err)
+	}
+	if len(orders) == 0 {
+		return nil, nil
+	}
+
+	err = r.setBatchItems(ctx, byID, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.setBatchAddresses(ctx, byID, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+func (r *OrderPostgreCommandRepo) setBatchItems(ctx context.Context, byID map[uuid.UUID]*entity.Order, orderIDs []uuid.UUID) error {
+	rows, err := r.Conn.QueryContext(ctx, queryGetOrderBatchItems, pq.Array(orderIDs))
+	if err != nil {
+		return fmt.Errorf("failed to query order items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			item       entity.OrderItem
+			categoryID uuid.NullUUID
+			note       sql.NullString
+		)
+		if err := rows.Scan(
+			&item.ID, &item.OrderID, &item.ProductID, &categoryID, &item.ProductType, &item.ProductQuantity, &item.Price,
+			&item.ShippingCost, &item.Discount, &item.ShippingDiscount, &item.Tax, &note, &item.CreatedAt, &item.UpdatedAt,
+		); err != nil {
+			return fmt.Errorf("failed to scan order item: %w", err)
+		}
+		item.CategoryID = categoryID.UUID
+		item.Note = note.String
+
+		order := byID[item.OrderID]
+		order.Items = append(order.Items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating order item rows: %w", err)
+	}
+
+	return nil
+}
+
+func (r *OrderPostgreCommandRepo) setBatchAddresses(ctx context.Context, byID map[uuid.UUID]*entity.Order, orderIDs []uuid.UUID) error {
+	rows, err := r.Conn.QueryContext(ctx, queryGetOrderBatchAddresses, pq.Array(orderIDs))
+	if err != nil {
+		return fmt.Errorf("failed to query order addresses: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			address entity.OrderAddress
+			note    sql.NullString
+		)
+		if err := rows.Scan(
+			&address.ID, &address.OrderID, &address.Street, &address.City, &address.State, &address.ZipCode,
+			&note, &address.CreatedAt, &address.UpdatedAt,
+		); err != nil {
+			return fmt.Errorf("failed to scan order address: %w", err)
+		}
+		address.Note = note.String
+
+		byID[address.OrderID].Address = address
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating order address rows: %w", err)
+	}
+
+	return nil
+}
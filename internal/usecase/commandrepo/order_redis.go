@@ -9,7 +9,13 @@ import (
 	rClient "github.com/idoyudha/eshop-order/pkg/redis"
 )
 
-const orderKey = "order"
+const (
+	orderKey            = "order"
+	deliveryConfirmKey  = "delivery-confirm"
+	deliveryReminderKey = "delivery-reminder"
+	saleReportKey       = "sale-report"
+	reminderSentKey     = "delivery-reminder-sent"
+)
 
 type OrderRedisRepo struct {
 	*rClient.RedisClient
@@ -22,7 +28,23 @@ func NewOrderRedisRepo(client *rClient.RedisClient) *OrderRedisRepo {
 }
 
 func getOrderKey(orderID uuid.UUID) string {
-	return fmt.Sprintf("order:%s", orderID.String())
+	return fmt.Sprintf("%s:%s", orderKey, orderID.String())
+}
+
+func getDeliveryConfirmKey(orderID uuid.UUID) string {
+	return fmt.Sprintf("%s:%s", deliveryConfirmKey, orderID.String())
+}
+
+func getDeliveryReminderKey(orderID uuid.UUID) string {
+	return fmt.Sprintf("%s:%s", deliveryReminderKey, orderID.String())
+}
+
+func getReminderSentKey(orderID uuid.UUID) string {
+	return fmt.Sprintf("%s:%s", reminderSentKey, orderID.String())
+}
+
+func getSaleReportKey(orderID uuid.UUID) string {
+	return fmt.Sprintf("%s:%s", saleReportKey, orderID.String())
 }
 
 func (r *OrderRedisRepo) Set(ctx context.Context, orderID uuid.UUID, value string, ttl time.Duration) error {
@@ -39,3 +61,33 @@ func (r *OrderRedisRepo) GetTTL(ctx context.Context, orderID uuid.UUID) (time.Du
 	key := getOrderKey(orderID)
 	return r.RedisClient.Client.TTL(ctx, key).Result()
 }
+
+// SetDeliverySchedule schedules the automatic delivery confirmation and the reminder before it.
+// existing schedule is kept, so repeated courier events do not restart the grace period.
+func (r *OrderRedisRepo) SetDeliverySchedule(ctx context.Context, orderID uuid.UUID, confirmTTL, reminderTTL time.Duration) error {
+	err := r.RedisClient.Client.SetNX(ctx, getDeliveryConfirmKey(orderID), "", confirmTTL).Err()
+	if err != nil {
+		return err
+	}
+
+	if reminderTTL <= 0 {
+		return nil
+	}
+	return r.RedisClient.Client.SetNX(ctx, getDeliveryReminderKey(orderID), "", reminderTTL).Err()
+}
+
+// DeleteDeliverySchedule drop the schedule and the sent reminder marker, the next attempt gets its own reminder
+func (r *OrderRedisRepo) DeleteDeliverySchedule(ctx context.Context, orderID uuid.UUID) error {
+	return r.RedisClient.Client.Del(ctx, getDeliveryConfirmKey(orderID), getDeliveryReminderKey(orderID), getReminderSentKey(orderID)).Err()
+}
+
+// ClaimDeliveryReminder mark the reminder of the order as sent, false when it is already claimed.
+// every instance receives the expired reminder key, only the one that claims it notifies the customer
+func (r *OrderRedisRepo) ClaimDeliveryReminder(ctx context.Context, orderID uuid.UUID, ttl time.Duration) (bool, error) {
+	return r.RedisClient.Client.SetNX(ctx, getReminderSentKey(orderID), "", ttl).Result()
+}
+
+// SetSaleReportRetry schedule another attempt to publish the sale report of the order
+func (r *OrderRedisRepo) SetSaleReportRetry(ctx context.Context, orderID uuid.UUID, ttl time.Duration) error {
+	return r.RedisClient.Client.Set(ctx, getSaleReportKey(orderID), "", ttl).Err()
+}
@@ -0,0 +1,144 @@
+package commandrepo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
+	"github.com/idoyudha/eshop-order/pkg/postgresql/postgrecommand"
+)
+
+type PromotionPostgreCommandRepo struct {
+	*postgrecommand.PostgresCommand
+}
+
+func NewPromotionPostgreCommandRepo(conn *postgrecommand.PostgresCommand) *PromotionPostgreCommandRepo {
+	return &PromotionPostgreCommandRepo{
+		PostgresCommand: conn,
+	}
+}
+
+const queryInsertPromotion = `
+	INSERT INTO promotions (
+		id, code, type, value, max_discount, min_spend, product_id, buy_quantity, get_quantity,
+		start_at, end_at, usage_limit, usage_limit_per_user, usage_count, created_at, updated_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16);
+`
+
+func (r *PromotionPostgreCommandRepo) Insert(ctx context.Context, promotion *entity.Promotion) error {
+	stmt, errStmt := r.Conn.PrepareContext(ctx, queryInsertPromotion)
+	if errStmt != nil {
+		return errStmt
+	}
+	defer stmt.Close()
+
+	productID := uuid.NullUUID{UUID: promotion.ProductID, Valid: promotion.ProductID != uuid.Nil}
+	_, insertErr := stmt.ExecContext(ctx,
+		promotion.ID, promotion.Code, promotion.Type, promotion.Value, promotion.MaxDiscount, promotion.MinSpend,
+		productID, promotion.BuyQuantity, promotion.GetQuantity, promotion.StartAt, promotion.EndAt,
+		promotion.UsageLimit, promotion.UsageLimitPerUser, promotion.UsageCount, promotion.CreatedAt, promotion.UpdatedAt)
+	if insertErr != nil {
+		return insertErr
+	}
+
+	return nil
+}
+
+const baseQueryPromotion = `
+	SELECT
+		id, code, type, value, max_discount, min_spend, product_id, buy_quantity, get_quantity,
+		start_at, end_at, usage_limit, usage_limit_per_user, usage_count, created_at, updated_at
+	FROM promotions
+	WHERE deleted_at IS NULL
+`
+
+const queryGetPromotionByCode = baseQueryPromotion + ` AND code = $1;`
+
+func (r *PromotionPostgreCommandRepo) GetByCode(ctx context.Context, code string) (*entity.Promotion, error) {
+	return scanPromotion(r.Conn.QueryRowContext(ctx, queryGetPromotionByCode, code))
+}
+
+const queryGetPromotionByID = baseQueryPromotion + ` AND id = $1;`
+
+func (r *PromotionPostgreCommandRepo) GetByID(ctx context.Context, id uuid.UUID) (*entity.Promotion, error) {
+	return scanPromotion(r.Conn.QueryRowContext(ctx, queryGetPromotionByID, id))
+}
+
+const (
+	queryGetAllPromotion      = baseQueryPromotion + ` ORDER BY created_at DESC, id DESC LIMIT $1;`
+	queryGetAllPromotionAfter = baseQueryPromotion + ` AND (created_at, id) < ($1, $2) ORDER BY created_at DESC, id DESC LIMIT $3;`
+)
+
+// GetAll return one keyset page of promotions, one more row than the limit is fetched to know whether there is a next page
+func (r *PromotionPostgreCommandRepo) GetAll(ctx context.Context, page entity.Page) ([]*entity.Promotion, entity.PageCursor, error) {
+	query, args := queryGetAllPromotion, []interface{}{page.Limit + 1}
+	if !page.After.IsZero() {
+		createdAt, err := page.After.TimeValue()
+		if err != nil || page.After.Sort != entity.PAGE_SORT_CREATED_AT {
+			return nil, entity.PageCursor{}, entity.ErrInvalidPageCursor
+		}
+		query, args = queryGetAllPromotionAfter, []interface{}{createdAt, page.After.ID, page.Limit + 1}
+	}
+
+	rows, err := r.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, entity.PageCursor{}, err
+	}
+	defer rows.Close()
+
+	var promotions []*entity.Promotion
+	for rows.Next() {
+		promotion, err := scanPromotion(rows)
+		if err != nil {
+			return nil, entity.PageCursor{}, err
+		}
+		promotions = append(promotions, promotion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, entity.PageCursor{}, err
+	}
+
+	var next entity.PageCursor
+	if len(promotions) > page.Limit {
+		promotions = promotions[:page.Limit]
+		last := promotions[len(promotions)-1]
+		next = entity.NewTimePageCursor(entity.PAGE_SORT_CREATED_AT, last.CreatedAt, last.ID)
+	}
+
+	return promotions, next, nil
+}
+
+const queryGetUserUsage = `SELECT COUNT(*) FROM promotion_usages WHERE promotion_id = $1 AND user_id = $2;`
+
+func (r *PromotionPostgreCommandRepo) GetUserUsage(ctx context.Context, promotionID, userID uuid.UUID) (int, error) {
+	var usage int
+	err := r.Conn.QueryRowContext(ctx, queryGetUserUsage, promotionID, userID).Scan(&usage)
+	if err != nil {
+		return 0, err
+	}
+
+	return usage, nil
+}
+
+// scanner is implemented by both sql.Row and sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPromotion(row scanner) (*entity.Promotion, error) {
+	var (
+		promotion entity.Promotion
+		productID uuid.NullUUID
+	)
+	err := row.Scan(
+		&promotion.ID, &promotion.Code, &promotion.Type, &promotion.Value, &promotion.MaxDiscount, &promotion.MinSpend,
+		&productID, &promotion.BuyQuantity, &promotion.GetQuantity, &promotion.StartAt, &promotion.EndAt,
+		&promotion.UsageLimit, &promotion.UsageLimitPerUser, &promotion.UsageCount, &promotion.CreatedAt, &promotion.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	promotion.ProductID = productID.UUID
+
+	return &promotion, nil
+}
@@ -0,0 +1,187 @@
+package commandrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
+	"github.com/idoyudha/eshop-order/pkg/postgresql/postgrecommand"
+	"github.com/lib/pq"
+)
+
+type RefundPostgreCommandRepo struct {
+	*postgrecommand.PostgresCommand
+}
+
+func NewRefundPostgreCommandRepo(conn *postgrecommand.PostgresCommand) *RefundPostgreCommandRepo {
+	return &RefundPostgreCommandRepo{
+		PostgresCommand: conn,
+	}
+}
+
+const (
+	// refunds of an order are saved one at a time, the next one waits for the order row
+	queryLockRefundOrder = `SELECT paid_amount FROM orders WHERE id = $1 FOR UPDATE;`
+	// quantity of the items that is not refunded yet
+	queryGetRefundableQuantity = `
+		SELECT oi.id, oi.product_quantity - COALESCE((
+			SELECT SUM(ri.product_quantity) FROM refund_items ri
+			JOIN refunds r ON r.id = ri.refund_id
+			WHERE ri.order_item_id = oi.id AND r.deleted_at IS NULL
+		), 0)
+		FROM order_items oi
+		WHERE oi.id = ANY($1);
+	`
+	queryInsertRefund      = `INSERT INTO refunds (id, order_id, user_id, return_id, amount, reason, status, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);`
+	queryInsertRefundItems = `INSERT INTO refund_items (id, refund_id, order_item_id, product_id, product_quantity, price, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8);`
+)
+
+func (r *RefundPostgreCommandRepo) Insert(ctx context.Context, refund *entity.Refund) error {
+	tx, err := r.Conn.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = insertRefund(ctx, tx, refund)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// insertRefund check the refund with the order locked and save it in the transaction of the caller
+func insertRefund(ctx context.Context, tx *sql.Tx, refund *entity.Refund) error {
+	err := checkRefundable(ctx, tx, refund)
+	if err != nil {
+		return err
+	}
+
+	returnID := uuid.NullUUID{UUID: refund.ReturnID, Valid: refund.ReturnID != uuid.Nil}
+
+	// insert refund
+	_, err = tx.ExecContext(ctx, queryInsertRefund,
+		refund.ID, refund.OrderID, refund.UserID, returnID, refund.Amount, refund.Reason, refund.Status,
+		refund.CreatedAt, refund.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	// insert refund items
+	for _, item := range refund.Items {
+		_, err = tx.ExecContext(ctx, queryInsertRefundItems,
+			item.ID, refund.ID, item.OrderItemID, item.ProductID, item.ProductQuantity, item.Price,
+			item.CreatedAt, item.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkRefundable lock the order and check the refund against what is left to refund,
+// entity.ErrRefundExceedsRemaining when a refund saved since the check in the usecase took it
+func checkRefundable(ctx context.Context, tx *sql.Tx, refund *entity.Refund) error {
+	var paidAmount float64
+	err := tx.QueryRowContext(ctx, queryLockRefundOrder, refund.OrderID).Scan(&paidAmount)
+	if err != nil {
+		return fmt.Errorf("failed to lock order: %w", err)
+	}
+
+	var refundedAmount float64
+	err = tx.QueryRowContext(ctx, queryGetRefundedAmountByOrderID, refund.OrderID).Scan(&refundedAmount)
+	if err != nil {
+		return fmt.Errorf("failed to get refunded amount: %w", err)
+	}
+	if refund.Amount-(paidAmount-refundedAmount) > entity.REFUND_AMOUNT_TOLERANCE {
+		return entity.ErrRefundExceedsRemaining
+	}
+
+	if len(refund.Items) == 0 {
+		return nil
+	}
+
+	orderItemIDs := make([]uuid.UUID, len(refund.Items))
+	for i, item := range refund.Items {
+		orderItemIDs[i] = item.OrderItemID
+	}
+	rows, err := tx.QueryContext(ctx, queryGetRefundableQuantity, pq.Array(orderItemIDs))
+	if err != nil {
+		return fmt.Errorf("failed to get refundable quantity: %w", err)
+	}
+	defer rows.Close()
+
+	refundable := make(map[uuid.UUID]int64)
+	for rows.Next() {
+		var orderItemID uuid.UUID
+		var quantity int64
+		if err := rows.Scan(&orderItemID, &quantity); err != nil {
+			return fmt.Errorf("failed to scan refundable quantity: %w", err)
+		}
+		refundable[orderItemID] = quantity
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating refundable quantity rows: %w", err)
+	}
+
+	for _, item := range refund.Items {
+		if item.ProductQuantity > refundable[item.OrderItemID] {
+			return entity.ErrRefundExceedsRemaining
+		}
+	}
+
+	return nil
+}
+
+const queryGetRefundedAmountByOrderID = `SELECT COALESCE(SUM(amount), 0) FROM refunds WHERE order_id = $1 AND deleted_at IS NULL;`
+
+func (r *RefundPostgreCommandRepo) GetRefundedAmountByOrderID(ctx context.Context, orderID uuid.UUID) (float64, error) {
+	var amount float64
+	err := r.Conn.QueryRowContext(ctx, queryGetRefundedAmountByOrderID, orderID).Scan(&amount)
+	if err != nil {
+		return 0, err
+	}
+
+	return amount, nil
+}
+
+const queryGetRefundedQuantityByOrderID = `
+	SELECT ri.order_item_id, SUM(ri.product_quantity)
+	FROM refunds r
+	JOIN refund_items ri ON r.id = ri.refund_id
+	WHERE r.order_id = $1 AND r.deleted_at IS NULL
+	GROUP BY ri.order_item_id;
+`
+
+func (r *RefundPostgreCommandRepo) GetRefundedQuantityByOrderID(ctx context.Context, orderID uuid.UUID) (map[uuid.UUID]int64, error) {
+	rows, err := r.Conn.QueryContext(ctx, queryGetRefundedQuantityByOrderID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	quantities := make(map[uuid.UUID]int64)
+	for rows.Next() {
+		var orderItemID uuid.UUID
+		var quantity int64
+		if err := rows.Scan(&orderItemID, &quantity); err != nil {
+			return nil, err
+		}
+		quantities[orderItemID] = quantity
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return quantities, nil
+}
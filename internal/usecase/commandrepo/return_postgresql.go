@@ -0,0 +1,170 @@
+package commandrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
+	"github.com/idoyudha/eshop-order/pkg/postgresql/postgrecommand"
+)
+
+type ReturnPostgreCommandRepo struct {
+	*postgrecommand.PostgresCommand
+}
+
+func NewReturnPostgreCommandRepo(conn *postgrecommand.PostgresCommand) *ReturnPostgreCommandRepo {
+	return &ReturnPostgreCommandRepo{
+		PostgresCommand: conn,
+	}
+}
+
+const (
+	queryInsertOrderReturn      = `INSERT INTO order_returns (id, order_id, user_id, status, reason, refund_amount, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8);`
+	queryInsertOrderReturnItems = `INSERT INTO order_return_items (id, return_id, order_item_id, product_id, product_quantity, price, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8);`
+)
+
+func (r *ReturnPostgreCommandRepo) Insert(ctx context.Context, orderReturn *entity.OrderReturn) error {
+	tx, err := r.Conn.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// insert return
+	_, err = tx.ExecContext(ctx, queryInsertOrderReturn,
+		orderReturn.ID, orderReturn.OrderID, orderReturn.UserID, orderReturn.Status, orderReturn.Reason,
+		orderReturn.RefundAmount, orderReturn.CreatedAt, orderReturn.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	// insert return items
+	for _, item := range orderReturn.Items {
+		_, err = tx.ExecContext(ctx, queryInsertOrderReturnItems,
+			item.ID, orderReturn.ID, item.OrderItemID, item.ProductID, item.ProductQuantity, item.Price,
+			item.CreatedAt, item.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+const queryUpdateStatusOrderReturn = `UPDATE order_returns SET status = $1, admin_note = $2, updated_at = $3 WHERE id = $4 AND status = $5;`
+
+// UpdateStatus move the return from one status to another, sql.ErrNoRows when the return
+// is not in the from status anymore, so two admins can not both decide the same return
+func (r *ReturnPostgreCommandRepo) UpdateStatus(ctx context.Context, orderReturn *entity.OrderReturn, from string) error {
+	stmt, errStmt := r.Conn.PrepareContext(ctx, queryUpdateStatusOrderReturn)
+	if errStmt != nil {
+		return errStmt
+	}
+	defer stmt.Close()
+
+	res, updateErr := stmt.ExecContext(ctx, orderReturn.Status, orderReturn.AdminNote, orderReturn.UpdatedAt, orderReturn.ID, from)
+	if updateErr != nil {
+		return updateErr
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+const queryGetOrderReturnByID = `
+	SELECT
+		r.id,
+		r.order_id,
+		r.user_id,
+		r.status,
+		r.reason,
+		r.refund_amount,
+		ri.id as item_id,
+		ri.order_item_id as item_order_item_id,
+		ri.product_id as item_product_id,
+		ri.product_quantity as item_product_quantity,
+		ri.price as item_price
+	FROM order_returns r
+	LEFT JOIN order_return_items ri ON r.id = ri.return_id
+	WHERE r.id = $1 AND r.deleted_at IS NULL;
+`
+
+func (r *ReturnPostgreCommandRepo) GetByID(ctx context.Context, id uuid.UUID) (*entity.OrderReturn, error) {
+	rows, err := r.Conn.QueryContext(ctx, queryGetOrderReturnByID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orderReturn *entity.OrderReturn
+	for rows.Next() {
+		var ret entity.OrderReturn
+		var item entity.OrderReturnItem
+		if err := rows.Scan(
+			&ret.ID, &ret.OrderID, &ret.UserID, &ret.Status, &ret.Reason, &ret.RefundAmount,
+			&item.ID, &item.OrderItemID, &item.ProductID, &item.ProductQuantity, &item.Price,
+		); err != nil {
+			return nil, err
+		}
+		if orderReturn == nil {
+			orderReturn = &ret
+		}
+		item.ReturnID = orderReturn.ID
+		orderReturn.Items = append(orderReturn.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if orderReturn == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	return orderReturn, nil
+}
+
+// quantity of each order item that is already requested or approved to be returned
+const queryGetReturnedQuantityByOrderID = `
+	SELECT ri.order_item_id, SUM(ri.product_quantity)
+	FROM order_returns r
+	JOIN order_return_items ri ON r.id = ri.return_id
+	WHERE r.order_id = $1 AND r.status <> 'REJECTED' AND r.deleted_at IS NULL
+	GROUP BY ri.order_item_id;
+`
+
+func (r *ReturnPostgreCommandRepo) GetReturnedQuantityByOrderID(ctx context.Context, orderID uuid.UUID) (map[uuid.UUID]int64, error) {
+	rows, err := r.Conn.QueryContext(ctx, queryGetReturnedQuantityByOrderID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	quantities := make(map[uuid.UUID]int64)
+	for rows.Next() {
+		var orderItemID uuid.UUID
+		var quantity int64
+		if err := rows.Scan(&orderItemID, &quantity); err != nil {
+			return nil, err
+		}
+		quantities[orderItemID] = quantity
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return quantities, nil
+}
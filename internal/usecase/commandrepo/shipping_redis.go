@@ -0,0 +1,113 @@
+package commandrepo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/idoyudha/eshop-order/internal/entity"
+	rClient "github.com/idoyudha/eshop-order/pkg/redis"
+	"github.com/redis/go-redis/v9"
+)
+
+const shippingCostKey = "shipping-cost"
+
+type ShippingRedisRepo struct {
+	*rClient.RedisClient
+}
+
+func NewShippingRedisRepo(client *rClient.RedisClient) *ShippingRedisRepo {
+	return &ShippingRedisRepo{
+		client,
+	}
+}
+
+func getShippingCostKey(fromZip, toZip, method string) string {
+	return fmt.Sprintf("%s:%s:%s:%s", shippingCostKey, fromZip, toZip, method)
+}
+
+type cachedShippingRate struct {
+	Cost          float64 `json:"cost"`
+	EstimatedDays int     `json:"estimated_days"`
+}
+
+// GetRates return the cached rate of every method, nil when any of the methods is not cached
+func (r *ShippingRedisRepo) GetRates(ctx context.Context, fromZip, toZip string, methods []string) ([]entity.ShippingRate, error) {
+	if len(methods) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(methods))
+	for _, method := range methods {
+		keys = append(keys, getShippingCostKey(fromZip, toZip, method))
+	}
+
+	values, err := r.RedisClient.Client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make([]entity.ShippingRate, 0, len(methods))
+	for i, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			return nil, nil
+		}
+
+		var cached cachedShippingRate
+		if err := json.Unmarshal([]byte(str), &cached); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal cached shipping rate: %w", err)
+		}
+		rates = append(rates, entity.ShippingRate{
+			Method:        methods[i],
+			Cost:          cached.Cost,
+			EstimatedDays: cached.EstimatedDays,
+		})
+	}
+
+	return rates, nil
+}
+
+func (r *ShippingRedisRepo) SetRates(ctx context.Context, fromZip, toZip string, rates []entity.ShippingRate, ttl time.Duration) error {
+	pipe := r.RedisClient.Client.Pipeline()
+	for _, rate := range rates {
+		value, err := json.Marshal(cachedShippingRate{
+			Cost:          rate.Cost,
+			EstimatedDays: rate.EstimatedDays,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal shipping rate: %w", err)
+		}
+		pipe.Set(ctx, getShippingCostKey(fromZip, toZip, rate.Method), value, ttl)
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// DeleteRates remove the cached rates of the zip pair, empty zip matches any zip
+func (r *ShippingRedisRepo) DeleteRates(ctx context.Context, fromZip, toZip string) (int64, error) {
+	if fromZip == "" {
+		fromZip = "*"
+	}
+	if toZip == "" {
+		toZip = "*"
+	}
+	pattern := getShippingCostKey(fromZip, toZip, "*")
+
+	var deleted int64
+	iter := r.RedisClient.Client.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		n, err := r.RedisClient.Client.Del(ctx, iter.Val()).Result()
+		if err != nil && err != redis.Nil {
+			return deleted, err
+		}
+		deleted += n
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, err
+	}
+
+	return deleted, nil
+}
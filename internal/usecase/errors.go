@@ -0,0 +1,12 @@
+package usecase
+
+import "errors"
+
+// sentinel errors, wrapped with detail so the controller can map them to the right response
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrValidation = errors.New("validation failed")
+	ErrConflict   = errors.New("conflict with current state")
+	// the read model has not caught up with the version asked by the client
+	ErrProjectionBehind = errors.New("projection is behind")
+)
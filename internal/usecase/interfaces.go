@@ -12,44 +12,186 @@ type (
 	OrderPostgreCommandRepo interface {
 		Insert(context.Context, *entity.Order) error
 		UpdateStatus(context.Context, *entity.Order) error
+		UpdateStatusFrom(context.Context, *entity.Order, []string) error
 		UpdatePaymentID(context.Context, *entity.Order) error
+		UpdateItems(context.Context, *entity.Order, *entity.Refund) error
+		Update(context.Context, *entity.Order) error
+		UpdateSaleReported(context.Context, *entity.Order) error
+		FailDelivery(context.Context, *entity.Order, int, []string) error
 		GetByID(context.Context, uuid.UUID) (*entity.Order, error)
+		Count(context.Context) (int, error)
+		GetBatch(context.Context, uuid.UUID, int) ([]*entity.Order, error)
 	}
 
 	OrderRedisRepo interface {
 		Set(context.Context, uuid.UUID, string, time.Duration) error
 		Delete(context.Context, uuid.UUID) error
 		GetTTL(context.Context, uuid.UUID) (time.Duration, error)
+		SetDeliverySchedule(context.Context, uuid.UUID, time.Duration, time.Duration) error
+		DeleteDeliverySchedule(context.Context, uuid.UUID) error
+		ClaimDeliveryReminder(context.Context, uuid.UUID, time.Duration) (bool, error)
+		SetSaleReportRetry(context.Context, uuid.UUID, time.Duration) error
+	}
+
+	ShippingRedisRepo interface {
+		GetRates(context.Context, string, string, []string) ([]entity.ShippingRate, error)
+		SetRates(context.Context, string, string, []entity.ShippingRate, time.Duration) error
+		DeleteRates(context.Context, string, string) (int64, error)
+	}
+
+	OrderRedisQueryRepo interface {
+		Get(context.Context, uuid.UUID) (*entity.OrderView, error)
+		Set(context.Context, *entity.OrderView, time.Duration) error
+		Delete(context.Context, uuid.UUID) error
+		DeleteAll(context.Context) error
+	}
+
+	OrderRebuildQueryRepo interface {
+		TryLock(context.Context, string) (func(), error)
+		PrepareShadow(context.Context) error
+		GetByOrderIDs(context.Context, []uuid.UUID) (map[uuid.UUID]*entity.OrderView, error)
+		WriteBatch(context.Context, bool, []*entity.OrderView) (int, error)
+		SwapShadow(context.Context, time.Time) error
+		GetOrderIDsBetween(context.Context, uuid.UUID, uuid.UUID, time.Time) ([]uuid.UUID, error)
+		DeleteByOrderID(context.Context, uuid.UUID) error
 	}
 
 	OrderPostgreQueryRepo interface {
 		Insert(context.Context, *entity.OrderView) error
 		UpdatePayment(context.Context, *entity.OrderView) error
 		GetByID(context.Context, uuid.UUID) (*entity.OrderView, error)
-		GetAll(context.Context) ([]*entity.OrderView, error)
-		GetByUserID(context.Context, uuid.UUID) ([]*entity.OrderView, error)
+		List(context.Context, entity.OrderFilter, entity.Page) ([]*entity.OrderView, entity.PageCursor, error)
+		Search(context.Context, string, entity.Page) ([]*entity.OrderView, entity.PageCursor, error)
+		GetByUserID(context.Context, uuid.UUID, entity.Page) ([]*entity.OrderView, entity.PageCursor, error)
 		GetByPaymentID(context.Context, uuid.UUID) (*entity.OrderView, error)
-		GetByStatus(context.Context, string) ([]*entity.OrderView, error)
 		UpdateStatus(context.Context, *entity.OrderView) error
-		GetProductPriceByOrderID(context.Context, uuid.UUID) (map[uuid.UUID]float64, error)
+		UpdateItems(context.Context, *entity.OrderView) error
+		Update(context.Context, *entity.OrderView) error
+		InsertParkedEvent(context.Context, *entity.ParkedOrderEvent) error
+		GetParkedEvents(context.Context, uuid.UUID) ([]*entity.ParkedOrderEvent, error)
+		GetReplayableParkedOrderIDs(context.Context, int) ([]uuid.UUID, error)
+		DeleteParkedEvent(context.Context, uuid.UUID) error
+	}
+
+	ReturnPostgreCommandRepo interface {
+		Insert(context.Context, *entity.OrderReturn) error
+		UpdateStatus(context.Context, *entity.OrderReturn, string) error
+		GetByID(context.Context, uuid.UUID) (*entity.OrderReturn, error)
+		GetReturnedQuantityByOrderID(context.Context, uuid.UUID) (map[uuid.UUID]int64, error)
+	}
+
+	ReturnPostgreQueryRepo interface {
+		Insert(context.Context, *entity.OrderReturnView) error
+		UpdateStatus(context.Context, *entity.OrderReturnView) error
+		GetByOrderID(context.Context, uuid.UUID, entity.Page) ([]*entity.OrderReturnView, entity.PageCursor, error)
+	}
+
+	RefundPostgreCommandRepo interface {
+		Insert(context.Context, *entity.Refund) error
+		GetRefundedAmountByOrderID(context.Context, uuid.UUID) (float64, error)
+		GetRefundedQuantityByOrderID(context.Context, uuid.UUID) (map[uuid.UUID]int64, error)
+	}
+
+	RefundPostgreQueryRepo interface {
+		Insert(context.Context, *entity.RefundView) error
+		GetByOrderID(context.Context, uuid.UUID, entity.Page) ([]*entity.RefundView, entity.PageCursor, error)
+	}
+
+	ShippingRateProvider interface {
+		GetShippingRates(context.Context, string, string) ([]entity.ShippingRate, error)
+	}
+
+	ShippingRuleEngine interface {
+		ApplyShippingRules(context.Context, *entity.Order) error
+	}
+
+	TaxCalculator interface {
+		TaxRate(context.Context, entity.OrderAddress, uuid.UUID) (float64, error)
+	}
+
+	PromotionPostgreCommandRepo interface {
+		Insert(context.Context, *entity.Promotion) error
+		GetByCode(context.Context, string) (*entity.Promotion, error)
+		GetByID(context.Context, uuid.UUID) (*entity.Promotion, error)
+		GetAll(context.Context, entity.Page) ([]*entity.Promotion, entity.PageCursor, error)
+		GetUserUsage(context.Context, uuid.UUID, uuid.UUID) (int, error)
 	}
 
 	OrderCommand interface {
 		CreateOrder(context.Context, *entity.Order, string) error
 		UpdateOrderStatus(context.Context, *entity.Order, string) error
 		UpdateOrderPaymentID(context.Context, *entity.Order, string) error
+		UpdateOrderDelivery(context.Context, *entity.Order, string) error
+		CancelOrderItems(context.Context, *entity.Order, string) error
+		EditOrder(context.Context, *entity.Order, string) error
+		GetShippingQuotes(context.Context, *entity.Order, string) ([]entity.ShippingRate, error)
+		ConfirmPickup(context.Context, *entity.Order) error
+		AutoConfirmDelivery(context.Context, uuid.UUID) error
+		SendDeliveryReminder(context.Context, uuid.UUID) error
 		SendSalesReport(context.Context, uuid.UUID) error
+		ReportSale(context.Context, uuid.UUID) error
 		GetOrderTTL(context.Context, uuid.UUID) (int, error)
+		GetOrderByID(context.Context, uuid.UUID) (*entity.Order, error)
 	}
 
 	OrderQuery interface {
 		CreateOrderView(context.Context, *entity.OrderView) error
 		UpdateOrderViewPayment(context.Context, *entity.OrderView, string) error
 		GetOrderByID(context.Context, uuid.UUID) (*entity.OrderView, error)
-		GetAllOrders(context.Context) ([]*entity.OrderView, error)
-		GetOrderByUserID(context.Context, uuid.UUID) ([]*entity.OrderView, error)
+		GetOrderByIDAtVersion(context.Context, uuid.UUID, int) (*entity.OrderView, error)
+		ListOrders(context.Context, entity.OrderFilter, entity.Page) ([]*entity.OrderView, entity.PageCursor, error)
+		SearchOrders(context.Context, string, entity.Page) ([]*entity.OrderView, entity.PageCursor, error)
+		GetOrderByUserID(context.Context, uuid.UUID, entity.Page) ([]*entity.OrderView, entity.PageCursor, error)
 		GetOrderByPaymentID(context.Context, uuid.UUID) (*entity.OrderView, error)
-		GetOrderByStatus(context.Context, string) ([]*entity.OrderView, error)
 		UpdateOrderViewStatus(context.Context, *entity.OrderView) error
+		UpdateOrderViewItems(context.Context, *entity.OrderView) error
+		UpdateOrderView(context.Context, *entity.OrderView) error
+		InvalidateOrderView(context.Context, uuid.UUID) error
+		ReplayParkedOrderEvents(context.Context) (int, error)
+	}
+
+	ProjectionRebuild interface {
+		RebuildOrderViews(context.Context, bool, int) (entity.ProjectionRebuild, error)
+		StartOrderViewRebuild(context.Context, bool, int) (entity.ProjectionRebuild, error)
+		GetOrderViewRebuild(context.Context) (entity.ProjectionRebuild, error)
+		RepairOrderViews(context.Context, []*entity.Order) error
+	}
+
+	ProjectionReconcile interface {
+		ReconcileOrderViews(context.Context, string) (entity.ReconcileReport, error)
+		StartOrderViewReconcile(context.Context, string) (entity.ReconcileReport, error)
+		GetOrderViewReconcile(context.Context) (entity.ReconcileReport, error)
+	}
+
+	ReturnCommand interface {
+		CreateReturn(context.Context, *entity.OrderReturn) error
+		ApproveReturn(context.Context, *entity.OrderReturn, string) error
+		RejectReturn(context.Context, *entity.OrderReturn) error
+	}
+
+	ReturnQuery interface {
+		CreateReturnView(context.Context, *entity.OrderReturnView) error
+		UpdateReturnViewStatus(context.Context, *entity.OrderReturnView) error
+		GetReturnsByOrderID(context.Context, uuid.UUID, entity.Page) ([]*entity.OrderReturnView, entity.PageCursor, error)
+	}
+
+	RefundCommand interface {
+		IssueRefund(context.Context, *entity.Refund) error
+		PrepareRefund(context.Context, *entity.Refund) error
+		PublishRefund(*entity.Refund, uuid.UUID) error
+	}
+
+	RefundQuery interface {
+		CreateRefundView(context.Context, *entity.RefundView) error
+		GetRefundsByOrderID(context.Context, uuid.UUID, entity.Page) ([]*entity.RefundView, entity.PageCursor, error)
+	}
+
+	PromotionCommand interface {
+		CreatePromotion(context.Context, *entity.Promotion) error
+		GetAllPromotions(context.Context, entity.Page) ([]*entity.Promotion, entity.PageCursor, error)
+	}
+
+	ShippingCommand interface {
+		InvalidateShippingCostCache(context.Context, string, string) (int64, error)
 	}
 )
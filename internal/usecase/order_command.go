@@ -3,10 +3,14 @@ package usecase
 import (
 	"bytes"
 	"context"
+	"crypto/subtle"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -21,9 +25,15 @@ type OrderCommandUseCase struct {
 	repoPostgresCommand OrderPostgreCommandRepo
 	repoPostgresQuery   OrderPostgreQueryRepo
 	repoRedisCommand    OrderRedisRepo
+	repoPromotion       PromotionPostgreCommandRepo
+	refund              RefundCommand
+	taxCalculator       TaxCalculator
 	producer            *kafka.ProducerServer
 	warehouseService    config.WarehouseService
-	shippingCostService config.ShippingCostService
+	productService      config.ProductService
+	shippingRate        ShippingRateProvider
+	shippingRules       ShippingRuleEngine
+	shipping            config.Shipping
 	constant            config.Constant
 }
 
@@ -31,25 +41,47 @@ func NewOrderCommandUseCase(
 	repoPostgresCommand OrderPostgreCommandRepo,
 	repoPostgresQuery OrderPostgreQueryRepo,
 	repoRedisCommand OrderRedisRepo,
+	repoPromotion PromotionPostgreCommandRepo,
+	refund RefundCommand,
+	taxCalculator TaxCalculator,
 	producer *kafka.ProducerServer,
 	warehouseService config.WarehouseService,
-	shippingCostService config.ShippingCostService,
+	productService config.ProductService,
+	shippingRate ShippingRateProvider,
+	shippingRules ShippingRuleEngine,
+	shipping config.Shipping,
 	constant config.Constant,
 ) *OrderCommandUseCase {
 	return &OrderCommandUseCase{
 		repoPostgresCommand,
 		repoPostgresQuery,
 		repoRedisCommand,
+		repoPromotion,
+		refund,
+		taxCalculator,
 		producer,
 		warehouseService,
-		shippingCostService,
+		productService,
+		shippingRate,
+		shippingRules,
+		shipping,
 		constant,
 	}
 }
 
 type stockMovementRequest struct {
-	Items   []orderItemRequest `json:"items"`
-	ZipCode string             `json:"zipcode"`
+	Items       []orderItemRequest `json:"items"`
+	ZipCode     string             `json:"zipcode"`
+	WarehouseID string             `json:"warehouse_id,omitempty"`
+}
+
+// pickupWarehouse is the warehouse chosen for a pickup order, empty for delivery order
+// so the warehouse service picks the nearest one from the zip code
+func pickupWarehouse(order *entity.Order) string {
+	if !order.IsPickup() {
+		return ""
+	}
+	return order.PickupWarehouseID.String()
 }
 
 type orderItemRequest struct {
@@ -61,6 +93,16 @@ type orderItemRequest struct {
 
 func createStockMovement(ctx context.Context, whBaseURL string, stockRequest stockMovementRequest, token string) error {
 	warehouseProductURL := fmt.Sprintf("%s/v1/stock-movements/moveout", whBaseURL)
+	return sendStockMovement(ctx, warehouseProductURL, stockRequest, token)
+}
+
+// createStockMoveIn put the items back to the warehouse
+func createStockMoveIn(ctx context.Context, whBaseURL string, stockRequest stockMovementRequest, token string) error {
+	warehouseProductURL := fmt.Sprintf("%s/v1/stock-movements/movein", whBaseURL)
+	return sendStockMovement(ctx, warehouseProductURL, stockRequest, token)
+}
+
+func sendStockMovement(ctx context.Context, warehouseProductURL string, stockRequest stockMovementRequest, token string) error {
 	requestBody, err := json.Marshal(stockRequest)
 	if err != nil {
 		return fmt.Errorf("failed to marshal stock request: %w", err)
@@ -150,60 +192,202 @@ func getNearestWarehouse(ctx context.Context, token, whBaseURL, zipCode string,
 	return &nearestWarehouseResponse.Data.ZipCode, nil
 }
 
-type shippingCostRequest struct {
-	FromZip string `json:"from_zip"`
-	ToZip   string `json:"to_zip"`
+type productResponse struct {
+	Code    int         `json:"code"`
+	Data    productData `json:"data"`
+	Message string      `json:"message"`
 }
 
-type shippingCostResponse struct {
-	Code int `json:"code"`
-	Data struct {
-		ShippingCost float64 `json:"shipping_cost"`
+type productData struct {
+	Name        string `json:"name"`
+	ImageURL    string `json:"image_url"`
+	Description string `json:"description"`
+	CategoryID  string `json:"category_id"`
+	Type        string `json:"type"`
+}
+
+// every checkout reads the products, a slow product service fails the order instead of holding it
+const productRequestTimeout = 5 * time.Second
+
+var productClient = &http.Client{Timeout: productRequestTimeout}
+
+func fetchProduct(ctx context.Context, productBaseURL string, productID uuid.UUID) (productData, error) {
+	productURL := fmt.Sprintf("%s/v1/products/%s", productBaseURL, productID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, productURL, nil)
+	if err != nil {
+		return productData{}, fmt.Errorf("failed to create product request: %w", err)
 	}
-	Message string `json:"message"`
+
+	resp, err := productClient.Do(req)
+	if err != nil {
+		return productData{}, fmt.Errorf("failed to make product request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return productData{}, fmt.Errorf("product service returned status: %d", resp.StatusCode)
+	}
+
+	var productResponse productResponse
+	if err := json.NewDecoder(resp.Body).Decode(&productResponse); err != nil {
+		return productData{}, fmt.Errorf("failed to decode product response: %w", err)
+	}
+
+	return productResponse.Data, nil
 }
 
-func getShippingCost(ctx context.Context, scBaseURL string, request shippingCostRequest) (float64, error) {
-	shippingCostURL := fmt.Sprintf("%s/shipping-cost", scBaseURL)
-	requestBody, err := json.Marshal(request)
+// getProduct get the category and type of the product, product without type is physical.
+// product without a valid category gets uuid.Nil, the default tax rule applies to it
+func getProduct(ctx context.Context, productBaseURL string, productID uuid.UUID) (uuid.UUID, string, error) {
+	product, err := fetchProduct(ctx, productBaseURL, productID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to marshal shipping cost request: %w", err)
+		return uuid.Nil, "", err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, shippingCostURL, bytes.NewBuffer(requestBody))
+	categoryID, err := uuid.Parse(product.CategoryID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create shipping cost request: %w", err)
+		categoryID = uuid.Nil
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	productType := product.Type
+	if productType == "" {
+		productType = entity.PRODUCT_PHYSICAL
+	}
+
+	return categoryID, productType, nil
+}
+
+// setItemProducts get the product category and type of the items that do not have it yet
+func (u *OrderCommandUseCase) setItemProducts(ctx context.Context, order *entity.Order) error {
+	for i := range order.Items {
+		item := &order.Items[i]
+		if item.CategoryID != uuid.Nil && item.ProductType != "" {
+			continue
+		}
+
+		categoryID, productType, err := getProduct(ctx, u.productService.BaseURL, item.ProductID)
+		if err != nil {
+			return fmt.Errorf("failed to get product: %w", err)
+		}
+		item.CategoryID = categoryID
+		item.ProductType = productType
+	}
+
+	return nil
+}
+
+// applyShippingRules waive, cap or override the shipping cost from the rate lookup,
+// it must run before the promotion because free shipping depends on the shipping cost
+func (u *OrderCommandUseCase) applyShippingRules(ctx context.Context, order *entity.Order) error {
+	err := u.setItemProducts(ctx, order)
 	if err != nil {
-		return 0, fmt.Errorf("failed to make shipping cost request: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("shipping cost service returned status: %d", resp.StatusCode)
+	err = u.shippingRules.ApplyShippingRules(ctx, order)
+	if err != nil {
+		return fmt.Errorf("failed to apply shipping rules: %w", err)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	return nil
+}
+
+// applyTax calculate the tax of every item from the address and product category,
+// it must run after the promotion because tax is charged on the discounted price
+func (u *OrderCommandUseCase) applyTax(ctx context.Context, order *entity.Order) error {
+	order.ClearTax()
+	err := u.setItemProducts(ctx, order)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read shipping cost response body: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
 
-	var shippingCostResponse shippingCostResponse
-	err = json.Unmarshal(body, &shippingCostResponse)
+	for i := range order.Items {
+		item := &order.Items[i]
+		rate, err := u.taxCalculator.TaxRate(ctx, order.Address, item.CategoryID)
+		if err != nil {
+			return fmt.Errorf("failed to get tax rate: %w", err)
+		}
+		order.AddTax(item.CalculateTax(rate))
+	}
+
+	return nil
+}
+
+// getItemShippingRates get the rate of every shipping method from the nearest warehouse that has the product
+func (u *OrderCommandUseCase) getItemShippingRates(ctx context.Context, token, zipCode string, productID uuid.UUID) ([]entity.ShippingRate, error) {
+	nearestZipCode, err := getNearestWarehouse(ctx, token, u.warehouseService.BaseURL, zipCode, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nearest warehouse zipcode: %w", err)
+	}
+
+	rates, err := u.shippingRate.GetShippingRates(ctx, zipCode, *nearestZipCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipping cost: %w", err)
+	}
+
+	return rates, nil
+}
+
+// getItemShippingCost get the shipping rate of the chosen method for the product
+func (u *OrderCommandUseCase) getItemShippingCost(ctx context.Context, token, zipCode, method string, productID uuid.UUID) (entity.ShippingRate, error) {
+	rates, err := u.getItemShippingRates(ctx, token, zipCode, productID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to unmarshal shipping cost response: %w", err)
+		return entity.ShippingRate{}, err
+	}
+
+	rate, ok := entity.FindShippingRate(rates, method)
+	if !ok {
+		return entity.ShippingRate{}, fmt.Errorf("shipping method %s is not available for product %s: %w", method, productID, ErrValidation)
+	}
+
+	return rate, nil
+}
+
+// isShippingMethodConfigured report whether the method is one of the shipping methods in the config
+func (u *OrderCommandUseCase) isShippingMethodConfigured(method string) bool {
+	for _, configured := range u.shipping.Methods {
+		if configured.Method == method {
+			return true
+		}
+	}
+	return false
+}
+
+// GetShippingQuotes sum the rate of every shipping method for all items of the order,
+// only methods available for every item are returned and the slowest item decides the estimated days
+func (u *OrderCommandUseCase) GetShippingQuotes(ctx context.Context, order *entity.Order, token string) ([]entity.ShippingRate, error) {
+	var quotes []entity.ShippingRate
+	for i, item := range order.Items {
+		rates, err := u.getItemShippingRates(ctx, token, order.Address.ZipCode, item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			quotes = rates
+			continue
+		}
+
+		var available []entity.ShippingRate
+		for _, quote := range quotes {
+			rate, ok := entity.FindShippingRate(rates, quote.Method)
+			if !ok {
+				continue
+			}
+			quote.Cost += rate.Cost
+			quote.EstimatedDays = max(quote.EstimatedDays, rate.EstimatedDays)
+			quote.Estimated = quote.Estimated || rate.Estimated
+			available = append(available, quote)
+		}
+		quotes = available
 	}
 
-	return shippingCostResponse.Data.ShippingCost, nil
+	return quotes, nil
 }
 
 func (u *OrderCommandUseCase) CreateOrder(ctx context.Context, order *entity.Order, token string) error {
 	order.SetStatusToPending()
+	order.Version = entity.ORDER_FIRST_VERSION
 	err := order.GenerateOrderID()
 	if err != nil {
 		return fmt.Errorf("failed to generate order id: %w", err)
@@ -214,6 +398,37 @@ func (u *OrderCommandUseCase) CreateOrder(ctx context.Context, order *entity.Ord
 		return fmt.Errorf("failed to generate order address id: %w", err)
 	}
 
+	// product type decides whether the items need stock and shipping
+	err = u.setItemProducts(ctx, order)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case order.IsAllItemsDigital():
+		// nothing to ship, address is optional
+		order.FulfilmentType = entity.FULFILMENT_DIGITAL
+		order.ShippingMethod = ""
+	case !order.IsPickup() && order.Address.ZipCode == "":
+		return fmt.Errorf("address is required to ship physical items: %w", ErrValidation)
+	}
+
+	// pickup order is collected from the chosen warehouse, no shipping
+	if order.IsPickup() {
+		if order.PickupWarehouseID == uuid.Nil {
+			return fmt.Errorf("pickup order needs a warehouse: %w", ErrValidation)
+		}
+		order.ShippingMethod = ""
+		err = order.GeneratePickupCode()
+		if err != nil {
+			return fmt.Errorf("failed to generate pickup code: %w", err)
+		}
+	}
+
+	if order.ShippingMethod != "" && !u.isShippingMethodConfigured(order.ShippingMethod) {
+		return fmt.Errorf("unknown shipping method %s: %w", order.ShippingMethod, ErrValidation)
+	}
+
 	// 1. create stock movement
 	var stockRequest stockMovementRequest
 	var items []orderItemRequest
@@ -224,45 +439,75 @@ func (u *OrderCommandUseCase) CreateOrder(ctx context.Context, order *entity.Ord
 			return fmt.Errorf("failed to generate order item id: %w", err)
 		}
 
-		// 2. get and set shipping cost
-		nearestZipCode, err := getNearestWarehouse(ctx, token, u.warehouseService.BaseURL, order.Address.ZipCode, order.Items[i].ProductID)
-		if err != nil {
-			return fmt.Errorf("failed to get nearest warehouse zipcode: %w", err)
-		}
-
-		shippingCostReq := shippingCostRequest{
-			FromZip: order.Address.ZipCode,
-			ToZip:   *nearestZipCode,
+		// digital item skip the nearest warehouse, shipping and stock movement
+		if order.Items[i].IsDigital() {
+			continue
 		}
 
-		shippingCost, err := getShippingCost(ctx, u.shippingCostService.URL, shippingCostReq)
-		if err != nil {
-			return fmt.Errorf("failed to get shipping cost: %w", err)
-		}
+		// 2. get and set shipping cost of the chosen method
+		if !order.IsPickup() {
+			rate, err := u.getItemShippingCost(ctx, token, order.Address.ZipCode, order.ShippingMethod, order.Items[i].ProductID)
+			if err != nil {
+				return err
+			}
 
-		order.Items[i].SetShippingCost(shippingCost)
+			order.Items[i].SetShippingCost(rate.Cost)
+			order.ApplyShippingEstimate(rate)
 
-		order.AddShippingCost(shippingCost)
+			order.AddShippingCost(rate.Cost)
+		}
 		items = append(items, orderItemRequest{
 			ProductID: order.Items[i].ProductID,
 			Quantity:  order.Items[i].ProductQuantity,
 		})
 	}
-	stockRequest.Items = items
-	stockRequest.ZipCode = order.Address.ZipCode
-	err = createStockMovement(ctx, u.warehouseService.BaseURL, stockRequest, token)
+
+	// 3. apply shipping rules to the shipping cost from the rate lookup
+	if !order.IsPickup() {
+		err = u.applyShippingRules(ctx, order)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 4. apply promotion code after shipping cost is known, free shipping depends on it
+	if order.PromotionCode != "" {
+		err = u.applyPromotion(ctx, order)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 5. calculate tax of the discounted items
+	err = u.applyTax(ctx, order)
 	if err != nil {
-		return fmt.Errorf("failed to create stock movement: %w", err)
+		return err
+	}
+
+	// digital order has no stock to move out
+	if len(items) > 0 {
+		stockRequest.Items = items
+		stockRequest.ZipCode = order.Address.ZipCode
+		stockRequest.WarehouseID = pickupWarehouse(order)
+		err = createStockMovement(ctx, u.warehouseService.BaseURL, stockRequest, token)
+		if err != nil {
+			return fmt.Errorf("failed to create stock movement: %w", err)
+		}
 	}
 
-	// 3. save order to database write
+	// 6. save order to database write, promotion usage is redeemed in the same transaction.
+	// the limit checked in applyPromotion can be taken by another order meanwhile, the stock goes back then
 	err = u.repoPostgresCommand.Insert(ctx, order)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("promotion %s usage limit is reached: %w", order.PromotionCode, ErrConflict)
+	} else if err != nil {
+		err = fmt.Errorf("failed to insert order record: %w", err)
+	}
 	if err != nil {
-		// TODO: handle error, send delete request to warehouse stock movement
-		return fmt.Errorf("failed to insert order record: %w", err)
+		return u.undoStockMoves(ctx, stockRequest, stockMovementRequest{}, token, err)
 	}
 
-	// 4. send event to kafka for database read
+	// 7. send event to kafka for database read
 	message := dto.OrderEntityToKafkaOrderCreatedMessage(order)
 	err = u.producer.Publish(
 		constant.OrderCreatedTopic,
@@ -274,7 +519,7 @@ func (u *OrderCommandUseCase) CreateOrder(ctx context.Context, order *entity.Ord
 		return fmt.Errorf("failed to produce kafka message: %w", err)
 	}
 
-	// 5. send scheduled task to upload the payment proof
+	// 8. send scheduled task to upload the payment proof
 	err = u.repoRedisCommand.Set(ctx, order.ID, "", time.Duration(u.constant.OrderTimeHours)*time.Hour)
 	if err != nil {
 		return fmt.Errorf("failed to set payment proof in redis: %w", err)
@@ -283,26 +528,161 @@ func (u *OrderCommandUseCase) CreateOrder(ctx context.Context, order *entity.Ord
 	return nil
 }
 
+// applyPromotion check the promotion code can be used by the user and allocate the discount to the order items
+func (u *OrderCommandUseCase) applyPromotion(ctx context.Context, order *entity.Order) error {
+	promotion, err := u.repoPromotion.GetByCode(ctx, order.PromotionCode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("promotion %s: %w", order.PromotionCode, ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get promotion: %w", err)
+	}
+
+	if !promotion.IsActive(time.Now()) {
+		return fmt.Errorf("promotion %s is not active: %w", promotion.Code, ErrValidation)
+	}
+	if !promotion.IsMinSpendReached(order) {
+		return fmt.Errorf("minimum spend of promotion %s is not reached: %w", promotion.Code, ErrValidation)
+	}
+	if promotion.IsUsageExceeded() {
+		return fmt.Errorf("promotion %s usage limit is reached: %w", promotion.Code, ErrConflict)
+	}
+
+	userUsage, err := u.repoPromotion.GetUserUsage(ctx, promotion.ID, order.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get promotion usage: %w", err)
+	}
+	if promotion.IsUserUsageExceeded(userUsage) {
+		return fmt.Errorf("promotion %s is already used: %w", promotion.Code, ErrConflict)
+	}
+
+	promotion.Apply(order)
+	return nil
+}
+
 func (u *OrderCommandUseCase) UpdateOrderPaymentID(ctx context.Context, order *entity.Order, paymentStatus string) error {
+	// payment event only has the order and payment id, the fulfilment decides the status after approval
+	stored, err := u.repoPostgresCommand.GetByID(ctx, order.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get order for payment: %w", err)
+	}
+	order.UserID = stored.UserID
+	order.FulfilmentType = stored.FulfilmentType
+	order.PickupWarehouseID = stored.PickupWarehouseID
+	order.PickupCode = stored.PickupCode
+	order.Items = stored.Items
+
 	switch paymentStatus {
 	case entity.ORDER_PAYMENT_APPROVED:
-		order.SetStatusToOnDelivery()
-		u.SendSalesReport(ctx, order.ID)
+		order.SetStatusToPaid()
 	case entity.ORDER_PAYMENT_REJECTED:
 		order.SetStatusToRejected()
 	}
 
-	err := u.repoRedisCommand.Delete(ctx, order.ID)
+	// the auto confirm grace period starts when the courier picks the package up, see UpdateOrderDelivery
+
+	// if payment rejected, call moveout in warehouse service, put back to warehouse
+
+	err = u.repoPostgresCommand.UpdatePaymentID(ctx, order)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("order %s is not pending anymore: %w", order.ID, ErrConflict)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update order payment: %w", err)
+	}
+
+	// the order does not wait for the payment anymore
+	err = u.repoRedisCommand.Delete(ctx, order.ID)
 	if err != nil {
 		return fmt.Errorf("failed to delete order in redis: %w", err)
 	}
 
-	// if payment rejected, call moveout in warehouse service, put back to warehouse
+	// sale is reported after the payment is saved, so the report has the paid amount and time
+	if paymentStatus == entity.ORDER_PAYMENT_APPROVED {
+		err = u.ReportSale(ctx, order.ID)
+		if err != nil {
+			return fmt.Errorf("failed to report sale: %w", err)
+		}
+	}
+
+	if order.Status == entity.ORDER_READY_FOR_PICKUP {
+		err = u.publishPickupEvent(constant.OrderReadyForPickupTopic, order)
+		if err != nil {
+			return err
+		}
+	}
+
+	// digital items are delivered by another service once the payment is approved
+	if paymentStatus == entity.ORDER_PAYMENT_APPROVED && len(order.DigitalItems()) > 0 {
+		err = u.producer.Publish(
+			constant.DigitalFulfilmentTopic,
+			[]byte(order.ID.String()),
+			dto.OrderEntityToKafkaDigitalFulfilmentMessage(order),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to produce kafka message: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ConfirmPickup mark the pickup order as collected after the warehouse checks the pickup code
+func (u *OrderCommandUseCase) ConfirmPickup(ctx context.Context, pickup *entity.Order) error {
+	order, err := u.repoPostgresCommand.GetByID(ctx, pickup.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get order for pickup: %w", err)
+	}
+	if order.ID == uuid.Nil {
+		return fmt.Errorf("order %s: %w", pickup.ID, ErrNotFound)
+	}
+	if !order.IsPickup() || order.Status != entity.ORDER_READY_FOR_PICKUP {
+		return fmt.Errorf("order is not ready for pickup: %w", ErrConflict)
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.ToUpper(pickup.PickupCode)), []byte(order.PickupCode)) != 1 {
+		return fmt.Errorf("invalid pickup code: %w", ErrValidation)
+	}
+
+	order.UpdatedAt = pickup.UpdatedAt
+	// a concurrent pickup, cancel or expiry may have moved the order since it was read
+	err = u.updateOrderStatus(ctx, order, entity.ORDER_PICKED_UP, []string{entity.ORDER_READY_FOR_PICKUP})
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("order is not ready for pickup anymore: %w", ErrConflict)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = u.publishPickupEvent(constant.OrderPickedUpTopic, order)
+	if err != nil {
+		return err
+	}
+
+	*pickup = *order
+	return nil
+}
+
+func (u *OrderCommandUseCase) publishPickupEvent(topic string, order *entity.Order) error {
+	message := dto.OrderEntityToKafkaOrderPickupMessage(order)
+	err := u.producer.Publish(
+		topic,
+		[]byte(order.ID.String()),
+		message,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to produce kafka message: %w", err)
+	}
 
-	return u.repoPostgresCommand.UpdatePaymentID(ctx, order)
+	return nil
 }
 
 func (u *OrderCommandUseCase) UpdateOrderStatus(ctx context.Context, order *entity.Order, orderStatus string) error {
+	return u.updateOrderStatus(ctx, order, orderStatus, nil)
+}
+
+// updateOrderStatus change the status only while the order is in one of the from statuses,
+// sql.ErrNoRows when it is not. nil from changes the status whatever it is
+func (u *OrderCommandUseCase) updateOrderStatus(ctx context.Context, order *entity.Order, orderStatus string, from []string) error {
 	switch orderStatus {
 	case entity.ORDER_DELIVERED:
 		order.SetStatusToDelivered()
@@ -310,18 +690,44 @@ func (u *OrderCommandUseCase) UpdateOrderStatus(ctx context.Context, order *enti
 		order.SetStatusToRejected()
 	case entity.ORDER_EXPIRED:
 		order.SetStatusToExpired()
+	case entity.ORDER_ON_DELIVERY:
+		order.SetStatusToOnDelivery()
+	case entity.ORDER_DELIVERY_FAILED:
+		order.SetStatusToDeliveryFailed()
+	case entity.ORDER_RETURNING:
+		order.SetStatusToReturning()
+	case entity.ORDER_CANCELLED:
+		order.SetStatusToCancelled()
+	case entity.ORDER_READY_FOR_PICKUP:
+		order.SetStatusToReadyForPickup()
+	case entity.ORDER_PICKED_UP:
+		order.SetStatusToPickedUp()
+	case entity.ORDER_FULFILLED:
+		order.SetStatusToFulfilled()
+	default:
+		return fmt.Errorf("invalid order status: %s", orderStatus)
 	}
 
-	err := u.repoPostgresCommand.UpdateStatus(ctx, order)
+	var err error
+	if from == nil {
+		err = u.repoPostgresCommand.UpdateStatus(ctx, order)
+	} else {
+		err = u.repoPostgresCommand.UpdateStatusFrom(ctx, order, from)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update order status: %w", err)
 	}
 
+	return u.publishOrderStatusUpdated(order)
+}
+
+func (u *OrderCommandUseCase) publishOrderStatusUpdated(order *entity.Order) error {
 	message := dto.OrderEntityToKafkaOrderStatusUpdatedMessage(order)
 
-	err = u.producer.Publish(
+	// keyed by the order so the status events of an order stay in order on one partition
+	err := u.producer.Publish(
 		constant.OrderStatusUpdatedTopic,
-		[]byte(uuid.New().String()),
+		[]byte(order.ID.String()),
 		message,
 	)
 	if err != nil {
@@ -331,29 +737,487 @@ func (u *OrderCommandUseCase) UpdateOrderStatus(ctx context.Context, order *enti
 	return nil
 }
 
-func (u *OrderCommandUseCase) SendSalesReport(ctx context.Context, id uuid.UUID) error {
+// UpdateOrderDelivery maps a courier delivery status onto an order status transition.
+// a failed delivery is retried until the attempts reach the limit, then the package is returned.
+// courier events for an order that is not out for delivery anymore, like a late pick up event
+// of a delivered or cancelled order, are ignored.
+func (u *OrderCommandUseCase) UpdateOrderDelivery(ctx context.Context, delivery *entity.Order, deliveryStatus string) error {
+	from := entity.DeliveryFromStatuses(deliveryStatus)
+	if from == nil {
+		return fmt.Errorf("unknown delivery status: %s", deliveryStatus)
+	}
+
+	order, err := u.repoPostgresCommand.GetByID(ctx, delivery.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get order for delivery: %w", err)
+	}
+	if order.ID == uuid.Nil {
+		return fmt.Errorf("order %s: %w", delivery.ID, ErrNotFound)
+	}
+	if !order.IsDeliveryApplicable(deliveryStatus) {
+		return nil
+	}
+	order.UpdatedAt = delivery.UpdatedAt
+
+	switch deliveryStatus {
+	case entity.DELIVERY_PICKED_UP, entity.DELIVERY_IN_TRANSIT:
+		if deliveryStatus == entity.DELIVERY_PICKED_UP {
+			order.SetShipped()
+		}
+		applied, err := u.updateDeliveryStatus(ctx, order, entity.ORDER_ON_DELIVERY, from)
+		if err != nil || !applied {
+			return err
+		}
+		err = u.scheduleDeliveryConfirmation(ctx, order.ID)
+		if err != nil {
+			return fmt.Errorf("failed to schedule delivery confirmation: %w", err)
+		}
+		return nil
+	case entity.DELIVERY_DELIVERED:
+		_, err = u.updateDeliveryStatus(ctx, order, entity.ORDER_DELIVERED, from)
+		return err
+	default:
+		err = u.repoPostgresCommand.FailDelivery(ctx, order, u.constant.MaxDeliveryAttempts, from)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update failed delivery: %w", err)
+		}
+
+		// grace period starts again on the next delivery attempt
+		err = u.repoRedisCommand.DeleteDeliverySchedule(ctx, order.ID)
+		if err != nil {
+			return fmt.Errorf("failed to delete delivery schedule: %w", err)
+		}
+
+		return u.publishOrderStatusUpdated(order)
+	}
+}
+
+// updateDeliveryStatus apply the courier event unless the order left the from statuses
+// since it was read, the event is then ignored like any other late event
+func (u *OrderCommandUseCase) updateDeliveryStatus(ctx context.Context, order *entity.Order, orderStatus string, from []string) (bool, error) {
+	err := u.updateOrderStatus(ctx, order, orderStatus, from)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CancelOrderItems cancel the whole or part of the quantity of some items before the order is shipped.
+// order without user id is cancelled by admin. shipping cost only depends on the warehouse route,
+// so it is removed only when the item is fully cancelled.
+func (u *OrderCommandUseCase) CancelOrderItems(ctx context.Context, cancel *entity.Order, token string) error {
+	order, err := u.repoPostgresCommand.GetByID(ctx, cancel.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get order for cancellation: %w", err)
+	}
+	if order.ID == uuid.Nil || (cancel.UserID != uuid.Nil && order.UserID != cancel.UserID) {
+		return fmt.Errorf("order %s: %w", cancel.ID, ErrNotFound)
+	}
+	if !order.IsAwaitingShipment() {
+		return fmt.Errorf("order is already shipped or closed: %w", ErrConflict)
+	}
+
+	itemIndex := make(map[uuid.UUID]int)
+	for i, item := range order.Items {
+		itemIndex[item.ProductID] = i
+	}
+
+	var stockRequest stockMovementRequest
+	var cancelledAmount float64
+	for _, cancelItem := range cancel.Items {
+		i, ok := itemIndex[cancelItem.ProductID]
+		if !ok {
+			return fmt.Errorf("product %s is not in the order: %w", cancelItem.ProductID, ErrValidation)
+		}
+
+		item := &order.Items[i]
+		if cancelItem.ProductQuantity <= 0 || cancelItem.ProductQuantity > item.ProductQuantity {
+			return fmt.Errorf("invalid cancel quantity for product %s: %w", item.ProductID, ErrValidation)
+		}
+
+		// promotion discount and tax follow the cancelled quantity, minimum spend is not checked again
+		discount, tax := item.ReduceQuantity(cancelItem.ProductQuantity)
+		amount := item.Price*float64(cancelItem.ProductQuantity) - discount + tax
+		if item.IsCancelled() {
+			amount += item.ShippingCost - item.ShippingDiscount
+			discount += item.ShippingDiscount
+			item.SetShippingCost(0)
+			item.ShippingDiscount = 0
+		}
+		order.ReduceTotalPrice(amount)
+		order.ReduceDiscount(discount)
+		order.ReduceTax(tax)
+		cancelledAmount += amount
+
+		if item.IsDigital() {
+			continue
+		}
+		stockRequest.Items = append(stockRequest.Items, orderItemRequest{
+			ProductID: item.ProductID,
+			Quantity:  cancelItem.ProductQuantity,
+		})
+	}
+
+	if order.IsAllItemsCancelled() {
+		order.SetStatusToCancelled()
+	}
+	order.UpdatedAt = cancel.UpdatedAt
+
+	// 1. paid order get the money of cancelled items back, the refund is saved with the cancellation
+	var refund *entity.Refund
+	if order.PaymentID != uuid.Nil {
+		refund = &entity.Refund{
+			OrderID:   order.ID,
+			Amount:    cancelledAmount,
+			Reason:    "order items cancelled",
+			CreatedAt: order.UpdatedAt,
+			UpdatedAt: order.UpdatedAt,
+		}
+		err = u.refund.PrepareRefund(ctx, refund)
+		if err != nil {
+			return fmt.Errorf("failed to prepare refund for cancelled items: %w", err)
+		}
+	}
+
+	// 2. put back the cancelled stock to warehouse
+	if len(stockRequest.Items) > 0 {
+		stockRequest.ZipCode = order.Address.ZipCode
+		stockRequest.WarehouseID = pickupWarehouse(order)
+		err = createStockMoveIn(ctx, u.warehouseService.BaseURL, stockRequest, token)
+		if err != nil {
+			return fmt.Errorf("failed to create stock move in: %w", err)
+		}
+	}
+
+	// 3. save the remaining items, total price and refund together, the stock is taken out again when it fails
+	err = u.repoPostgresCommand.UpdateItems(ctx, order, refund)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = fmt.Errorf("order changed while cancelling its items: %w", ErrConflict)
+		case errors.Is(err, entity.ErrRefundExceedsRemaining):
+			err = fmt.Errorf("another refund of the order was issued first: %w", ErrConflict)
+		default:
+			err = fmt.Errorf("failed to update order items: %w", err)
+		}
+		return u.undoStockMoves(ctx, stockMovementRequest{}, stockRequest, token, err)
+	}
+
+	// 4. cancelled order does not wait for payment anymore
+	if order.Status == entity.ORDER_CANCELLED {
+		err = u.repoRedisCommand.Delete(ctx, order.ID)
+		if err != nil {
+			return fmt.Errorf("failed to delete order in redis: %w", err)
+		}
+	}
+
+	// 5. payment service pays the saved refund back
+	if refund != nil {
+		err = u.refund.PublishRefund(refund, order.PaymentID)
+		if err != nil {
+			return fmt.Errorf("failed to publish refund for cancelled items: %w", err)
+		}
+	}
+
+	// 6. send event to kafka for database read
+	message := dto.OrderEntityToKafkaOrderItemsCancelledMessage(order, cancel.Items)
+	err = u.producer.Publish(
+		constant.OrderItemsCancelledTopic,
+		[]byte(order.ID.String()),
+		message,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+
+	*cancel = *order
+	return nil
+}
+
+// EditOrder change the address and the item quantity of an order that is not paid yet.
+// shipping cost is calculated again and the stock difference is moved out of or into the warehouse.
+func (u *OrderCommandUseCase) EditOrder(ctx context.Context, edit *entity.Order, token string) error {
+	order, err := u.repoPostgresCommand.GetByID(ctx, edit.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get order for edit: %w", err)
+	}
+	if order.ID == uuid.Nil || (edit.UserID != uuid.Nil && order.UserID != edit.UserID) {
+		return fmt.Errorf("order %s: %w", edit.ID, ErrNotFound)
+	}
+	if order.Status != entity.ORDER_PENDING {
+		return fmt.Errorf("only pending order can be edited: %w", ErrConflict)
+	}
+
+	// address is optional, zip code is always filled when the address is changed
+	if edit.Address.ZipCode != "" {
+		edit.Address.ID = order.Address.ID
+		edit.Address.OrderID = order.ID
+		order.Address = edit.Address
+	}
+
+	itemIndex := make(map[uuid.UUID]int)
+	for i, item := range order.Items {
+		itemIndex[item.ProductID] = i
+	}
+
+	var moveOutRequest, moveInRequest stockMovementRequest
+	for _, editItem := range edit.Items {
+		i, ok := itemIndex[editItem.ProductID]
+		if !ok {
+			return fmt.Errorf("product %s is not in the order: %w", editItem.ProductID, ErrValidation)
+		}
+		if editItem.ProductQuantity <= 0 {
+			return fmt.Errorf("invalid quantity for product %s: %w", editItem.ProductID, ErrValidation)
+		}
+
+		item := &order.Items[i]
+		diff := editItem.ProductQuantity - item.ProductQuantity
+		switch {
+		case item.IsDigital():
+			// digital item has no stock
+		case diff > 0:
+			moveOutRequest.Items = append(moveOutRequest.Items, orderItemRequest{ProductID: item.ProductID, Quantity: diff})
+		case diff < 0:
+			moveInRequest.Items = append(moveInRequest.Items, orderItemRequest{ProductID: item.ProductID, Quantity: -diff})
+		}
+		item.ProductQuantity = editItem.ProductQuantity
+	}
+
+	// 1. get shipping cost again and recompute the total price
+	order.ClearDiscount()
+	order.ClearTax()
+	order.TotalPrice = 0
+	order.ShippingEstimatedDays = 0
+	order.EstimatedShipping = false
+	for i := range order.Items {
+		if !order.IsPickup() && !order.Items[i].IsDigital() {
+			rate, err := u.getItemShippingCost(ctx, token, order.Address.ZipCode, order.ShippingMethod, order.Items[i].ProductID)
+			if err != nil {
+				return err
+			}
+
+			order.Items[i].SetShippingCost(rate.Cost)
+			order.ApplyShippingEstimate(rate)
+			order.AddShippingCost(rate.Cost)
+		}
+		order.AddTotalPrice(order.Items[i].Price * float64(order.Items[i].ProductQuantity))
+	}
+	order.UpdatedAt = edit.UpdatedAt
+
+	// subtotal and address may change the applied shipping rule
+	if !order.IsPickup() {
+		err = u.applyShippingRules(ctx, order)
+		if err != nil {
+			return err
+		}
+	}
+
+	// already redeemed promotion is applied again to the new quantity, usage limit is not counted twice
+	if order.HasPromotion() {
+		promotion, err := u.repoPromotion.GetByID(ctx, order.PromotionID)
+		if err != nil {
+			return fmt.Errorf("failed to get order promotion: %w", err)
+		}
+		if !promotion.IsMinSpendReached(order) {
+			return fmt.Errorf("minimum spend of promotion %s is not reached: %w", promotion.Code, ErrValidation)
+		}
+		promotion.Apply(order)
+	}
+
+	err = u.applyTax(ctx, order)
+	if err != nil {
+		return err
+	}
+
+	// 2. adjust stock movement with the quantity difference
+	moveOutRequest.ZipCode = order.Address.ZipCode
+	moveOutRequest.WarehouseID = pickupWarehouse(order)
+	moveInRequest.ZipCode = order.Address.ZipCode
+	moveInRequest.WarehouseID = pickupWarehouse(order)
+	if len(moveOutRequest.Items) > 0 {
+		err = createStockMovement(ctx, u.warehouseService.BaseURL, moveOutRequest, token)
+		if err != nil {
+			return fmt.Errorf("failed to create stock movement: %w", err)
+		}
+	}
+	if len(moveInRequest.Items) > 0 {
+		err = createStockMoveIn(ctx, u.warehouseService.BaseURL, moveInRequest, token)
+		if err != nil {
+			return u.undoStockMoves(ctx, moveOutRequest, stockMovementRequest{}, token,
+				fmt.Errorf("failed to create stock move in: %w", err))
+		}
+	}
+
+	// 3. save order to database write, unless it was paid or changed since it was read
+	err = u.repoPostgresCommand.Update(ctx, order)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("order was changed while it was edited: %w", ErrConflict)
+	} else if err != nil {
+		err = fmt.Errorf("failed to update order record: %w", err)
+	}
+	if err != nil {
+		return u.undoStockMoves(ctx, moveOutRequest, moveInRequest, token, err)
+	}
+
+	// 4. send event to kafka for database read
+	message := dto.OrderEntityToKafkaOrderUpdatedMessage(order)
+	err = u.producer.Publish(
+		constant.OrderUpdatedTopic,
+		[]byte(order.ID.String()),
+		message,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+
+	*edit = *order
+	return nil
+}
+
+// undoStockMoves put the stock moved out back in and take the stock moved in out again
+// after the change they were made for failed, cause is returned
+func (u *OrderCommandUseCase) undoStockMoves(ctx context.Context, movedOut, movedIn stockMovementRequest, token string, cause error) error {
+	if len(movedOut.Items) > 0 {
+		if err := createStockMoveIn(ctx, u.warehouseService.BaseURL, movedOut, token); err != nil {
+			return fmt.Errorf("%w, failed to undo stock movement: %v", cause, err)
+		}
+	}
+	if len(movedIn.Items) > 0 {
+		if err := createStockMovement(ctx, u.warehouseService.BaseURL, movedIn, token); err != nil {
+			return fmt.Errorf("%w, failed to undo stock move in: %v", cause, err)
+		}
+	}
+
+	return cause
+}
+
+func (u *OrderCommandUseCase) scheduleDeliveryConfirmation(ctx context.Context, id uuid.UUID) error {
+	confirmTTL := time.Duration(u.constant.AutoConfirmDeliveryDays) * 24 * time.Hour
+	reminderTTL := time.Duration(u.constant.AutoConfirmDeliveryDays-u.constant.DeliveryReminderDays) * 24 * time.Hour
+	return u.repoRedisCommand.SetDeliverySchedule(ctx, id, confirmTTL, reminderTTL)
+}
+
+// AutoConfirmDelivery set the order to DELIVERED when customer does not confirm it within the grace period
+func (u *OrderCommandUseCase) AutoConfirmDelivery(ctx context.Context, id uuid.UUID) error {
 	order, err := u.repoPostgresCommand.GetByID(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to get order for sales report: %w", err)
+		return fmt.Errorf("failed to get order for auto confirm delivery: %w", err)
+	}
+	if order.ID == uuid.Nil {
+		return fmt.Errorf("order %s not found", id)
 	}
 
-	products, err := u.repoPostgresQuery.GetProductPriceByOrderID(ctx, id)
+	// already confirmed by customer, or the delivery is not in progress anymore
+	if order.Status != entity.ORDER_ON_DELIVERY {
+		return nil
+	}
+
+	order.UpdatedBy = entity.ACTOR_SYSTEM_AUTO_CONFIRM
+	order.UpdatedAt = time.Now()
+
+	// every instance receives the expired key, only the first one moves the order and publishes
+	err = u.updateOrderStatus(ctx, order, entity.ORDER_DELIVERED, []string{entity.ORDER_ON_DELIVERY})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+// SendDeliveryReminder notify the customer that the order will be confirmed automatically soon
+func (u *OrderCommandUseCase) SendDeliveryReminder(ctx context.Context, id uuid.UUID) error {
+	order, err := u.repoPostgresCommand.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get order for delivery reminder: %w", err)
+	}
+	if order.ID == uuid.Nil {
+		return fmt.Errorf("order %s not found", id)
+	}
+
+	if order.Status != entity.ORDER_ON_DELIVERY {
+		return nil
+	}
+
+	// every instance receives the expired key, the marker outlives the grace period so the customer is notified once
+	claimed, err := u.repoRedisCommand.ClaimDeliveryReminder(ctx, order.ID, time.Duration(u.constant.AutoConfirmDeliveryDays)*24*time.Hour)
 	if err != nil {
-		return fmt.Errorf("failed to get product price: %w", err)
+		return fmt.Errorf("failed to claim delivery reminder: %w", err)
+	}
+	if !claimed {
+		return nil
 	}
 
-	message := dto.OrderEntityToKafkaSaleCreatedMessage(order, products)
+	autoConfirmAt := time.Now().Add(time.Duration(u.constant.DeliveryReminderDays) * 24 * time.Hour)
+	message := dto.OrderEntityToKafkaDeliveryReminderMessage(order, autoConfirmAt)
 
 	err = u.producer.Publish(
+		constant.DeliveryReminderTopic,
+		[]byte(order.ID.String()),
+		message,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+
+	return nil
+}
+
+// SendSalesReport publish the financial breakdown of a paid order from the database write.
+// the order id is the message key and a reported order is skipped, so retrying is safe.
+func (u *OrderCommandUseCase) SendSalesReport(ctx context.Context, id uuid.UUID) error {
+	order, err := u.repoPostgresCommand.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get order for sales report: %w", err)
+	}
+	if order.ID == uuid.Nil {
+		return fmt.Errorf("order %s: %w", id, ErrNotFound)
+	}
+	if order.IsSaleReported() {
+		return nil
+	}
+
+	message := dto.OrderEntityToKafkaSaleCreatedMessage(order, u.constant.Currency)
+
+	// not acknowledged in time leaves the sale unreported, the scheduled retry publishes it again
+	err = u.producer.PublishSync(
+		ctx,
 		constant.SaleCreated,
-		[]byte(uuid.New().String()),
+		[]byte(order.ID.String()),
 		message,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to produce kafka message: %w", err)
 	}
 
-	return err
+	order.SaleReportedAt = time.Now()
+	err = u.repoPostgresCommand.UpdateSaleReported(ctx, order)
+	if err != nil {
+		return fmt.Errorf("failed to update sale reported: %w", err)
+	}
+
+	return nil
+}
+
+// ReportSale send the sales report and schedule another attempt when it fails,
+// error is returned only when the sale report can not be scheduled for retry
+func (u *OrderCommandUseCase) ReportSale(ctx context.Context, id uuid.UUID) error {
+	reportErr := u.SendSalesReport(ctx, id)
+	if reportErr == nil {
+		return nil
+	}
+
+	retryDelay := time.Duration(u.constant.SaleReportRetryMinutes) * time.Minute
+	err := u.repoRedisCommand.SetSaleReportRetry(ctx, id, retryDelay)
+	if err != nil {
+		return fmt.Errorf("failed to schedule sale report retry after %v: %w", reportErr, err)
+	}
+
+	return nil
 }
 
 func (u *OrderCommandUseCase) GetOrderTTL(ctx context.Context, id uuid.UUID) (int, error) {
@@ -364,3 +1228,16 @@ func (u *OrderCommandUseCase) GetOrderTTL(ctx context.Context, id uuid.UUID) (in
 
 	return int(ttlNanoSecs.Seconds()), nil
 }
+
+// GetOrderByID read the order from the command database, used while the read model is behind
+func (u *OrderCommandUseCase) GetOrderByID(ctx context.Context, id uuid.UUID) (*entity.Order, error) {
+	order, err := u.repoPostgresCommand.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	if order.ID == uuid.Nil {
+		return nil, fmt.Errorf("order %s: %w", id, ErrNotFound)
+	}
+
+	return order, nil
+}
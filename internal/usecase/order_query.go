@@ -2,19 +2,54 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/config"
 	"github.com/idoyudha/eshop-order/internal/entity"
 )
 
+var (
+	orderViewCacheHits   = expvar.NewInt("order_view_cache_hits")
+	orderViewCacheMisses = expvar.NewInt("order_view_cache_misses")
+	orderViewCacheErrors = expvar.NewInt("order_view_cache_errors")
+
+	orderViewEventsParked   = expvar.NewInt("order_view_events_parked")
+	orderViewEventsReplayed = expvar.NewInt("order_view_events_replayed")
+	orderViewEventsStale    = expvar.NewInt("order_view_events_stale")
+)
+
+// how often the order view is read again while waiting for the projection
+const projectionPollInterval = 50 * time.Millisecond
+
+// orders whose parked events are replayed by one sweep
+const parkedReplayBatchSize = 100
+
+// a read that started before the projection write may cache the old view after the first delete,
+// the key is deleted again once such a read is done
+const orderViewRedeleteDelay = time.Second
+
 type OrderQueryUseCase struct {
 	repoPostgresQuery OrderPostgreQueryRepo
+	repoRedisQuery    OrderRedisQueryRepo
+	constant          config.Constant
 }
 
-func NewOrderQueryUseCase(repoPostgresQuery OrderPostgreQueryRepo) *OrderQueryUseCase {
+func NewOrderQueryUseCase(
+	repoPostgresQuery OrderPostgreQueryRepo,
+	repoRedisQuery OrderRedisQueryRepo,
+	constant config.Constant,
+) *OrderQueryUseCase {
 	return &OrderQueryUseCase{
 		repoPostgresQuery,
+		repoRedisQuery,
+		constant,
 	}
 }
 
@@ -38,39 +73,269 @@ func (u *OrderQueryUseCase) CreateOrderView(ctx context.Context, order *entity.O
 		return fmt.Errorf("failed to generate order view address id: %w", err)
 	}
 
-	return u.repoPostgresQuery.Insert(ctx, order)
+	err = u.repoPostgresQuery.Insert(ctx, order)
+	if err != nil {
+		return err
+	}
+
+	// nothing else replays the parked events of the order right away, a failed cache delete must not skip them
+	cacheErr := u.InvalidateOrderView(ctx, order.OrderID)
+
+	err = u.replayParkedOrderEvents(ctx, order.OrderID)
+	if err != nil {
+		return err
+	}
+
+	return cacheErr
 }
 
 func (u *OrderQueryUseCase) UpdateOrderViewPayment(ctx context.Context, order *entity.OrderView, paymentStatus string) error {
+	err := u.updateOrderViewPayment(ctx, order, paymentStatus)
+	return u.finishOrderViewEvent(ctx, entity.PARKED_ORDER_PAYMENT, order, err)
+}
+
+func (u *OrderQueryUseCase) updateOrderViewPayment(ctx context.Context, order *entity.OrderView, paymentStatus string) error {
 	switch paymentStatus {
 	case entity.ORDER_PAYMENT_APPROVED:
-		order.SetStatusToOnDelivery()
+		// payment event does not know the fulfilment of the order
+		existing, err := u.repoPostgresQuery.GetByID(ctx, order.OrderID)
+		if err != nil {
+			return fmt.Errorf("failed to get order view for payment: %w", err)
+		}
+		order.FulfilmentType = existing.FulfilmentType
+		order.SetStatusToPaid()
 	case entity.ORDER_PAYMENT_REJECTED:
 		order.SetStatusToRejected()
 	}
 	return u.repoPostgresQuery.UpdatePayment(ctx, order)
 }
 
+// GetOrderByID read through the cache, redis errors fall back to the database
 func (u *OrderQueryUseCase) GetOrderByID(ctx context.Context, id uuid.UUID) (*entity.OrderView, error) {
-	return u.repoPostgresQuery.GetByID(ctx, id)
+	cached, err := u.repoRedisQuery.Get(ctx, id)
+	if err != nil {
+		orderViewCacheErrors.Add(1)
+	}
+	if cached != nil {
+		orderViewCacheHits.Add(1)
+		return cached, nil
+	}
+	orderViewCacheMisses.Add(1)
+
+	order, err := u.repoPostgresQuery.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := time.Duration(u.constant.OrderCacheMinutes) * time.Minute
+	if err := u.repoRedisQuery.Set(ctx, order, ttl); err != nil {
+		orderViewCacheErrors.Add(1)
+	}
+
+	return order, nil
 }
 
-func (u *OrderQueryUseCase) GetAllOrders(ctx context.Context) ([]*entity.OrderView, error) {
-	return u.repoPostgresQuery.GetAll(ctx)
+// GetOrderByIDAtVersion wait until the order view reaches the version of a projection token,
+// ErrProjectionBehind is returned when the projection has not caught up within the wait
+func (u *OrderQueryUseCase) GetOrderByIDAtVersion(ctx context.Context, id uuid.UUID, version int) (*entity.OrderView, error) {
+	deadline := time.Now().Add(time.Duration(u.constant.ProjectionWaitMillis) * time.Millisecond)
+
+	order, err := u.GetOrderByID(ctx, id)
+	for {
+		switch {
+		case err == nil && order.Version >= version:
+			return order, nil
+		case err != nil && !errors.Is(err, sql.ErrNoRows):
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("order view %s is below version %d: %w", id, version, ErrProjectionBehind)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(projectionPollInterval):
+		}
+		// the cached view may be older than the projection, read the database directly
+		order, err = u.repoPostgresQuery.GetByID(ctx, id)
+	}
 }
 
-func (u *OrderQueryUseCase) GetOrderByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.OrderView, error) {
-	return u.repoPostgresQuery.GetByUserID(ctx, userID)
+// ListOrders return one page of the orders matching the filter, the cursor must come from the same sort
+func (u *OrderQueryUseCase) ListOrders(ctx context.Context, filter entity.OrderFilter, page entity.Page) ([]*entity.OrderView, entity.PageCursor, error) {
+	if !filter.IsTotalPriceRangeValid() {
+		return nil, entity.PageCursor{}, fmt.Errorf("min total price is above max total price: %w", ErrValidation)
+	}
+	if !page.After.IsZero() && page.After.Sort != filter.SortField() {
+		return nil, entity.PageCursor{}, fmt.Errorf("page cursor is for sort %s: %w", page.After.Sort, ErrValidation)
+	}
+	return u.repoPostgresQuery.List(ctx, filter, page)
+}
+
+// SearchOrders return one page of the orders matching the full-text query, best match first
+func (u *OrderQueryUseCase) SearchOrders(ctx context.Context, text string, page entity.Page) ([]*entity.OrderView, entity.PageCursor, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, entity.PageCursor{}, fmt.Errorf("search query is empty: %w", ErrValidation)
+	}
+	if !page.After.IsZero() && page.After.Sort != entity.ORDER_SORT_RANK {
+		return nil, entity.PageCursor{}, fmt.Errorf("page cursor is for sort %s: %w", page.After.Sort, ErrValidation)
+	}
+	return u.repoPostgresQuery.Search(ctx, text, page)
+}
+
+func (u *OrderQueryUseCase) GetOrderByUserID(ctx context.Context, userID uuid.UUID, page entity.Page) ([]*entity.OrderView, entity.PageCursor, error) {
+	return u.repoPostgresQuery.GetByUserID(ctx, userID, page)
 }
 
 func (u *OrderQueryUseCase) GetOrderByPaymentID(ctx context.Context, paymentID uuid.UUID) (*entity.OrderView, error) {
 	return u.repoPostgresQuery.GetByPaymentID(ctx, paymentID)
 }
 
-func (u *OrderQueryUseCase) GetOrderByStatus(ctx context.Context, status string) ([]*entity.OrderView, error) {
-	return u.repoPostgresQuery.GetByStatus(ctx, status)
+func (u *OrderQueryUseCase) UpdateOrderViewStatus(ctx context.Context, order *entity.OrderView) error {
+	err := u.repoPostgresQuery.UpdateStatus(ctx, order)
+	return u.finishOrderViewEvent(ctx, entity.PARKED_ORDER_STATUS, order, err)
 }
 
-func (u *OrderQueryUseCase) UpdateOrderViewStatus(ctx context.Context, order *entity.OrderView) error {
-	return u.repoPostgresQuery.UpdateStatus(ctx, order)
+func (u *OrderQueryUseCase) UpdateOrderViewItems(ctx context.Context, order *entity.OrderView) error {
+	err := u.repoPostgresQuery.UpdateItems(ctx, order)
+	return u.finishOrderViewEvent(ctx, entity.PARKED_ORDER_ITEMS, order, err)
+}
+
+func (u *OrderQueryUseCase) UpdateOrderView(ctx context.Context, order *entity.OrderView) error {
+	err := u.repoPostgresQuery.Update(ctx, order)
+	return u.finishOrderViewEvent(ctx, entity.PARKED_ORDER_UPDATE, order, err)
+}
+
+// finishOrderViewEvent handle the result of an order view update event, the update is parked
+// when the order view is not created yet and dropped when the view already has its version
+func (u *OrderQueryUseCase) finishOrderViewEvent(ctx context.Context, kind string, order *entity.OrderView, err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return u.parkOrderViewEvent(ctx, kind, order)
+	case errors.Is(err, entity.ErrStaleOrderEvent):
+		orderViewEventsStale.Add(1)
+		return nil
+	case err != nil:
+		return err
+	}
+
+	return u.InvalidateOrderView(ctx, order.OrderID)
+}
+
+func (u *OrderQueryUseCase) parkOrderViewEvent(ctx context.Context, kind string, order *entity.OrderView) error {
+	payload, err := json.Marshal(order)
+	if err != nil {
+		return fmt.Errorf("failed to encode parked order event: %w", err)
+	}
+
+	event := entity.ParkedOrderEvent{
+		OrderID:   order.OrderID,
+		Kind:      kind,
+		Version:   order.Version,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+	err = event.GenerateParkedOrderEventID()
+	if err != nil {
+		return fmt.Errorf("failed to generate parked order event id: %w", err)
+	}
+
+	err = u.repoPostgresQuery.InsertParkedEvent(ctx, &event)
+	if errors.Is(err, entity.ErrOrderViewCreated) {
+		return u.applyOrderViewEvent(ctx, kind, order)
+	}
+	if err != nil {
+		return err
+	}
+
+	orderViewEventsParked.Add(1)
+	return nil
+}
+
+// replayParkedOrderEvents apply the updates that arrived before the order view was created,
+// an event is deleted only after it is applied so a failed replay is tried again by the sweep
+func (u *OrderQueryUseCase) replayParkedOrderEvents(ctx context.Context, orderID uuid.UUID) error {
+	events, err := u.repoPostgresQuery.GetParkedEvents(ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		var order entity.OrderView
+		if err := json.Unmarshal(event.Payload, &order); err != nil {
+			return fmt.Errorf("failed to decode parked order event %s: %w", event.ID, err)
+		}
+
+		err = u.applyOrderViewEvent(ctx, event.Kind, &order)
+		if err != nil {
+			return fmt.Errorf("failed to replay parked order event %s: %w", event.ID, err)
+		}
+
+		err = u.repoPostgresQuery.DeleteParkedEvent(ctx, event.ID)
+		if err != nil {
+			return err
+		}
+		orderViewEventsReplayed.Add(1)
+	}
+
+	return nil
+}
+
+func (u *OrderQueryUseCase) applyOrderViewEvent(ctx context.Context, kind string, order *entity.OrderView) error {
+	switch kind {
+	case entity.PARKED_ORDER_STATUS:
+		return u.UpdateOrderViewStatus(ctx, order)
+	case entity.PARKED_ORDER_PAYMENT:
+		return u.UpdateOrderViewPayment(ctx, order, order.PaymentStatus)
+	case entity.PARKED_ORDER_ITEMS:
+		return u.UpdateOrderViewItems(ctx, order)
+	case entity.PARKED_ORDER_UPDATE:
+		return u.UpdateOrderView(ctx, order)
+	default:
+		return fmt.Errorf("unknown parked order event kind %s", kind)
+	}
+}
+
+// ReplayParkedOrderEvents replay the parked events left behind by a failed replay once their order view exists,
+// it returns the number of orders replayed and the last replay error
+func (u *OrderQueryUseCase) ReplayParkedOrderEvents(ctx context.Context) (int, error) {
+	orderIDs, err := u.repoPostgresQuery.GetReplayableParkedOrderIDs(ctx, parkedReplayBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var replayed int
+	var lastErr error
+	for _, orderID := range orderIDs {
+		if err := u.replayParkedOrderEvents(ctx, orderID); err != nil {
+			lastErr = err
+			continue
+		}
+		replayed++
+	}
+
+	return replayed, lastErr
+}
+
+// InvalidateOrderView delete the cached order view after the projection changes it
+func (u *OrderQueryUseCase) InvalidateOrderView(ctx context.Context, orderID uuid.UUID) error {
+	err := deleteCachedOrderView(ctx, u.repoRedisQuery, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to delete cached order view: %w", err)
+	}
+	return nil
+}
+
+// deleteCachedOrderView delete the cached order view now and again after orderViewRedeleteDelay.
+// a returned or refunded item changes the view without a new version, so the cache write
+// cannot tell the old view from the new one by the version alone
+func deleteCachedOrderView(ctx context.Context, repo OrderRedisQueryRepo, orderID uuid.UUID) error {
+	err := repo.Delete(ctx, orderID)
+	time.AfterFunc(orderViewRedeleteDelay, func() {
+		if err := repo.Delete(context.Background(), orderID); err != nil {
+			orderViewCacheErrors.Add(1)
+		}
+	})
+	return err
 }
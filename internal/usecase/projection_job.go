@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
+)
+
+// jobLocker take the named lock shared by every instance, OrderRebuildQueryRepo implements it
+type jobLocker interface {
+	TryLock(context.Context, string) (func(), error)
+}
+
+// projectionJob track the last run of a projection job in the process, like the rebuild or the reconcile.
+// one run goes at a time across every instance, the job lock is held from begin to finish
+type projectionJob[T any] struct {
+	name   string // used in the errors, like "projection rebuild"
+	lock   string
+	locker jobLocker
+
+	mu      sync.Mutex
+	id      uuid.UUID
+	last    *T
+	running bool
+	unlock  func()
+}
+
+func newProjectionJob[T any](name, lock string, locker jobLocker) *projectionJob[T] {
+	return &projectionJob[T]{
+		name:   name,
+		lock:   lock,
+		locker: locker,
+	}
+}
+
+// begin record the new run, ErrConflict when a run is going on this or another instance
+func (j *projectionJob[T]) begin(ctx context.Context, id uuid.UUID, run T) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.running {
+		return fmt.Errorf("%s %s is running: %w", j.name, j.id, ErrConflict)
+	}
+
+	unlock, err := j.locker.TryLock(ctx, j.lock)
+	if errors.Is(err, entity.ErrProjectionJobLocked) {
+		return fmt.Errorf("%s is running on another instance: %w", j.name, ErrConflict)
+	}
+	if err != nil {
+		return err
+	}
+
+	j.id = id
+	j.last = &run
+	j.running = true
+	j.unlock = unlock
+
+	return nil
+}
+
+// report the progress of the running run
+func (j *projectionJob[T]) report(run T) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.last = &run
+}
+
+// finish record the end of the run and release the job lock
+func (j *projectionJob[T]) finish(run T) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.last = &run
+	j.running = false
+	if j.unlock != nil {
+		j.unlock()
+		j.unlock = nil
+	}
+}
+
+// get the last run, ErrNotFound when none started since the process started
+func (j *projectionJob[T]) get() (T, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.last == nil {
+		var zero T
+		return zero, fmt.Errorf("%s: %w", j.name, ErrNotFound)
+	}
+	return *j.last, nil
+}
+
+// background run the job on its own context, the run outlives the request that started it
+func (j *projectionJob[T]) background(run func(context.Context) error, done func(error)) {
+	go func() {
+		done(run(context.Background()))
+	}()
+}
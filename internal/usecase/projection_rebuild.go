@@ -0,0 +1,308 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/config"
+	"github.com/idoyudha/eshop-order/internal/entity"
+	"github.com/idoyudha/eshop-order/pkg/logger"
+)
+
+type ProjectionRebuildUseCase struct {
+	repoPostgresCommand OrderPostgreCommandRepo
+	repoRebuildQuery    OrderRebuildQueryRepo
+	repoRedisQuery      OrderRedisQueryRepo
+	productService      config.ProductService
+	l                   logger.Interface
+	job                 *projectionJob[entity.ProjectionRebuild]
+}
+
+func NewProjectionRebuildUseCase(
+	repoPostgresCommand OrderPostgreCommandRepo,
+	repoRebuildQuery OrderRebuildQueryRepo,
+	repoRedisQuery OrderRedisQueryRepo,
+	productService config.ProductService,
+	l logger.Interface,
+) *ProjectionRebuildUseCase {
+	return &ProjectionRebuildUseCase{
+		repoPostgresCommand,
+		repoRebuildQuery,
+		repoRedisQuery,
+		productService,
+		l,
+		// the shadow tables are dropped and swapped, a rebuild of another replica or of the cli must not overlap
+		newProjectionJob[entity.ProjectionRebuild]("projection rebuild", entity.REBUILD_LOCK, repoRebuildQuery),
+	}
+}
+
+// RebuildOrderViews repopulate the order views from the command tables and wait until it is done
+func (u *ProjectionRebuildUseCase) RebuildOrderViews(ctx context.Context, shadow bool, batchSize int) (entity.ProjectionRebuild, error) {
+	rebuild, err := u.begin(ctx, shadow, batchSize)
+	if err != nil {
+		return entity.ProjectionRebuild{}, err
+	}
+
+	err = u.rebuildOrderViews(ctx, &rebuild)
+	return u.finish(rebuild, err), err
+}
+
+// StartOrderViewRebuild run the rebuild in the background, the progress is read with GetOrderViewRebuild
+func (u *ProjectionRebuildUseCase) StartOrderViewRebuild(ctx context.Context, shadow bool, batchSize int) (entity.ProjectionRebuild, error) {
+	rebuild, err := u.begin(ctx, shadow, batchSize)
+	if err != nil {
+		return entity.ProjectionRebuild{}, err
+	}
+
+	started := rebuild
+	u.job.background(func(ctx context.Context) error {
+		return u.rebuildOrderViews(ctx, &rebuild)
+	}, func(err error) {
+		if err != nil {
+			u.l.Error(err, "usecase - ProjectionRebuildUseCase - StartOrderViewRebuild")
+		}
+		u.finish(rebuild, err)
+	})
+
+	return started, nil
+}
+
+func (u *ProjectionRebuildUseCase) GetOrderViewRebuild(ctx context.Context) (entity.ProjectionRebuild, error) {
+	return u.job.get()
+}
+
+// RepairOrderViews write the order views of the orders again over the live tables, used by the reconciler
+func (u *ProjectionRebuildUseCase) RepairOrderViews(ctx context.Context, orders []*entity.Order) error {
+	_, err := u.rebuildBatch(ctx, false, orders, make(map[uuid.UUID]productData))
+	return err
+}
+
+func (u *ProjectionRebuildUseCase) begin(ctx context.Context, shadow bool, batchSize int) (entity.ProjectionRebuild, error) {
+	rebuild, err := entity.NewProjectionRebuild(shadow, batchSize)
+	if err != nil {
+		return entity.ProjectionRebuild{}, fmt.Errorf("failed to generate projection rebuild id: %w", err)
+	}
+
+	if err := u.job.begin(ctx, rebuild.ID, rebuild); err != nil {
+		return entity.ProjectionRebuild{}, err
+	}
+
+	return rebuild, nil
+}
+
+func (u *ProjectionRebuildUseCase) finish(rebuild entity.ProjectionRebuild, err error) entity.ProjectionRebuild {
+	if err != nil {
+		rebuild.SetFailed(err)
+	} else {
+		rebuild.SetDone()
+	}
+	u.job.finish(rebuild)
+
+	return rebuild
+}
+
+func (u *ProjectionRebuildUseCase) rebuildOrderViews(ctx context.Context, rebuild *entity.ProjectionRebuild) error {
+	total, err := u.repoPostgresCommand.Count(ctx)
+	if err != nil {
+		return err
+	}
+	rebuild.Total = total
+	u.job.report(*rebuild)
+
+	if rebuild.Shadow {
+		err = u.repoRebuildQuery.PrepareShadow(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	products := make(map[uuid.UUID]productData)
+	after := uuid.Nil
+	for {
+		orders, err := u.repoPostgresCommand.GetBatch(ctx, after, rebuild.BatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to get order batch: %w", err)
+		}
+		if len(orders) == 0 {
+			break
+		}
+
+		skipped, err := u.rebuildBatch(ctx, rebuild.Shadow, orders, products)
+		if err != nil {
+			return err
+		}
+
+		rebuild.Processed += len(orders)
+		rebuild.Skipped += skipped
+		u.job.report(*rebuild)
+		u.l.Info("projection rebuild %s: %d of %d orders", rebuild.ID, rebuild.Processed, rebuild.Total)
+
+		after = orders[len(orders)-1].ID
+	}
+
+	if !rebuild.Shadow {
+		return nil
+	}
+
+	err = u.repoRebuildQuery.SwapShadow(ctx, rebuild.StartedAt)
+	if err != nil {
+		return err
+	}
+
+	// the cache still has the views of the old tables, the swap is done so a failed delete is only reported
+	err = u.repoRedisQuery.DeleteAll(ctx)
+	if err != nil {
+		orderViewCacheErrors.Add(1)
+		u.l.Error(err, "usecase - ProjectionRebuildUseCase - rebuildOrderViews")
+	}
+
+	return nil
+}
+
+func (u *ProjectionRebuildUseCase) rebuildBatch(ctx context.Context, shadow bool, orders []*entity.Order, products map[uuid.UUID]productData) (int, error) {
+	orderIDs := make([]uuid.UUID, len(orders))
+	for i, order := range orders {
+		orderIDs[i] = order.ID
+	}
+
+	live, err := u.repoRebuildQuery.GetByOrderIDs(ctx, orderIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	views := make([]*entity.OrderView, len(orders))
+	for i, order := range orders {
+		views[i], err = u.rebuildOrderView(ctx, order, live[order.ID], products)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	skipped, err := u.repoRebuildQuery.WriteBatch(ctx, shadow, views)
+	if err != nil {
+		return 0, err
+	}
+
+	if !shadow {
+		for _, id := range orderIDs {
+			if err := deleteCachedOrderView(ctx, u.repoRedisQuery, id); err != nil {
+				orderViewCacheErrors.Add(1)
+			}
+		}
+	}
+
+	return skipped, nil
+}
+
+// rebuildOrderView map the command order to its view, the ids, product details and payment proof
+// are kept from the live view, products missing there are read from the product service
+func (u *ProjectionRebuildUseCase) rebuildOrderView(ctx context.Context, order *entity.Order, live *entity.OrderView, products map[uuid.UUID]productData) (*entity.OrderView, error) {
+	view := &entity.OrderView{
+		OrderID:               order.ID,
+		UserID:                order.UserID,
+		Status:                order.Status,
+		TotalPrice:            order.TotalPrice,
+		PromotionCode:         order.PromotionCode,
+		DiscountAmount:        order.DiscountAmount,
+		TaxAmount:             order.TaxAmount,
+		ShippingMethod:        order.ShippingMethod,
+		ShippingEstimatedDays: order.ShippingEstimatedDays,
+		EstimatedShipping:     order.EstimatedShipping,
+		ShippingRule:          order.ShippingRule,
+		FulfilmentType:        order.FulfilmentType,
+		PickupWarehouseID:     order.PickupWarehouseID,
+		PickupCode:            order.PickupCode,
+		PaymentID:             order.PaymentID,
+		PaymentStatus:         order.PaymentStatus(),
+		Version:               order.Version,
+		Address: entity.OrderAddressView{
+			Street:    order.Address.Street,
+			City:      order.Address.City,
+			State:     order.Address.State,
+			ZipCode:   order.Address.ZipCode,
+			Note:      order.Address.Note,
+			CreatedAt: order.Address.CreatedAt,
+			UpdatedAt: order.Address.UpdatedAt,
+		},
+		CreatedAt: order.CreatedAt,
+		UpdatedAt: order.UpdatedAt,
+	}
+
+	liveItems := make(map[uuid.UUID]entity.OrderItemView)
+	if live != nil {
+		view.ID = live.ID
+		view.Address.ID = live.Address.ID
+		if live.PaymentStatus != "" {
+			view.PaymentStatus = live.PaymentStatus
+		}
+		view.PaymentImageURL = live.PaymentImageURL
+		view.PaymentAdminNote = live.PaymentAdminNote
+		for _, item := range live.Items {
+			liveItems[item.ProductID] = item
+		}
+	}
+	if view.ID == uuid.Nil {
+		if err := view.GenerateOrderViewID(); err != nil {
+			return nil, fmt.Errorf("failed to generate order view id: %w", err)
+		}
+	}
+	if view.Address.ID == uuid.Nil {
+		if err := view.Address.GenerateOrderAddressViewID(); err != nil {
+			return nil, fmt.Errorf("failed to generate order view address id: %w", err)
+		}
+	}
+
+	for _, item := range order.Items {
+		itemView := entity.OrderItemView{
+			ProductID:         item.ProductID,
+			ProductPrice:      item.Price,
+			ProductQuantity:   item.ProductQuantity,
+			ProductCategoryID: item.CategoryID,
+			ShippingCost:      item.ShippingCost,
+			Discount:          item.TotalDiscount(),
+			Tax:               item.Tax,
+			Note:              item.Note,
+			CreatedAt:         item.CreatedAt,
+			UpdatedAt:         item.UpdatedAt,
+		}
+
+		if liveItem, ok := liveItems[item.ProductID]; ok {
+			itemView.ID = liveItem.ID
+			itemView.ProductName = liveItem.ProductName
+			itemView.ProductImageURL = liveItem.ProductImageURL
+			itemView.ProductDescription = liveItem.ProductDescription
+			itemView.ProductCategoryName = liveItem.ProductCategoryName
+		} else {
+			product := u.getRebuildProduct(ctx, item.ProductID, products)
+			itemView.ProductName = product.Name
+			itemView.ProductImageURL = product.ImageURL
+			itemView.ProductDescription = product.Description
+		}
+		if itemView.ID == uuid.Nil {
+			if err := itemView.GenerateOrderItemViewID(); err != nil {
+				return nil, fmt.Errorf("failed to generate order view item id: %w", err)
+			}
+		}
+
+		view.Items = append(view.Items, itemView)
+	}
+
+	return view, nil
+}
+
+// getRebuildProduct read the product once per rebuild, a product that can not be read
+// is rebuilt without its details rather than stopping the rebuild
+func (u *ProjectionRebuildUseCase) getRebuildProduct(ctx context.Context, productID uuid.UUID, products map[uuid.UUID]productData) productData {
+	if product, ok := products[productID]; ok {
+		return product
+	}
+
+	product, err := fetchProduct(ctx, u.productService.BaseURL, productID)
+	if err != nil {
+		u.l.Error(err, "usecase - ProjectionRebuildUseCase - getRebuildProduct")
+	}
+	products[productID] = product
+
+	return product
+}
@@ -0,0 +1,383 @@
+package usecase
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/config"
+	"github.com/idoyudha/eshop-order/internal/constant"
+	"github.com/idoyudha/eshop-order/internal/dto"
+	"github.com/idoyudha/eshop-order/internal/entity"
+	"github.com/idoyudha/eshop-order/pkg/kafka"
+	"github.com/idoyudha/eshop-order/pkg/logger"
+)
+
+var (
+	orderViewReconcileRuns     = expvar.NewInt("order_view_reconcile_runs")
+	orderViewReconcileRepaired = expvar.NewInt("order_view_reconcile_repaired")
+	// discrepancies of the last finished run by kind
+	orderViewReconcileDiscrepancies = expvar.NewMap("order_view_reconcile_discrepancies")
+)
+
+// orders changed within the grace period may still be on their way to the read model
+const reconcileGracePeriod = time.Minute
+
+var discrepancyKinds = []string{
+	entity.DISCREPANCY_MISSING_VIEW,
+	entity.DISCREPANCY_ORPHAN_VIEW,
+	entity.DISCREPANCY_STATUS_MISMATCH,
+	entity.DISCREPANCY_PAYMENT_MISMATCH,
+	entity.DISCREPANCY_TOTAL_MISMATCH,
+}
+
+type ProjectionReconcileUseCase struct {
+	repoPostgresCommand OrderPostgreCommandRepo
+	repoRebuildQuery    OrderRebuildQueryRepo
+	repoRedisQuery      OrderRedisQueryRepo
+	rebuild             ProjectionRebuild
+	producer            *kafka.ProducerServer
+	constant            config.Constant
+	l                   logger.Interface
+	job                 *projectionJob[entity.ReconcileReport]
+}
+
+func NewProjectionReconcileUseCase(
+	repoPostgresCommand OrderPostgreCommandRepo,
+	repoRebuildQuery OrderRebuildQueryRepo,
+	repoRedisQuery OrderRedisQueryRepo,
+	rebuild ProjectionRebuild,
+	producer *kafka.ProducerServer,
+	constant config.Constant,
+	l logger.Interface,
+) *ProjectionReconcileUseCase {
+	return &ProjectionReconcileUseCase{
+		repoPostgresCommand,
+		repoRebuildQuery,
+		repoRedisQuery,
+		rebuild,
+		producer,
+		constant,
+		l,
+		// the replicas must not repair the same orders at the same time
+		newProjectionJob[entity.ReconcileReport]("projection reconcile", entity.RECONCILE_LOCK, repoRebuildQuery),
+	}
+}
+
+// ReconcileOrderViews compare the order views with the orders and wait until it is done,
+// an empty repair uses the configured one
+func (u *ProjectionReconcileUseCase) ReconcileOrderViews(ctx context.Context, repair string) (entity.ReconcileReport, error) {
+	report, err := u.begin(ctx, repair)
+	if err != nil {
+		return entity.ReconcileReport{}, err
+	}
+
+	err = u.reconcileOrderViews(ctx, &report)
+	return u.finish(report, err), err
+}
+
+// StartOrderViewReconcile run the reconcile in the background, the report is read with GetOrderViewReconcile
+func (u *ProjectionReconcileUseCase) StartOrderViewReconcile(ctx context.Context, repair string) (entity.ReconcileReport, error) {
+	report, err := u.begin(ctx, repair)
+	if err != nil {
+		return entity.ReconcileReport{}, err
+	}
+
+	started := report.Snapshot()
+	u.job.background(func(ctx context.Context) error {
+		return u.reconcileOrderViews(ctx, &report)
+	}, func(err error) {
+		if err != nil {
+			u.l.Error(err, "usecase - ProjectionReconcileUseCase - StartOrderViewReconcile")
+		}
+		u.finish(report, err)
+	})
+
+	return started, nil
+}
+
+func (u *ProjectionReconcileUseCase) GetOrderViewReconcile(ctx context.Context) (entity.ReconcileReport, error) {
+	return u.job.get()
+}
+
+func (u *ProjectionReconcileUseCase) begin(ctx context.Context, repair string) (entity.ReconcileReport, error) {
+	if repair == "" {
+		repair = u.constant.ReconcileRepair
+	}
+	switch repair {
+	case entity.RECONCILE_REPAIR_NONE, entity.RECONCILE_REPAIR_PATCH, entity.RECONCILE_REPAIR_EVENTS:
+	default:
+		return entity.ReconcileReport{}, fmt.Errorf("invalid reconcile repair %s: %w", repair, ErrValidation)
+	}
+
+	report, err := entity.NewReconcileReport(repair)
+	if err != nil {
+		return entity.ReconcileReport{}, fmt.Errorf("failed to generate reconcile report id: %w", err)
+	}
+
+	// the report keeps being written by the reconcile, the job keeps a copy
+	if err := u.job.begin(ctx, report.ID, report.Snapshot()); err != nil {
+		return entity.ReconcileReport{}, err
+	}
+
+	return report, nil
+}
+
+func (u *ProjectionReconcileUseCase) finish(report entity.ReconcileReport, err error) entity.ReconcileReport {
+	if err != nil {
+		report.SetFailed(err)
+	} else {
+		report.SetDone()
+		for _, kind := range discrepancyKinds {
+			count := new(expvar.Int)
+			count.Set(int64(report.Counts[kind]))
+			orderViewReconcileDiscrepancies.Set(kind, count)
+		}
+	}
+	orderViewReconcileRuns.Add(1)
+	orderViewReconcileRepaired.Add(int64(report.Repaired))
+
+	u.job.finish(report)
+
+	return report
+}
+
+// reconcileOrderViews walk the orders by id in batches and compare each batch with its order views,
+// the order views between the ids of the batch without an order are orphans
+func (u *ProjectionReconcileUseCase) reconcileOrderViews(ctx context.Context, report *entity.ReconcileReport) error {
+	before := report.StartedAt.Add(-reconcileGracePeriod)
+
+	after := uuid.Nil
+	for {
+		orders, err := u.repoPostgresCommand.GetBatch(ctx, after, entity.REBUILD_DEFAULT_BATCH_SIZE)
+		if err != nil {
+			return fmt.Errorf("failed to get order batch: %w", err)
+		}
+
+		last := uuid.Nil
+		if len(orders) > 0 {
+			last = orders[len(orders)-1].ID
+		}
+
+		err = u.reconcileBatch(ctx, report, orders, after, last, before)
+		if err != nil {
+			return err
+		}
+
+		if len(orders) == 0 {
+			break
+		}
+		after = last
+	}
+
+	u.l.Info("projection reconcile %s: %d orders checked, %d discrepancies, %d repaired",
+		report.ID, report.Checked, totalDiscrepancies(report), report.Repaired)
+
+	return nil
+}
+
+func (u *ProjectionReconcileUseCase) reconcileBatch(ctx context.Context, report *entity.ReconcileReport, orders []*entity.Order, after, last uuid.UUID, before time.Time) error {
+	orderIDs := make([]uuid.UUID, len(orders))
+	for i, order := range orders {
+		orderIDs[i] = order.ID
+	}
+
+	views, err := u.repoRebuildQuery.GetByOrderIDs(ctx, orderIDs)
+	if err != nil {
+		return err
+	}
+
+	var discrepancies []entity.OrderDiscrepancy
+	var drifted []*entity.Order
+	for _, order := range orders {
+		// changed too recently to tell drift from projection lag
+		if order.UpdatedAt.After(before) || order.CreatedAt.After(before) {
+			continue
+		}
+		report.Checked++
+
+		found := compareOrderView(order, views[order.ID])
+		if len(found) == 0 {
+			continue
+		}
+		found = u.repairOrder(report.Repair, order, found)
+		discrepancies = append(discrepancies, found...)
+		drifted = append(drifted, order)
+	}
+
+	if report.Repair == entity.RECONCILE_REPAIR_PATCH && len(drifted) > 0 {
+		if err := u.rebuild.RepairOrderViews(ctx, drifted); err != nil {
+			u.l.Error(err, "usecase - ProjectionReconcileUseCase - reconcileBatch")
+		} else {
+			for i := range discrepancies {
+				discrepancies[i].Repaired = true
+			}
+		}
+	}
+
+	viewIDs, err := u.repoRebuildQuery.GetOrderIDsBetween(ctx, after, last, before)
+	if err != nil {
+		return err
+	}
+	for _, orderID := range viewIDs {
+		if _, ok := views[orderID]; ok {
+			continue
+		}
+		discrepancies = append(discrepancies, u.repairOrphanView(ctx, report.Repair, orderID))
+	}
+
+	for _, discrepancy := range discrepancies {
+		report.Add(discrepancy)
+	}
+
+	return nil
+}
+
+// compareOrderView list what differs between the order and its order view
+func compareOrderView(order *entity.Order, view *entity.OrderView) []entity.OrderDiscrepancy {
+	if view == nil {
+		return []entity.OrderDiscrepancy{{OrderID: order.ID, Kind: entity.DISCREPANCY_MISSING_VIEW}}
+	}
+
+	var discrepancies []entity.OrderDiscrepancy
+	if order.Status != view.Status {
+		discrepancies = append(discrepancies, entity.OrderDiscrepancy{
+			OrderID:      order.ID,
+			Kind:         entity.DISCREPANCY_STATUS_MISMATCH,
+			CommandValue: order.Status,
+			ViewValue:    view.Status,
+		})
+	}
+	if order.PaymentID != view.PaymentID {
+		discrepancies = append(discrepancies, entity.OrderDiscrepancy{
+			OrderID:      order.ID,
+			Kind:         entity.DISCREPANCY_PAYMENT_MISMATCH,
+			CommandValue: order.PaymentID.String(),
+			ViewValue:    view.PaymentID.String(),
+		})
+	}
+	if !entity.IsTotalEqual(order.TotalPrice, view.TotalPrice) ||
+		!entity.IsTotalEqual(order.DiscountAmount, view.DiscountAmount) ||
+		!entity.IsTotalEqual(order.TaxAmount, view.TaxAmount) {
+		discrepancies = append(discrepancies, entity.OrderDiscrepancy{
+			OrderID:      order.ID,
+			Kind:         entity.DISCREPANCY_TOTAL_MISMATCH,
+			CommandValue: formatTotals(order.TotalPrice, order.DiscountAmount, order.TaxAmount),
+			ViewValue:    formatTotals(view.TotalPrice, view.DiscountAmount, view.TaxAmount),
+		})
+	}
+
+	return discrepancies
+}
+
+// repairOrder publish the events of the order again when repairing with events, onto the repair topics
+// so the other consumers of the order topics do not see them twice. the events go out
+// without a version so the projection applies them over a view that already has the order version.
+// the payment event also writes the command side, a payment mismatch is only reported
+func (u *ProjectionReconcileUseCase) repairOrder(repair string, order *entity.Order, discrepancies []entity.OrderDiscrepancy) []entity.OrderDiscrepancy {
+	if repair != entity.RECONCILE_REPAIR_EVENTS {
+		return discrepancies
+	}
+
+	for i, discrepancy := range discrepancies {
+		var err error
+		switch discrepancy.Kind {
+		case entity.DISCREPANCY_MISSING_VIEW:
+			// the created event carries no status, orders past pending need the status event as well
+			err = u.publishOrderCreated(order)
+			if err == nil && order.Status != entity.ORDER_PENDING {
+				err = u.publishOrderStatusUpdated(order)
+			}
+		case entity.DISCREPANCY_STATUS_MISMATCH:
+			err = u.publishOrderStatusUpdated(order)
+		case entity.DISCREPANCY_TOTAL_MISMATCH:
+			err = u.publishOrderUpdated(order)
+		default:
+			continue
+		}
+		if err != nil {
+			u.l.Error(err, "usecase - ProjectionReconcileUseCase - repairOrder")
+			continue
+		}
+		discrepancies[i].Repaired = true
+	}
+
+	return discrepancies
+}
+
+// repairOrphanView remove the order view when repairing with patches, no event removes an order view
+func (u *ProjectionReconcileUseCase) repairOrphanView(ctx context.Context, repair string, orderID uuid.UUID) entity.OrderDiscrepancy {
+	discrepancy := entity.OrderDiscrepancy{OrderID: orderID, Kind: entity.DISCREPANCY_ORPHAN_VIEW}
+	if repair != entity.RECONCILE_REPAIR_PATCH {
+		return discrepancy
+	}
+
+	if err := u.repoRebuildQuery.DeleteByOrderID(ctx, orderID); err != nil {
+		u.l.Error(err, "usecase - ProjectionReconcileUseCase - repairOrphanView")
+		return discrepancy
+	}
+	if err := deleteCachedOrderView(ctx, u.repoRedisQuery, orderID); err != nil {
+		orderViewCacheErrors.Add(1)
+	}
+	discrepancy.Repaired = true
+
+	return discrepancy
+}
+
+func (u *ProjectionReconcileUseCase) publishOrderCreated(order *entity.Order) error {
+	message := dto.OrderEntityToKafkaOrderCreatedMessage(order)
+	err := u.producer.Publish(
+		constant.OrderCreatedRepairTopic,
+		[]byte(message.OrderID.String()),
+		message,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+
+	return nil
+}
+
+func (u *ProjectionReconcileUseCase) publishOrderStatusUpdated(order *entity.Order) error {
+	message := dto.OrderEntityToKafkaOrderStatusUpdatedMessage(order)
+	message.Version = 0
+	err := u.producer.Publish(
+		constant.OrderStatusUpdatedRepairTopic,
+		[]byte(order.ID.String()),
+		message,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+
+	return nil
+}
+
+func (u *ProjectionReconcileUseCase) publishOrderUpdated(order *entity.Order) error {
+	message := dto.OrderEntityToKafkaOrderUpdatedMessage(order)
+	message.Version = 0
+	err := u.producer.Publish(
+		constant.OrderUpdatedRepairTopic,
+		[]byte(order.ID.String()),
+		message,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+
+	return nil
+}
+
+func formatTotals(total, discount, tax float64) string {
+	return fmt.Sprintf("total=%.2f discount=%.2f tax=%.2f", total, discount, tax)
+}
+
+func totalDiscrepancies(report *entity.ReconcileReport) int {
+	total := 0
+	for _, count := range report.Counts {
+		total += count
+	}
+	return total
+}
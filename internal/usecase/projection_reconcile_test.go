@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
+)
+
+func newReconcileTestOrder() (*entity.Order, *entity.OrderView) {
+	order := &entity.Order{
+		ID:             uuid.New(),
+		Status:         entity.ORDER_ON_DELIVERY,
+		PaymentID:      uuid.New(),
+		TotalPrice:     150000,
+		DiscountAmount: 10000,
+		TaxAmount:      16500,
+	}
+	view := &entity.OrderView{
+		OrderID:        order.ID,
+		Status:         order.Status,
+		PaymentID:      order.PaymentID,
+		TotalPrice:     order.TotalPrice,
+		DiscountAmount: order.DiscountAmount,
+		TaxAmount:      order.TaxAmount,
+	}
+	return order, view
+}
+
+func TestCompareOrderView(t *testing.T) {
+	tests := []struct {
+		name      string
+		change    func(view *entity.OrderView)
+		wantKinds []string
+	}{
+		{
+			name:   "in sync",
+			change: func(view *entity.OrderView) {},
+		},
+		{
+			name:   "total within rounding",
+			change: func(view *entity.OrderView) { view.TotalPrice += 0.004 },
+		},
+		{
+			name:      "status",
+			change:    func(view *entity.OrderView) { view.Status = entity.ORDER_PENDING },
+			wantKinds: []string{entity.DISCREPANCY_STATUS_MISMATCH},
+		},
+		{
+			name:      "payment",
+			change:    func(view *entity.OrderView) { view.PaymentID = uuid.Nil },
+			wantKinds: []string{entity.DISCREPANCY_PAYMENT_MISMATCH},
+		},
+		{
+			name:      "total",
+			change:    func(view *entity.OrderView) { view.TotalPrice += 0.01 },
+			wantKinds: []string{entity.DISCREPANCY_TOTAL_MISMATCH},
+		},
+		{
+			name:      "discount",
+			change:    func(view *entity.OrderView) { view.DiscountAmount = 0 },
+			wantKinds: []string{entity.DISCREPANCY_TOTAL_MISMATCH},
+		},
+		{
+			name:      "tax",
+			change:    func(view *entity.OrderView) { view.TaxAmount = 0 },
+			wantKinds: []string{entity.DISCREPANCY_TOTAL_MISMATCH},
+		},
+		{
+			name: "several fields are one discrepancy each",
+			change: func(view *entity.OrderView) {
+				view.Status = entity.ORDER_PENDING
+				view.PaymentID = uuid.Nil
+				view.TotalPrice = 0
+				view.TaxAmount = 0
+			},
+			wantKinds: []string{entity.DISCREPANCY_STATUS_MISMATCH, entity.DISCREPANCY_PAYMENT_MISMATCH, entity.DISCREPANCY_TOTAL_MISMATCH},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, view := newReconcileTestOrder()
+			tt.change(view)
+
+			got := compareOrderView(order, view)
+			if len(got) != len(tt.wantKinds) {
+				t.Fatalf("compareOrderView() = %+v, want kinds %v", got, tt.wantKinds)
+			}
+			for i, discrepancy := range got {
+				if discrepancy.Kind != tt.wantKinds[i] {
+					t.Errorf("discrepancy %d kind = %s, want %s", i, discrepancy.Kind, tt.wantKinds[i])
+				}
+				if discrepancy.OrderID != order.ID {
+					t.Errorf("discrepancy %d order id = %s, want %s", i, discrepancy.OrderID, order.ID)
+				}
+				if discrepancy.CommandValue == discrepancy.ViewValue {
+					t.Errorf("discrepancy %d has the same command and view value %q", i, discrepancy.CommandValue)
+				}
+				if discrepancy.Repaired {
+					t.Errorf("discrepancy %d is repaired before any repair", i)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareOrderViewMissingView(t *testing.T) {
+	order, _ := newReconcileTestOrder()
+
+	got := compareOrderView(order, nil)
+	if len(got) != 1 || got[0].Kind != entity.DISCREPANCY_MISSING_VIEW || got[0].OrderID != order.ID {
+		t.Errorf("compareOrderView() = %+v, want one %s discrepancy", got, entity.DISCREPANCY_MISSING_VIEW)
+	}
+}
+
+func TestCompareOrderViewValues(t *testing.T) {
+	order, view := newReconcileTestOrder()
+	view.Status = entity.ORDER_PENDING
+	view.TaxAmount = 0
+
+	got := compareOrderView(order, view)
+	if len(got) != 2 {
+		t.Fatalf("compareOrderView() = %+v, want 2 discrepancies", got)
+	}
+	if got[0].CommandValue != entity.ORDER_ON_DELIVERY || got[0].ViewValue != entity.ORDER_PENDING {
+		t.Errorf("status values = %q/%q, want %q/%q", got[0].CommandValue, got[0].ViewValue, entity.ORDER_ON_DELIVERY, entity.ORDER_PENDING)
+	}
+	wantCommand := "total=150000.00 discount=10000.00 tax=16500.00"
+	wantView := "total=150000.00 discount=10000.00 tax=0.00"
+	if got[1].CommandValue != wantCommand || got[1].ViewValue != wantView {
+		t.Errorf("total values = %q/%q, want %q/%q", got[1].CommandValue, got[1].ViewValue, wantCommand, wantView)
+	}
+}
@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
+)
+
+type PromotionCommandUseCase struct {
+	repoPostgresCommand PromotionPostgreCommandRepo
+}
+
+func NewPromotionCommandUseCase(repoPostgresCommand PromotionPostgreCommandRepo) *PromotionCommandUseCase {
+	return &PromotionCommandUseCase{
+		repoPostgresCommand,
+	}
+}
+
+func (u *PromotionCommandUseCase) CreatePromotion(ctx context.Context, promotion *entity.Promotion) error {
+	if !promotion.EndAt.After(promotion.StartAt) {
+		return fmt.Errorf("promotion end must be after the start: %w", ErrValidation)
+	}
+
+	switch promotion.Type {
+	case entity.PROMOTION_PERCENTAGE:
+		if promotion.Value <= 0 || promotion.Value > 100 {
+			return fmt.Errorf("percentage must be between 0 and 100: %w", ErrValidation)
+		}
+	case entity.PROMOTION_FIXED:
+		if promotion.Value <= 0 {
+			return fmt.Errorf("fixed discount must be positive: %w", ErrValidation)
+		}
+	case entity.PROMOTION_FREE_SHIPPING:
+	case entity.PROMOTION_BUY_X_GET_Y:
+		if promotion.ProductID == uuid.Nil || promotion.BuyQuantity <= 0 || promotion.GetQuantity <= 0 {
+			return fmt.Errorf("buy x get y needs product, buy and get quantity: %w", ErrValidation)
+		}
+	default:
+		return fmt.Errorf("unknown promotion type %s: %w", promotion.Type, ErrValidation)
+	}
+
+	err := promotion.GeneratePromotionID()
+	if err != nil {
+		return fmt.Errorf("failed to generate promotion id: %w", err)
+	}
+
+	err = u.repoPostgresCommand.Insert(ctx, promotion)
+	if err != nil {
+		return fmt.Errorf("failed to insert promotion: %w", err)
+	}
+
+	return nil
+}
+
+func (u *PromotionCommandUseCase) GetAllPromotions(ctx context.Context, page entity.Page) ([]*entity.Promotion, entity.PageCursor, error) {
+	return u.repoPostgresCommand.GetAll(ctx, page)
+}
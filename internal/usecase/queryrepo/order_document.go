@@ -0,0 +1,271 @@
+package queryrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
+)
+
+// orderViewTables name the tables of the order projection, the rebuild writes into shadow tables
+type orderViewTables struct {
+	orders    string
+	items     string
+	addresses string
+}
+
+var (
+	liveOrderViewTables   = orderViewTables{"orders_view", "order_items_view", "order_addresses_view"}
+	shadowOrderViewTables = orderViewTables{"orders_view_shadow", "order_items_view_shadow", "order_addresses_view_shadow"}
+)
+
+// queryRefreshOrderDocument rebuild the jsonb document of the order from the view tables,
+// every projection write on the order, its returns or refunds runs it in the same transaction.
+// timestamps are stored without time zone and read as UTC, same as the columns
+const queryRefreshOrderDocument = `
+	UPDATE %[1]s ov SET document = jsonb_build_object(
+		'id', ov.id,
+		'order_id', ov.order_id,
+		'user_id', ov.user_id,
+		'status', ov.status,
+		'total_price', ov.total_price,
+		'promotion_code', COALESCE(ov.promotion_code, ''),
+		'discount_amount', ov.discount_amount,
+		'tax_amount', ov.tax_amount,
+		'version', ov.version,
+		'payment', jsonb_build_object(
+			'id', ov.payment_id,
+			'status', COALESCE(ov.payment_status::text, ''),
+			'image_url', COALESCE(ov.payment_image_url, ''),
+			'admin_note', COALESCE(ov.payment_admin_note, '')
+		),
+		'shipment', jsonb_build_object(
+			'method', ov.shipping_method,
+			'estimated_days', ov.shipping_estimated_days,
+			'estimated', ov.estimated_shipping,
+			'rule', ov.shipping_rule,
+			'fulfilment_type', ov.fulfilment_type,
+			'pickup_warehouse_id', ov.pickup_warehouse_id,
+			'pickup_code', ov.pickup_code
+		),
+		'address', COALESCE((
+			SELECT jsonb_build_object(
+				'id', oa.id,
+				'street', oa.street,
+				'city', oa.city,
+				'state', oa.state,
+				'zip_code', oa.zip_code,
+				'note', COALESCE(oa.note, '')
+			)
+			FROM %[3]s oa
+			WHERE oa.order_view_id = ov.id
+			LIMIT 1
+		), '{}'::jsonb),
+		'items', COALESCE((
+			SELECT jsonb_agg(jsonb_build_object(
+				'id', oi.id,
+				'product_id', oi.product_id,
+				'product_name', oi.product_name,
+				'product_price', oi.product_price::float,
+				'product_quantity', oi.product_quantity,
+				'product_image_url', COALESCE(oi.product_image_url, ''),
+				'product_description', oi.product_description,
+				'product_category_id', oi.product_category_id,
+				'product_category_name', oi.product_category_name,
+				'shipping_cost', oi.shipping_cost,
+				'discount', oi.discount,
+				'tax', oi.tax,
+				'note', COALESCE(oi.note, '')
+			) ORDER BY oi.created_at, oi.id)
+			FROM %[2]s oi
+			WHERE oi.order_view_id = ov.id AND oi.deleted_at IS NULL
+		), '[]'::jsonb),
+		'timeline', jsonb_build_object(
+			'created_at', ov.created_at AT TIME ZONE 'UTC',
+			'updated_at', ov.updated_at AT TIME ZONE 'UTC',
+			'return_count', (SELECT count(*) FROM order_returns_view r WHERE r.order_id = ov.order_id AND r.deleted_at IS NULL),
+			'refund_count', (SELECT count(*) FROM refunds_view f WHERE f.order_id = ov.order_id AND f.deleted_at IS NULL),
+			'refunded_amount', (SELECT COALESCE(sum(f.amount), 0) FROM refunds_view f WHERE f.order_id = ov.order_id AND f.deleted_at IS NULL)
+		)
+	)
+	WHERE ov.order_id = $1;
+`
+
+// queryRefreshOrderSearch rebuild the full-text search vector from the refreshed document,
+// product names and the order number rank above the address and the product descriptions
+const queryRefreshOrderSearch = `
+	UPDATE %[1]s ov SET search =
+		setweight(to_tsvector('simple', ov.order_id::text), 'A') ||
+		setweight(to_tsvector('simple', COALESCE((SELECT string_agg(i->>'product_name', ' ') FROM jsonb_array_elements(ov.document->'items') i), '')), 'A') ||
+		setweight(to_tsvector('simple', concat_ws(' ',
+			ov.document->'address'->>'street',
+			ov.document->'address'->>'city',
+			ov.document->'address'->>'state',
+			ov.document->'address'->>'zip_code'
+		)), 'B') ||
+		setweight(to_tsvector('simple', COALESCE((SELECT string_agg(i->>'product_description', ' ') FROM jsonb_array_elements(ov.document->'items') i), '')), 'C')
+	WHERE ov.order_id = $1;
+`
+
+func refreshOrderDocument(ctx context.Context, tx *sql.Tx, orderID uuid.UUID) error {
+	return refreshOrderDocumentIn(ctx, tx, liveOrderViewTables, orderID)
+}
+
+func refreshOrderDocumentIn(ctx context.Context, tx *sql.Tx, tables orderViewTables, orderID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, tables.format(queryRefreshOrderDocument), orderID)
+	if err != nil {
+		return fmt.Errorf("failed to refresh order document: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, tables.format(queryRefreshOrderSearch), orderID)
+	if err != nil {
+		return fmt.Errorf("failed to refresh order search: %w", err)
+	}
+
+	return nil
+}
+
+// format fill the table names into a query, %[1]s is the orders, %[2]s the items and %[3]s the addresses
+func (t orderViewTables) format(query string) string {
+	return fmt.Sprintf(query, t.orders, t.items, t.addresses)
+}
+
+// orderDocument is the shape of orders_view.document
+type orderDocument struct {
+	ID             uuid.UUID             `json:"id"`
+	OrderID        uuid.UUID             `json:"order_id"`
+	UserID         uuid.UUID             `json:"user_id"`
+	Status         string                `json:"status"`
+	TotalPrice     float64               `json:"total_price"`
+	PromotionCode  string                `json:"promotion_code"`
+	DiscountAmount float64               `json:"discount_amount"`
+	TaxAmount      float64               `json:"tax_amount"`
+	Version        int                   `json:"version"`
+	Payment        orderDocumentPayment  `json:"payment"`
+	Shipment       orderDocumentShipment `json:"shipment"`
+	Address        orderDocumentAddress  `json:"address"`
+	Items          []orderDocumentItem   `json:"items"`
+	Timeline       orderDocumentTimeline `json:"timeline"`
+}
+
+type orderDocumentPayment struct {
+	ID        uuid.NullUUID `json:"id"`
+	Status    string        `json:"status"`
+	ImageURL  string        `json:"image_url"`
+	AdminNote string        `json:"admin_note"`
+}
+
+type orderDocumentShipment struct {
+	Method            string        `json:"method"`
+	EstimatedDays     int           `json:"estimated_days"`
+	Estimated         bool          `json:"estimated"`
+	Rule              string        `json:"rule"`
+	FulfilmentType    string        `json:"fulfilment_type"`
+	PickupWarehouseID uuid.NullUUID `json:"pickup_warehouse_id"`
+	PickupCode        string        `json:"pickup_code"`
+}
+
+type orderDocumentAddress struct {
+	ID      uuid.UUID `json:"id"`
+	Street  string    `json:"street"`
+	City    string    `json:"city"`
+	State   string    `json:"state"`
+	ZipCode string    `json:"zip_code"`
+	Note    string    `json:"note"`
+}
+
+type orderDocumentItem struct {
+	ID                  uuid.UUID     `json:"id"`
+	ProductID           uuid.UUID     `json:"product_id"`
+	ProductName         string        `json:"product_name"`
+	ProductPrice        float64       `json:"product_price"`
+	ProductQuantity     int64         `json:"product_quantity"`
+	ProductImageURL     string        `json:"product_image_url"`
+	ProductDescription  string        `json:"product_description"`
+	ProductCategoryID   uuid.NullUUID `json:"product_category_id"`
+	ProductCategoryName string        `json:"product_category_name"`
+	ShippingCost        float64       `json:"shipping_cost"`
+	Discount            float64       `json:"discount"`
+	Tax                 float64       `json:"tax"`
+	Note                string        `json:"note"`
+}
+
+type orderDocumentTimeline struct {
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	ReturnCount    int       `json:"return_count"`
+	RefundCount    int       `json:"refund_count"`
+	RefundedAmount float64   `json:"refunded_amount"`
+}
+
+func decodeOrderDocument(raw []byte) (*entity.OrderView, error) {
+	var doc orderDocument
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode order document: %w", err)
+	}
+
+	order := &entity.OrderView{
+		ID:                    doc.ID,
+		OrderID:               doc.OrderID,
+		UserID:                doc.UserID,
+		Status:                doc.Status,
+		TotalPrice:            doc.TotalPrice,
+		PromotionCode:         doc.PromotionCode,
+		DiscountAmount:        doc.DiscountAmount,
+		TaxAmount:             doc.TaxAmount,
+		ShippingMethod:        doc.Shipment.Method,
+		ShippingEstimatedDays: doc.Shipment.EstimatedDays,
+		EstimatedShipping:     doc.Shipment.Estimated,
+		ShippingRule:          doc.Shipment.Rule,
+		FulfilmentType:        doc.Shipment.FulfilmentType,
+		PickupWarehouseID:     doc.Shipment.PickupWarehouseID.UUID,
+		PickupCode:            doc.Shipment.PickupCode,
+		PaymentID:             doc.Payment.ID.UUID,
+		PaymentStatus:         doc.Payment.Status,
+		PaymentImageURL:       doc.Payment.ImageURL,
+		PaymentAdminNote:      doc.Payment.AdminNote,
+		Version:               doc.Version,
+		Address: entity.OrderAddressView{
+			ID:          doc.Address.ID,
+			OrderViewID: doc.ID,
+			Street:      doc.Address.Street,
+			City:        doc.Address.City,
+			State:       doc.Address.State,
+			ZipCode:     doc.Address.ZipCode,
+			Note:        doc.Address.Note,
+		},
+		Timeline: entity.OrderTimelineView{
+			ReturnCount:    doc.Timeline.ReturnCount,
+			RefundCount:    doc.Timeline.RefundCount,
+			RefundedAmount: doc.Timeline.RefundedAmount,
+		},
+		CreatedAt: doc.Timeline.CreatedAt,
+		UpdatedAt: doc.Timeline.UpdatedAt,
+	}
+
+	order.Items = make([]entity.OrderItemView, len(doc.Items))
+	for i, item := range doc.Items {
+		order.Items[i] = entity.OrderItemView{
+			ID:                  item.ID,
+			OrderViewID:         doc.ID,
+			ProductID:           item.ProductID,
+			ProductName:         item.ProductName,
+			ProductPrice:        item.ProductPrice,
+			ProductQuantity:     item.ProductQuantity,
+			ProductImageURL:     item.ProductImageURL,
+			ProductDescription:  item.ProductDescription,
+			ProductCategoryID:   item.ProductCategoryID.UUID,
+			ProductCategoryName: item.ProductCategoryName,
+			ShippingCost:        item.ShippingCost,
+			Discount:            item.Discount,
+			Tax:                 item.Tax,
+			Note:                item.Note,
+		}
+	}
+
+	return order, nil
+}
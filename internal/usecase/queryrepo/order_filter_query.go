@@ -0,0 +1,86 @@
+package queryrepo
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
+	"github.com/lib/pq"
+)
+
+// sort fields allowed on the order listing, only these column names are put into the query
+var orderSortColumns = map[string]string{
+	entity.ORDER_SORT_CREATED_AT:  "created_at",
+	entity.ORDER_SORT_UPDATED_AT:  "updated_at",
+	entity.ORDER_SORT_TOTAL_PRICE: "total_price",
+}
+
+// orderFilterQuery build the where clause on orders_view ov, every value is a query argument
+type orderFilterQuery struct {
+	where strings.Builder
+	args  []interface{}
+}
+
+func newOrderFilterQuery(filter entity.OrderFilter) *orderFilterQuery {
+	q := &orderFilterQuery{}
+	if len(filter.Statuses) > 0 {
+		q.and("ov.status = ANY(?)", pq.Array(filter.Statuses))
+	}
+	if !filter.CreatedFrom.IsZero() {
+		q.and("ov.created_at >= ?", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		q.and("ov.created_at <= ?", filter.CreatedTo)
+	}
+	if !filter.UpdatedFrom.IsZero() {
+		q.and("ov.updated_at >= ?", filter.UpdatedFrom)
+	}
+	if !filter.UpdatedTo.IsZero() {
+		q.and("ov.updated_at <= ?", filter.UpdatedTo)
+	}
+	if filter.MinTotalPrice > 0 {
+		q.and("ov.total_price >= ?", filter.MinTotalPrice)
+	}
+	if filter.MaxTotalPrice > 0 {
+		q.and("ov.total_price <= ?", filter.MaxTotalPrice)
+	}
+	if filter.PaymentStatus != "" {
+		q.and("ov.payment_status = ?", filter.PaymentStatus)
+	}
+	if filter.UserID != uuid.Nil {
+		q.and("ov.user_id = ?", filter.UserID)
+	}
+	if filter.ProductID != uuid.Nil {
+		// containment on the document uses its gin index
+		q.and("ov.document @> jsonb_build_object('items', jsonb_build_array(jsonb_build_object('product_id', ?::text)))", filter.ProductID.String())
+	}
+	if filter.State != "" {
+		q.and("lower(ov.document->'address'->>'state') = lower(?)", filter.State)
+	}
+	if filter.City != "" {
+		q.and("lower(ov.document->'address'->>'city') = lower(?)", filter.City)
+	}
+	return q
+}
+
+// and append the condition, each ? in the condition is replaced by the numbered placeholder of its argument
+func (q *orderFilterQuery) and(condition string, args ...interface{}) {
+	for _, arg := range args {
+		q.args = append(q.args, arg)
+		condition = strings.Replace(condition, "?", "$"+strconv.Itoa(len(q.args)), 1)
+	}
+	q.where.WriteString(" AND ")
+	q.where.WriteString(condition)
+}
+
+// orderCursorValue convert the cursor value to the type of the sort field
+func orderCursorValue(sort string, cursor entity.PageCursor) (interface{}, error) {
+	if cursor.Sort != sort {
+		return nil, entity.ErrInvalidPageCursor
+	}
+	if sort == entity.ORDER_SORT_TOTAL_PRICE {
+		return cursor.FloatValue()
+	}
+	return cursor.TimeValue()
+}
@@ -0,0 +1,42 @@
+package queryrepo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/idoyudha/eshop-order/internal/entity"
+)
+
+// the job locks are session advisory locks, so they span every replica and the cli,
+// and postgres releases them when the process holding them dies
+const (
+	queryTryJobLock = `SELECT pg_try_advisory_lock(hashtextextended($1, 0));`
+	queryJobUnlock  = `SELECT pg_advisory_unlock(hashtextextended($1, 0));`
+)
+
+// TryLock take the named job lock on its own connection, entity.ErrProjectionJobLocked when it is
+// held elsewhere. the returned unlock release the lock and the connection
+func (r *OrderRebuildQueryRepo) TryLock(ctx context.Context, name string) (func(), error) {
+	conn, err := r.Conn.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lock connection: %w", err)
+	}
+
+	var locked bool
+	err = conn.QueryRowContext(ctx, queryTryJobLock, name).Scan(&locked)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to take %s lock: %w", name, err)
+	}
+	if !locked {
+		conn.Close()
+		return nil, fmt.Errorf("%s: %w", name, entity.ErrProjectionJobLocked)
+	}
+
+	unlock := func() {
+		// the job may end with its context cancelled, the lock is still released
+		_, _ = conn.ExecContext(context.Background(), queryJobUnlock, name)
+		conn.Close()
+	}
+	return unlock, nil
+}
@@ -0,0 +1,159 @@
+package queryrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
+)
+
+const (
+	queryLockOrderViewVersion = `SELECT version FROM orders_view WHERE order_id = $1 FOR UPDATE;`
+	querySetOrderViewVersion  = `UPDATE orders_view SET version = $1 WHERE order_id = $2;`
+)
+
+// lockOrderViewVersion lock the order view for an update event and move it to the event version,
+// sql.ErrNoRows when the order view is not created yet and entity.ErrStaleOrderEvent when the view
+// already has the version, events published before orders were versioned are always applied
+func lockOrderViewVersion(ctx context.Context, tx *sql.Tx, orderID uuid.UUID, version int) error {
+	var current int
+	err := tx.QueryRowContext(ctx, queryLockOrderViewVersion, orderID).Scan(&current)
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		return nil
+	}
+	if current >= version {
+		return entity.ErrStaleOrderEvent
+	}
+
+	_, err = tx.ExecContext(ctx, querySetOrderViewVersion, version, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to set order view version: %w", err)
+	}
+
+	return nil
+}
+
+// creating the order view and parking an update of the order take the same lock, so an update is either
+// parked before the order view is created and replayed after it, or it finds the order view
+const queryLockOrderViewCreation = `SELECT pg_advisory_xact_lock(hashtextextended($1::text, 0));`
+
+func lockOrderViewCreation(ctx context.Context, tx *sql.Tx, orderID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, queryLockOrderViewCreation, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to lock order view creation: %w", err)
+	}
+	return nil
+}
+
+const (
+	queryExistsOrderView        = `SELECT EXISTS (SELECT 1 FROM orders_view WHERE order_id = $1);`
+	queryInsertParkedOrderEvent = `INSERT INTO order_parked_events_view (id, order_id, kind, version, payload, created_at) VALUES ($1, $2, $3, $4, $5, $6);`
+)
+
+// InsertParkedEvent park the update until its order view is created,
+// entity.ErrOrderViewCreated when the order view was created in the meantime
+func (r *OrderPostgreQueryRepo) InsertParkedEvent(ctx context.Context, event *entity.ParkedOrderEvent) error {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = lockOrderViewCreation(ctx, tx, event.OrderID)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, queryExistsOrderView, event.OrderID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check order view: %w", err)
+	}
+	if exists {
+		return entity.ErrOrderViewCreated
+	}
+
+	_, err = tx.ExecContext(ctx, queryInsertParkedOrderEvent,
+		event.ID, event.OrderID, event.Kind, event.Version, event.Payload, event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert parked order event: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// orders with parked events whose order view exists, their replay failed after the order view was created
+const queryGetReplayableParkedOrderIDs = `
+	SELECT DISTINCT pe.order_id
+	FROM order_parked_events_view pe
+	JOIN orders_view ov ON ov.order_id = pe.order_id
+	LIMIT $1;
+`
+
+func (r *OrderPostgreQueryRepo) GetReplayableParkedOrderIDs(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	rows, err := r.Conn.QueryContext(ctx, queryGetReplayableParkedOrderIDs, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query replayable parked order ids: %w", err)
+	}
+	defer rows.Close()
+
+	var orderIDs []uuid.UUID
+	for rows.Next() {
+		var orderID uuid.UUID
+		if err := rows.Scan(&orderID); err != nil {
+			return nil, fmt.Errorf("failed to scan replayable parked order id: %w", err)
+		}
+		orderIDs = append(orderIDs, orderID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating replayable parked order id rows: %w", err)
+	}
+
+	return orderIDs, nil
+}
+
+// oldest version first, so the events are applied in the order they were written
+const queryGetParkedOrderEvents = `
+	SELECT id, order_id, kind, version, payload, created_at
+	FROM order_parked_events_view
+	WHERE order_id = $1
+	ORDER BY version, created_at;
+`
+
+func (r *OrderPostgreQueryRepo) GetParkedEvents(ctx context.Context, orderID uuid.UUID) ([]*entity.ParkedOrderEvent, error) {
+	rows, err := r.Conn.QueryContext(ctx, queryGetParkedOrderEvents, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query parked order events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*entity.ParkedOrderEvent
+	for rows.Next() {
+		var event entity.ParkedOrderEvent
+		if err := rows.Scan(&event.ID, &event.OrderID, &event.Kind, &event.Version, &event.Payload, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan parked order event: %w", err)
+		}
+		events = append(events, &event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating parked order event rows: %w", err)
+	}
+
+	return events, nil
+}
+
+const queryDeleteParkedOrderEvent = `DELETE FROM order_parked_events_view WHERE id = $1;`
+
+func (r *OrderPostgreQueryRepo) DeleteParkedEvent(ctx context.Context, id uuid.UUID) error {
+	_, err := r.Conn.ExecContext(ctx, queryDeleteParkedOrderEvent, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete parked order event: %w", err)
+	}
+
+	return nil
+}
@@ -21,8 +21,8 @@ func NewOrderPostgreQueryRepo(conn *postgrequery.PostgresQuery) *OrderPostgreQue
 }
 
 const (
-	queryInsertOrdersView       = `INSERT INTO orders_view (id, order_id, user_id, status, total_price, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7);`
-	queryInserrOrderItemsView   = `INSERT INTO order_items_view (id, order_view_id, product_id, product_name, product_price, product_quantity, product_image_url, product_description, product_category_id, product_category_name, shipping_cost, note, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14);`
+	queryInsertOrdersView       = `INSERT INTO orders_view (id, order_id, user_id, status, total_price, promotion_code, discount_amount, tax_amount, shipping_method, shipping_estimated_days, estimated_shipping, shipping_rule, fulfilment_type, pickup_warehouse_id, pickup_code, version, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18);`
+	queryInserrOrderItemsView   = `INSERT INTO order_items_view (id, order_view_id, product_id, product_name, product_price, product_quantity, product_image_url, product_description, product_category_id, product_category_name, shipping_cost, discount, tax, note, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16);`
 	queryInsertOrderAddressView = `INSERT INTO order_addresses_view (id, order_view_id, street, city, state, zip_code, note, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);`
 )
 
@@ -33,10 +33,18 @@ func (r *OrderPostgreQueryRepo) Insert(ctx context.Context, order *entity.OrderV
 	}
 	defer tx.Rollback()
 
+	err = lockOrderViewCreation(ctx, tx, order.OrderID)
+	if err != nil {
+		return err
+	}
+
 	// order
 	_, err = tx.ExecContext(ctx, queryInsertOrdersView,
 		order.ID, order.OrderID, order.UserID, order.Status, order.TotalPrice,
-		order.CreatedAt, order.UpdatedAt)
+		sql.NullString{String: order.PromotionCode, Valid: order.PromotionCode != ""}, order.DiscountAmount,
+		order.TaxAmount, order.ShippingMethod, order.ShippingEstimatedDays, order.EstimatedShipping, order.ShippingRule,
+		order.FulfilmentType, uuid.NullUUID{UUID: order.PickupWarehouseID, Valid: order.PickupWarehouseID != uuid.Nil}, order.PickupCode,
+		order.Version, order.CreatedAt, order.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to insert order view: %w", err)
 	}
@@ -47,8 +55,8 @@ func (r *OrderPostgreQueryRepo) Insert(ctx context.Context, order *entity.OrderV
 			item.ID, order.ID,
 			item.ProductID, item.ProductName, item.ProductPrice,
 			item.ProductQuantity, item.ProductImageURL, item.ProductDescription,
-			item.ProductCategoryID, item.ProductCategoryName, item.ShippingCost,
-			item.Note, item.CreatedAt, item.UpdatedAt)
+			uuid.NullUUID{UUID: item.ProductCategoryID, Valid: item.ProductCategoryID != uuid.Nil}, item.ProductCategoryName, item.ShippingCost,
+			item.Discount, item.Tax, item.Note, item.CreatedAt, item.UpdatedAt)
 		if err != nil {
 			return fmt.Errorf("failed to insert order item view: %w", err)
 		}
@@ -63,406 +71,334 @@ func (r *OrderPostgreQueryRepo) Insert(ctx context.Context, order *entity.OrderV
 		return fmt.Errorf("failed to insert order address view: %w", err)
 	}
 
+	err = refreshOrderDocument(ctx, tx, order.OrderID)
+	if err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
 const queryUpdateOrderPayment = `UPDATE orders_view SET status = $1, payment_id = $2, payment_status = $3, payment_image_url = $4, payment_admin_note = $5, updated_at = $6 WHERE order_id = $7;`
 
 func (r *OrderPostgreQueryRepo) UpdatePayment(ctx context.Context, orderView *entity.OrderView) error {
-	stmt, errStmt := r.Conn.PrepareContext(ctx, queryUpdateOrderPayment)
-	if errStmt != nil {
-		return errStmt
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	defer stmt.Close()
-	_, updateErr := stmt.ExecContext(ctx, orderView.Status, orderView.PaymentID, orderView.PaymentStatus, orderView.PaymentImageURL, orderView.PaymentAdminNote, orderView.UpdatedAt, orderView.OrderID)
-	if updateErr != nil {
-		return updateErr
+	defer tx.Rollback()
+
+	err = lockOrderViewVersion(ctx, tx, orderView.OrderID, orderView.Version)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, queryUpdateOrderPayment,
+		orderView.Status, orderView.PaymentID, orderView.PaymentStatus, orderView.PaymentImageURL, orderView.PaymentAdminNote, orderView.UpdatedAt, orderView.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to update order view payment: %w", err)
+	}
+
+	err = refreshOrderDocument(ctx, tx, orderView.OrderID)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
-const baseQueryOrder = `
-    SELECT 
-        o.id,
-        o.order_id,
-        o.user_id,
-        o.status,
-        o.total_price,
-        o.payment_id,
-        o.payment_status,
-        o.payment_image_url,
-        o.payment_admin_note,
-        o.created_at,
-        o.updated_at,
-        oa.id as address_id,
-        oa.street as address_street,
-        oa.city as address_city,
-        oa.state as address_state,
-        oa.zip_code as address_zip_code,
-        oa.note as address_note,
-        oi.id as item_id,
-        oi.product_id,
-        oi.product_name,
-        oi.product_price,
-        oi.product_quantity,
-        oi.product_image_url,
-        oi.product_description,
-        oi.product_category_id,
-        oi.product_category_name,
-		oi.shipping_cost,
-        oi.note as item_note
-    FROM orders_view o
-    LEFT JOIN order_addresses_view oa ON o.id = oa.order_view_id
-    LEFT JOIN order_items_view oi ON o.id = oi.order_view_id
-    WHERE o.deleted_at IS NULL
+// the projection keeps the whole order as a jsonb document, reads are a single row each
+const baseQueryOrderDocument = `
+    SELECT ov.document
+    FROM orders_view ov
+    WHERE ov.deleted_at IS NULL
 `
 
-const queryGetOrderByID = baseQueryOrder + ` AND o.order_id = $1;`
+const queryGetOrderByID = baseQueryOrderDocument + ` AND ov.order_id = $1;`
 
 func (r *OrderPostgreQueryRepo) GetByID(ctx context.Context, id uuid.UUID) (*entity.OrderView, error) {
-	return r.scanSingleOrder(ctx, queryGetOrderByID, id)
+	return r.getSingleOrder(ctx, queryGetOrderByID, id)
 }
 
-const queryGetAllOrder = baseQueryOrder + ` ORDER BY o.created_at DESC;`
-
-func (r *OrderPostgreQueryRepo) GetAll(ctx context.Context) ([]*entity.OrderView, error) {
-	return r.scanMultipleOrders(ctx, queryGetAllOrder)
+func (r *OrderPostgreQueryRepo) GetByUserID(ctx context.Context, userID uuid.UUID, page entity.Page) ([]*entity.OrderView, entity.PageCursor, error) {
+	return r.List(ctx, entity.OrderFilter{UserID: userID}, page)
 }
 
-const queryGetOrderByUserID = baseQueryOrder + ` AND o.user_id = $1 ORDER BY o.created_at DESC;`
+const queryGetOrderByPaymentID = baseQueryOrderDocument + ` AND ov.payment_id = $1;`
 
-func (r *OrderPostgreQueryRepo) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.OrderView, error) {
-	return r.scanMultipleOrders(ctx, queryGetOrderByUserID, userID)
+func (r *OrderPostgreQueryRepo) GetByPaymentID(ctx context.Context, paymentID uuid.UUID) (*entity.OrderView, error) {
+	return r.getSingleOrder(ctx, queryGetOrderByPaymentID, paymentID)
 }
 
-const queryGetOrderByPaymentID = baseQueryOrder + ` AND o.payment_id = $1;`
+// one more order than the limit is fetched to know whether there is a next page
+const queryOrderPage = `%[1]s
+    ORDER BY ov.%[2]s %[3]s, ov.id %[3]s
+    LIMIT $%[4]d;`
 
-func (r *OrderPostgreQueryRepo) GetByPaymentID(ctx context.Context, paymentID uuid.UUID) (*entity.OrderView, error) {
-	return r.scanSingleOrder(ctx, queryGetOrderByPaymentID, paymentID)
+// List return one keyset page of the orders matching the filter
+func (r *OrderPostgreQueryRepo) List(ctx context.Context, filter entity.OrderFilter, page entity.Page) ([]*entity.OrderView, entity.PageCursor, error) {
+	column, ok := orderSortColumns[filter.SortField()]
+	if !ok {
+		return nil, entity.PageCursor{}, fmt.Errorf("invalid order sort field: %s", filter.SortBy)
+	}
+	direction, operator := "DESC", "<"
+	if filter.SortAsc {
+		direction, operator = "ASC", ">"
+	}
+
+	q := newOrderFilterQuery(filter)
+	if !page.After.IsZero() {
+		value, err := orderCursorValue(filter.SortField(), page.After)
+		if err != nil {
+			return nil, entity.PageCursor{}, err
+		}
+		q.and(fmt.Sprintf("(ov.%s, ov.id) %s (?, ?)", column, operator), value, page.After.ID)
+	}
+	q.args = append(q.args, page.Limit+1)
+	query := baseQueryOrderDocument + fmt.Sprintf(queryOrderPage, q.where.String(), column, direction, len(q.args))
+
+	orders, err := r.getMultipleOrders(ctx, query, q.args...)
+	if err != nil {
+		return nil, entity.PageCursor{}, err
+	}
+
+	var next entity.PageCursor
+	if len(orders) > page.Limit {
+		orders = orders[:page.Limit]
+		next = filter.PageCursor(orders[len(orders)-1])
+	}
+
+	return orders, next, nil
 }
 
-const queryGetOrderByStatus = baseQueryOrder + ` AND o.status = $1 ORDER BY o.created_at DESC;`
+// helper to get single order from its document
+func (r *OrderPostgreQueryRepo) getSingleOrder(ctx context.Context, query string, args ...interface{}) (*entity.OrderView, error) {
+	var document []byte
+	err := r.Conn.QueryRowContext(ctx, query, args...).Scan(&document)
+	if err != nil {
+		return nil, err
+	}
 
-func (r *OrderPostgreQueryRepo) GetByStatus(ctx context.Context, status string) ([]*entity.OrderView, error) {
-	return r.scanMultipleOrders(ctx, queryGetOrderByStatus, status)
+	return decodeOrderDocument(document)
 }
 
-// helper to scan single order
-func (r *OrderPostgreQueryRepo) scanSingleOrder(ctx context.Context, query string, args ...interface{}) (*entity.OrderView, error) {
+// helper to get the orders from their documents in the query order
+func (r *OrderPostgreQueryRepo) getMultipleOrders(ctx context.Context, query string, args ...interface{}) ([]*entity.OrderView, error) {
 	rows, err := r.Conn.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query order: %w", err)
+		return nil, fmt.Errorf("failed to query orders: %w", err)
 	}
 	defer rows.Close()
 
-	var order *entity.OrderView
-	items := make(map[uuid.UUID]entity.OrderItemView)
-
+	var orders []*entity.OrderView
 	for rows.Next() {
-		var (
-			// order fields
-			o entity.OrderView
-			// nullable fields
-			nullablePaymentID     sql.NullString
-			nullablePaymentStatus sql.NullString
-			nullablePaymentImage  sql.NullString
-			nullablePaymentNote   sql.NullString
-			// address fields
-			addressID                                 uuid.UUID
-			street, city, state, zipCode, addressNote string
-			// item fields
-			itemID, productID                   uuid.UUID
-			productName                         string
-			productPrice                        float64
-			productQuantity                     int64
-			productImageURL, productDescription string
-			productCategoryID                   uuid.UUID
-			productCategoryName                 string
-			shippingCost                        float64
-			itemNote                            string
-		)
-
-		err := rows.Scan(
-			&o.ID,
-			&o.OrderID,
-			&o.UserID,
-			&o.Status,
-			&o.TotalPrice,
-			&nullablePaymentID,
-			&nullablePaymentStatus,
-			&nullablePaymentImage,
-			&nullablePaymentNote,
-			&o.CreatedAt,
-			&o.UpdatedAt,
-			&addressID,
-			&street,
-			&city,
-			&state,
-			&zipCode,
-			&addressNote,
-			&itemID,
-			&productID,
-			&productName,
-			&productPrice,
-			&productQuantity,
-			&productImageURL,
-			&productDescription,
-			&productCategoryID,
-			&productCategoryName,
-			&shippingCost,
-			&itemNote,
-		)
-		if err != nil {
+		var document []byte
+		if err := rows.Scan(&document); err != nil {
 			return nil, fmt.Errorf("failed to scan order: %w", err)
 		}
-
-		// convert nullable fields
-		if nullablePaymentID.Valid {
-			paymentID, err := uuid.Parse(nullablePaymentID.String)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse payment ID: %w", err)
-			}
-			o.PaymentID = paymentID
-		}
-		if nullablePaymentStatus.Valid {
-			o.PaymentStatus = nullablePaymentStatus.String
-		}
-		if nullablePaymentImage.Valid {
-			o.PaymentImageURL = nullablePaymentImage.String
-		}
-		if nullablePaymentNote.Valid {
-			o.PaymentAdminNote = nullablePaymentNote.String
-		}
-
-		if order == nil {
-			order = &o
-			order.Address = entity.OrderAddressView{
-				ID:          addressID,
-				OrderViewID: order.ID,
-				Street:      street,
-				City:        city,
-				State:       state,
-				ZipCode:     zipCode,
-				Note:        addressNote,
-			}
-		}
-
-		items[itemID] = entity.OrderItemView{
-			ID:                  itemID,
-			OrderViewID:         order.ID,
-			ProductID:           productID,
-			ProductName:         productName,
-			ProductPrice:        productPrice,
-			ProductQuantity:     productQuantity,
-			ProductImageURL:     productImageURL,
-			ProductDescription:  productDescription,
-			ProductCategoryID:   productCategoryID,
-			ProductCategoryName: productCategoryName,
-			ShippingCost:        shippingCost,
-			Note:                itemNote,
+		order, err := decodeOrderDocument(document)
+		if err != nil {
+			return nil, err
 		}
+		orders = append(orders, order)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating order rows: %w", err)
 	}
 
-	if order == nil {
-		return nil, sql.ErrNoRows
+	return orders, nil
+}
+
+const queryUpdateStatusByOrderID = `UPDATE orders_view SET status = $1, updated_at = $2 WHERE order_id = $3;`
+
+func (r *OrderPostgreQueryRepo) UpdateStatus(ctx context.Context, orderView *entity.OrderView) error {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = lockOrderViewVersion(ctx, tx, orderView.OrderID, orderView.Version)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, queryUpdateStatusByOrderID, orderView.Status, orderView.UpdatedAt, orderView.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to update order view status: %w", err)
 	}
 
-	// convert items map to slice
-	order.Items = make([]entity.OrderItemView, 0, len(items))
-	for _, item := range items {
-		order.Items = append(order.Items, item)
+	err = refreshOrderDocument(ctx, tx, orderView.OrderID)
+	if err != nil {
+		return err
 	}
 
-	return order, nil
+	return tx.Commit()
 }
 
-// helper to scan multiple orders
-func (r *OrderPostgreQueryRepo) scanMultipleOrders(ctx context.Context, query string, args ...interface{}) ([]*entity.OrderView, error) {
-	rows, err := r.Conn.QueryContext(ctx, query, args...)
+const (
+	queryUpdateTotalByOrderID    = `UPDATE orders_view SET status = $1, total_price = $2, discount_amount = $3, tax_amount = $4, updated_at = $5 WHERE order_id = $6;`
+	queryUpdateItemViewByOrderID = `
+		UPDATE order_items_view SET
+			product_quantity = $1,
+			shipping_cost = $2,
+			discount = $3,
+			tax = $4,
+			updated_at = $5,
+			deleted_at = CASE WHEN $1 = 0 THEN $5 ELSE NULL END
+		WHERE product_id = $6 AND order_view_id = (SELECT id FROM orders_view WHERE order_id = $7);
+	`
+)
+
+func (r *OrderPostgreQueryRepo) UpdateItems(ctx context.Context, orderView *entity.OrderView) error {
+	tx, err := r.Conn.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query orders: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	ordersMap := make(map[uuid.UUID]*entity.OrderView)
-	itemsMap := make(map[uuid.UUID]map[uuid.UUID]entity.OrderItemView)
+	err = lockOrderViewVersion(ctx, tx, orderView.OrderID, orderView.Version)
+	if err != nil {
+		return err
+	}
 
-	for rows.Next() {
-		var (
-			// order fields
-			o entity.OrderView
-			// nullable fields
-			nullablePaymentID     sql.NullString
-			nullablePaymentStatus sql.NullString
-			nullablePaymentImage  sql.NullString
-			nullablePaymentNote   sql.NullString
-			// address fields
-			addressID                                 uuid.UUID
-			street, city, state, zipCode, addressNote string
-			// item fields
-			itemID, productID                   uuid.UUID
-			productName                         string
-			productPrice                        float64
-			productQuantity                     int64
-			productImageURL, productDescription string
-			productCategoryID                   uuid.UUID
-			productCategoryName                 string
-			shippingCost                        float64
-			itemNote                            string
-		)
+	_, err = tx.ExecContext(ctx, queryUpdateTotalByOrderID,
+		orderView.Status, orderView.TotalPrice, orderView.DiscountAmount, orderView.TaxAmount, orderView.UpdatedAt, orderView.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to update order view total: %w", err)
+	}
 
-		err := rows.Scan(
-			&o.ID,
-			&o.OrderID,
-			&o.UserID,
-			&o.Status,
-			&o.TotalPrice,
-			&nullablePaymentID,
-			&nullablePaymentStatus,
-			&nullablePaymentImage,
-			&nullablePaymentNote,
-			&o.CreatedAt,
-			&o.UpdatedAt,
-			&addressID,
-			&street,
-			&city,
-			&state,
-			&zipCode,
-			&addressNote,
-			&itemID,
-			&productID,
-			&productName,
-			&productPrice,
-			&productQuantity,
-			&productImageURL,
-			&productDescription,
-			&productCategoryID,
-			&productCategoryName,
-			&shippingCost,
-			&itemNote,
-		)
+	for _, item := range orderView.Items {
+		_, err = tx.ExecContext(ctx, queryUpdateItemViewByOrderID,
+			item.ProductQuantity, item.ShippingCost, item.Discount, item.Tax, item.UpdatedAt, item.ProductID, orderView.OrderID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan order: %w", err)
+			return fmt.Errorf("failed to update order item view: %w", err)
 		}
+	}
 
-		// convert nullable fields
-		if nullablePaymentID.Valid {
-			paymentID, err := uuid.Parse(nullablePaymentID.String)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse payment ID: %w", err)
-			}
-			o.PaymentID = paymentID
-		}
-		if nullablePaymentStatus.Valid {
-			o.PaymentStatus = nullablePaymentStatus.String
-		}
-		if nullablePaymentImage.Valid {
-			o.PaymentImageURL = nullablePaymentImage.String
-		}
-		if nullablePaymentNote.Valid {
-			o.PaymentAdminNote = nullablePaymentNote.String
-		}
+	err = refreshOrderDocument(ctx, tx, orderView.OrderID)
+	if err != nil {
+		return err
+	}
 
-		// if this is the first time we're seeing this order
-		if _, exists := ordersMap[o.ID]; !exists {
-			ordersMap[o.ID] = &o
-			ordersMap[o.ID].Address = entity.OrderAddressView{
-				ID:          addressID,
-				OrderViewID: o.ID,
-				Street:      street,
-				City:        city,
-				State:       state,
-				ZipCode:     zipCode,
-				Note:        addressNote,
-			}
-			itemsMap[o.ID] = make(map[uuid.UUID]entity.OrderItemView)
-		}
+	return tx.Commit()
+}
 
-		// add item if it exists and isn't already added
-		if _, exists := itemsMap[o.ID][itemID]; !exists {
-			itemsMap[o.ID][itemID] = entity.OrderItemView{
-				ID:                  itemID,
-				OrderViewID:         o.ID,
-				ProductID:           productID,
-				ProductName:         productName,
-				ProductPrice:        productPrice,
-				ProductQuantity:     productQuantity,
-				ProductImageURL:     productImageURL,
-				ProductDescription:  productDescription,
-				ProductCategoryID:   productCategoryID,
-				ProductCategoryName: productCategoryName,
-				ShippingCost:        shippingCost,
-				Note:                itemNote,
-			}
-		}
-	}
+const (
+	queryUpdateTotalPriceByOrderID  = `UPDATE orders_view SET total_price = $1, discount_amount = $2, tax_amount = $3, shipping_estimated_days = $4, estimated_shipping = $5, shipping_rule = $6, updated_at = $7 WHERE order_id = $8;`
+	queryUpdateAddressViewByOrderID = `
+		UPDATE order_addresses_view SET
+			street = $1,
+			city = $2,
+			state = $3,
+			zip_code = $4,
+			note = $5,
+			updated_at = $6
+		WHERE order_view_id = (SELECT id FROM orders_view WHERE order_id = $7);
+	`
+)
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating order rows: %w", err)
+// Update apply the edited items, address and total price of a pending order
+func (r *OrderPostgreQueryRepo) Update(ctx context.Context, orderView *entity.OrderView) error {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	// Convert maps to slices
-	orders := make([]*entity.OrderView, 0, len(ordersMap))
-	for orderID, order := range ordersMap {
-		items := make([]entity.OrderItemView, 0, len(itemsMap[orderID]))
-		for _, item := range itemsMap[orderID] {
-			items = append(items, item)
-		}
-		order.Items = items
-		orders = append(orders, order)
+	err = lockOrderViewVersion(ctx, tx, orderView.OrderID, orderView.Version)
+	if err != nil {
+		return err
 	}
 
-	return orders, nil
-}
+	_, err = tx.ExecContext(ctx, queryUpdateTotalPriceByOrderID,
+		orderView.TotalPrice, orderView.DiscountAmount, orderView.TaxAmount, orderView.ShippingEstimatedDays, orderView.EstimatedShipping, orderView.ShippingRule, orderView.UpdatedAt, orderView.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to update order view total: %w", err)
+	}
 
-const queryUpdateStatusByOrderID = `UPDATE orders_view SET status = $1, updated_at = $2 WHERE order_id = $3;`
+	for _, item := range orderView.Items {
+		_, err = tx.ExecContext(ctx, queryUpdateItemViewByOrderID,
+			item.ProductQuantity, item.ShippingCost, item.Discount, item.Tax, item.UpdatedAt, item.ProductID, orderView.OrderID)
+		if err != nil {
+			return fmt.Errorf("failed to update order item view: %w", err)
+		}
+	}
 
-func (r *OrderPostgreQueryRepo) UpdateStatus(ctx context.Context, orderView *entity.OrderView) error {
-	stmt, errStmt := r.Conn.PrepareContext(ctx, queryUpdateStatusByOrderID)
-	if errStmt != nil {
-		return errStmt
+	_, err = tx.ExecContext(ctx, queryUpdateAddressViewByOrderID,
+		orderView.Address.Street, orderView.Address.City, orderView.Address.State,
+		orderView.Address.ZipCode, orderView.Address.Note, orderView.Address.UpdatedAt, orderView.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to update order address view: %w", err)
 	}
-	defer stmt.Close()
 
-	_, updateErr := stmt.ExecContext(ctx, orderView.Status, orderView.UpdatedAt, orderView.OrderID)
-	if updateErr != nil {
-		return updateErr
+	err = refreshOrderDocument(ctx, tx, orderView.OrderID)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
-const queryGetProductPriceByOrderID = `
-	SELECT oiv.product_id, oiv.product_price
-	FROM orders_view ov
-	LEFT JOIN order_items_view oiv ON ov.id = oiv.order_view_id
-	WHERE ov.order_id = $1
-`
+// the rank is repeated in the where clause as the select alias is not visible there
+const queryOrderSearch = `
+		SELECT ov.document, ts_rank(ov.search, query) AS rank
+		FROM orders_view ov, websearch_to_tsquery('simple', $1) query
+		WHERE ov.deleted_at IS NULL AND ov.search @@ query%s
+		ORDER BY rank DESC, ov.id DESC
+		LIMIT $%d;
+	`
+
+// Search return one page of the orders matching the full-text query, best match first
+func (r *OrderPostgreQueryRepo) Search(ctx context.Context, text string, page entity.Page) ([]*entity.OrderView, entity.PageCursor, error) {
+	where := ""
+	args := []interface{}{text}
+	if !page.After.IsZero() {
+		rank, err := page.After.FloatValue()
+		if err != nil || page.After.Sort != entity.ORDER_SORT_RANK {
+			return nil, entity.PageCursor{}, entity.ErrInvalidPageCursor
+		}
+		args = append(args, rank, page.After.ID)
+		where = " AND (ts_rank(ov.search, query), ov.id) < ($2, $3)"
+	}
+	args = append(args, page.Limit+1)
 
-func (r *OrderPostgreQueryRepo) GetProductPriceByOrderID(ctx context.Context, orderID uuid.UUID) (map[uuid.UUID]float64, error) {
-	rows, err := r.Conn.QueryContext(ctx, queryGetProductPriceByOrderID, orderID)
+	rows, err := r.Conn.QueryContext(ctx, fmt.Sprintf(queryOrderSearch, where, len(args)), args...)
 	if err != nil {
-		return nil, err
+		return nil, entity.PageCursor{}, fmt.Errorf("failed to search orders: %w", err)
 	}
 	defer rows.Close()
 
-	items := make(map[uuid.UUID]float64)
+	var (
+		orders []*entity.OrderView
+		ranks  []float64
+	)
 	for rows.Next() {
-		var productID uuid.UUID
-		var productPrice float64
-		if err := rows.Scan(&productID, &productPrice); err != nil {
-			return nil, err
+		var (
+			document []byte
+			rank     float64
+		)
+		if err := rows.Scan(&document, &rank); err != nil {
+			return nil, entity.PageCursor{}, fmt.Errorf("failed to scan order: %w", err)
+		}
+		order, err := decodeOrderDocument(document)
+		if err != nil {
+			return nil, entity.PageCursor{}, err
 		}
-		items[productID] = productPrice
+		orders = append(orders, order)
+		ranks = append(ranks, rank)
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+	if err = rows.Err(); err != nil {
+		return nil, entity.PageCursor{}, fmt.Errorf("error iterating order rows: %w", err)
+	}
+
+	var next entity.PageCursor
+	if len(orders) > page.Limit {
+		orders = orders[:page.Limit]
+		last := len(orders) - 1
+		next = entity.NewFloatPageCursor(entity.ORDER_SORT_RANK, ranks[last], orders[last].ID)
 	}
 
-	return items, nil
+	return orders, next, nil
 }
@@ -0,0 +1,255 @@
+package queryrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
+	"github.com/idoyudha/eshop-order/pkg/postgresql/postgrequery"
+	_ "github.com/lib/pq"
+)
+
+// the benchmarks need a throwaway postgres, they create their own schema in it and drop it afterwards
+const benchQueryDBEnv = "BENCH_PG_QUERY_URL"
+
+const (
+	benchOrderCount    = 5000
+	benchUserCount     = 50
+	benchItemsPerOrder = 3
+	benchPageLimit     = 20
+)
+
+var (
+	benchStatuses = []string{entity.ORDER_PENDING, entity.ORDER_ON_DELIVERY, entity.ORDER_DELIVERED, entity.ORDER_CANCELLED}
+	benchStates   = []string{"Jawa Barat", "Jawa Timur", "Bali", "DKI Jakarta"}
+	benchCities   = []string{"Bandung", "Surabaya", "Denpasar", "Jakarta"}
+)
+
+// seeded dataset shared by every benchmark in the package
+type benchOrderDataset struct {
+	repo      *OrderPostgreQueryRepo
+	userID    uuid.UUID // user with the most orders
+	productID uuid.UUID // product in about a third of the orders
+}
+
+func BenchmarkOrderList(b *testing.B) {
+	ds := newBenchOrderDataset(b)
+
+	b.Run("created_at", func(b *testing.B) {
+		benchmarkOrderList(b, ds.repo, entity.OrderFilter{})
+	})
+	b.Run("total_price_asc", func(b *testing.B) {
+		benchmarkOrderList(b, ds.repo, entity.OrderFilter{SortBy: entity.ORDER_SORT_TOTAL_PRICE, SortAsc: true})
+	})
+	b.Run("user", func(b *testing.B) {
+		benchmarkOrderList(b, ds.repo, entity.OrderFilter{UserID: ds.userID})
+	})
+	b.Run("status_and_city", func(b *testing.B) {
+		benchmarkOrderList(b, ds.repo, entity.OrderFilter{Statuses: []string{entity.ORDER_DELIVERED}, City: "bandung"})
+	})
+	b.Run("price_range", func(b *testing.B) {
+		benchmarkOrderList(b, ds.repo, entity.OrderFilter{MinTotalPrice: 100_000, MaxTotalPrice: 250_000, SortBy: entity.ORDER_SORT_UPDATED_AT})
+	})
+	b.Run("product", func(b *testing.B) {
+		benchmarkOrderList(b, ds.repo, entity.OrderFilter{ProductID: ds.productID})
+	})
+}
+
+// BenchmarkOrderListDeepPage walk the pages until the end, keyset pages should not get slower the deeper they are
+func BenchmarkOrderListDeepPage(b *testing.B) {
+	ds := newBenchOrderDataset(b)
+	ctx := context.Background()
+	filter := entity.OrderFilter{}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		page := entity.Page{Limit: benchPageLimit}
+		for {
+			_, next, err := ds.repo.List(ctx, filter, page)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if next.IsZero() {
+				break
+			}
+			page.After = next
+		}
+	}
+}
+
+// benchmark the first page and the page after it, the second one goes through the cursor predicate
+func benchmarkOrderList(b *testing.B, repo *OrderPostgreQueryRepo, filter entity.OrderFilter) {
+	ctx := context.Background()
+	_, next, err := repo.List(ctx, filter, entity.Page{Limit: benchPageLimit})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := repo.List(ctx, filter, entity.Page{Limit: benchPageLimit}); err != nil {
+			b.Fatal(err)
+		}
+		if next.IsZero() {
+			continue
+		}
+		if _, _, err := repo.List(ctx, filter, entity.Page{Limit: benchPageLimit, After: next}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// newBenchOrderDataset migrate a fresh schema and seed it, the schema is dropped when the benchmark ends
+func newBenchOrderDataset(b *testing.B) *benchOrderDataset {
+	b.Helper()
+
+	dsn := os.Getenv(benchQueryDBEnv)
+	if dsn == "" {
+		b.Skipf("%s is not set", benchQueryDBEnv)
+	}
+
+	schema := fmt.Sprintf("bench_order_%d", time.Now().UnixNano())
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { admin.Close() })
+	if _, err := admin.Exec(fmt.Sprintf(`CREATE SCHEMA %s;`, schema)); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		if _, err := admin.Exec(fmt.Sprintf(`DROP SCHEMA %s CASCADE;`, schema)); err != nil {
+			b.Error(err)
+		}
+	})
+
+	conn, err := sql.Open("postgres", withSearchPath(b, dsn, schema))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { conn.Close() })
+
+	migrateBenchSchema(b, conn)
+
+	ds := &benchOrderDataset{
+		repo:      NewOrderPostgreQueryRepo(&postgrequery.PostgresQuery{Conn: conn}),
+		userID:    uuid.New(),
+		productID: uuid.New(),
+	}
+	seedOrderViews(b, ds)
+
+	if _, err := conn.Exec(`ANALYZE;`); err != nil {
+		b.Fatal(err)
+	}
+
+	return ds
+}
+
+// every connection of the pool use the benchmark schema
+func withSearchPath(b *testing.B, dsn, schema string) string {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		b.Fatal(err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// run the query database migrations in their version order
+func migrateBenchSchema(b *testing.B, conn *sql.DB) {
+	files, err := filepath.Glob(filepath.Join("..", "..", "..", "migrations", "queries", "*.up.sql"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	if len(files) == 0 {
+		b.Fatal("no query migrations found")
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := conn.Exec(string(migration)); err != nil {
+			b.Fatalf("failed to run migration %s: %v", filepath.Base(file), err)
+		}
+	}
+}
+
+// seedOrderViews insert the orders through the projection, so the documents are built the same way as in production
+func seedOrderViews(b *testing.B, ds *benchOrderDataset) {
+	b.Helper()
+	ctx := context.Background()
+
+	users := make([]uuid.UUID, benchUserCount)
+	users[0] = ds.userID
+	for i := 1; i < len(users); i++ {
+		users[i] = uuid.New()
+	}
+	products := make([]uuid.UUID, 10)
+	products[0] = ds.productID
+	for i := 1; i < len(products); i++ {
+		products[i] = uuid.New()
+	}
+
+	start := time.Now().Add(-180 * 24 * time.Hour)
+	for i := 0; i < benchOrderCount; i++ {
+		createdAt := start.Add(time.Duration(i) * time.Hour)
+		order := &entity.OrderView{
+			ID:             uuid.New(),
+			OrderID:        uuid.New(),
+			UserID:         users[i%benchUserCount],
+			Status:         benchStatuses[i%len(benchStatuses)],
+			ShippingMethod: entity.SHIPPING_STANDARD,
+			FulfilmentType: entity.FULFILMENT_DELIVERY,
+			Version:        1,
+			CreatedAt:      createdAt,
+			UpdatedAt:      createdAt.Add(time.Duration(i%48) * time.Hour),
+		}
+		// the first user places a fifth of the orders
+		if i%5 == 0 {
+			order.UserID = ds.userID
+		}
+
+		for j := 0; j < benchItemsPerOrder; j++ {
+			price := float64(10_000 + (i*7+j*13)%200*1_000)
+			order.Items = append(order.Items, entity.OrderItemView{
+				ID:                 uuid.New(),
+				OrderViewID:        order.ID,
+				ProductID:          products[(i+j)%len(products)],
+				ProductName:        fmt.Sprintf("product %d", (i+j)%len(products)),
+				ProductPrice:       price,
+				ProductQuantity:    int64(1 + j),
+				ProductDescription: "seeded product",
+				CreatedAt:          createdAt,
+				UpdatedAt:          createdAt,
+			})
+			order.TotalPrice += price * float64(1+j)
+		}
+		order.Address = entity.OrderAddressView{
+			ID:          uuid.New(),
+			OrderViewID: order.ID,
+			Street:      fmt.Sprintf("Jalan %d", i),
+			City:        benchCities[i%len(benchCities)],
+			State:       benchStates[i%len(benchStates)],
+			ZipCode:     "40111",
+			CreatedAt:   createdAt,
+			UpdatedAt:   createdAt,
+		}
+
+		if err := ds.repo.Insert(ctx, order); err != nil {
+			b.Fatalf("failed to seed order %d: %v", i, err)
+		}
+	}
+}
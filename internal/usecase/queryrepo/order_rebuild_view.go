@@ -0,0 +1,264 @@
+package queryrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
+	"github.com/idoyudha/eshop-order/pkg/postgresql/postgrequery"
+	"github.com/lib/pq"
+)
+
+// OrderRebuildQueryRepo write the order views rebuilt from the command tables,
+// either over the live tables or into shadow tables that are swapped in at the end
+type OrderRebuildQueryRepo struct {
+	*postgrequery.PostgresQuery
+}
+
+func NewOrderRebuildQueryRepo(conn *postgrequery.PostgresQuery) *OrderRebuildQueryRepo {
+	return &OrderRebuildQueryRepo{
+		PostgresQuery: conn,
+	}
+}
+
+// shadow tables copy the columns, defaults and indexes of the live tables
+const queryPrepareShadowTables = `
+	DROP TABLE IF EXISTS order_items_view_shadow, order_addresses_view_shadow, orders_view_shadow;
+	CREATE TABLE orders_view_shadow (LIKE orders_view INCLUDING ALL);
+	CREATE TABLE order_items_view_shadow (LIKE order_items_view INCLUDING ALL);
+	CREATE TABLE order_addresses_view_shadow (LIKE order_addresses_view INCLUDING ALL);
+	ALTER TABLE order_items_view_shadow ADD FOREIGN KEY (order_view_id) REFERENCES orders_view_shadow (id);
+	ALTER TABLE order_addresses_view_shadow ADD FOREIGN KEY (order_view_id) REFERENCES orders_view_shadow (id);
+`
+
+// PrepareShadow create empty shadow tables, the ones left by an unfinished rebuild are dropped
+func (r *OrderRebuildQueryRepo) PrepareShadow(ctx context.Context) error {
+	_, err := r.Conn.ExecContext(ctx, queryPrepareShadowTables)
+	if err != nil {
+		return fmt.Errorf("failed to prepare shadow tables: %w", err)
+	}
+
+	return nil
+}
+
+const queryGetLiveOrderDocuments = `SELECT ov.document FROM orders_view ov WHERE ov.order_id = ANY($1);`
+
+// GetByOrderIDs return the live order views of the orders, keyed by order id,
+// the rebuild keeps what only the projection knows, like product names and payment proof
+func (r *OrderRebuildQueryRepo) GetByOrderIDs(ctx context.Context, orderIDs []uuid.UUID) (map[uuid.UUID]*entity.OrderView, error) {
+	rows, err := r.Conn.QueryContext(ctx, queryGetLiveOrderDocuments, pq.Array(orderIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order views: %w", err)
+	}
+	defer rows.Close()
+
+	orders := make(map[uuid.UUID]*entity.OrderView)
+	for rows.Next() {
+		var document []byte
+		if err := rows.Scan(&document); err != nil {
+			return nil, fmt.Errorf("failed to scan order view: %w", err)
+		}
+		order, err := decodeOrderDocument(document)
+		if err != nil {
+			return nil, err
+		}
+		// document was never refreshed
+		if order.OrderID == uuid.Nil {
+			continue
+		}
+		orders[order.OrderID] = order
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating order view rows: %w", err)
+	}
+
+	return orders, nil
+}
+
+const (
+	queryDeleteRebuiltItems     = `DELETE FROM order_items_view WHERE order_view_id IN (SELECT id FROM orders_view WHERE order_id = $1);`
+	queryDeleteRebuiltAddresses = `DELETE FROM order_addresses_view WHERE order_view_id IN (SELECT id FROM orders_view WHERE order_id = $1);`
+	queryDeleteRebuiltOrder     = `DELETE FROM orders_view WHERE order_id = $1;`
+
+	queryInsertRebuiltOrder   = `INSERT INTO %[1]s (id, order_id, user_id, status, total_price, promotion_code, discount_amount, tax_amount, shipping_method, shipping_estimated_days, estimated_shipping, shipping_rule, fulfilment_type, pickup_warehouse_id, pickup_code, payment_id, payment_status, payment_image_url, payment_admin_note, version, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22);`
+	queryInsertRebuiltItem    = `INSERT INTO %[2]s (id, order_view_id, product_id, product_name, product_price, product_quantity, product_image_url, product_description, product_category_id, product_category_name, shipping_cost, discount, tax, note, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16);`
+	queryInsertRebuiltAddress = `INSERT INTO %[3]s (id, order_view_id, street, city, state, zip_code, note, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);`
+)
+
+// WriteBatch save one batch of rebuilt order views in a transaction and return how many were skipped,
+// on the live tables an order view the projection moved past the rebuilt version is kept as it is
+func (r *OrderRebuildQueryRepo) WriteBatch(ctx context.Context, shadow bool, orders []*entity.OrderView) (int, error) {
+	tables := liveOrderViewTables
+	if shadow {
+		tables = shadowOrderViewTables
+	}
+
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	skipped := 0
+	for _, order := range orders {
+		if !shadow {
+			replace, err := replaceLiveOrderView(ctx, tx, order)
+			if err != nil {
+				return 0, err
+			}
+			if !replace {
+				skipped++
+				continue
+			}
+		}
+
+		err = insertRebuiltOrderView(ctx, tx, tables, order)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return skipped, nil
+}
+
+// replaceLiveOrderView delete the live order view unless the projection already has a later version
+func replaceLiveOrderView(ctx context.Context, tx *sql.Tx, order *entity.OrderView) (bool, error) {
+	var current int
+	err := tx.QueryRowContext(ctx, queryLockOrderViewVersion, order.OrderID).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock order view: %w", err)
+	}
+	if current > order.Version {
+		return false, nil
+	}
+
+	for _, query := range []string{queryDeleteRebuiltItems, queryDeleteRebuiltAddresses, queryDeleteRebuiltOrder} {
+		_, err = tx.ExecContext(ctx, query, order.OrderID)
+		if err != nil {
+			return false, fmt.Errorf("failed to delete order view: %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+func insertRebuiltOrderView(ctx context.Context, tx *sql.Tx, tables orderViewTables, order *entity.OrderView) error {
+	_, err := tx.ExecContext(ctx, tables.format(queryInsertRebuiltOrder),
+		order.ID, order.OrderID, order.UserID, order.Status, order.TotalPrice,
+		sql.NullString{String: order.PromotionCode, Valid: order.PromotionCode != ""}, order.DiscountAmount,
+		order.TaxAmount, order.ShippingMethod, order.ShippingEstimatedDays, order.EstimatedShipping, order.ShippingRule,
+		order.FulfilmentType, uuid.NullUUID{UUID: order.PickupWarehouseID, Valid: order.PickupWarehouseID != uuid.Nil}, order.PickupCode,
+		uuid.NullUUID{UUID: order.PaymentID, Valid: order.PaymentID != uuid.Nil},
+		sql.NullString{String: order.PaymentStatus, Valid: order.PaymentStatus != ""},
+		sql.NullString{String: order.PaymentImageURL, Valid: order.PaymentImageURL != ""},
+		sql.NullString{String: order.PaymentAdminNote, Valid: order.PaymentAdminNote != ""},
+		order.Version, order.CreatedAt, order.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert rebuilt order view: %w", err)
+	}
+
+	for _, item := range order.Items {
+		_, err = tx.ExecContext(ctx, tables.format(queryInsertRebuiltItem),
+			item.ID, order.ID,
+			item.ProductID, item.ProductName, item.ProductPrice,
+			item.ProductQuantity, item.ProductImageURL, item.ProductDescription,
+			uuid.NullUUID{UUID: item.ProductCategoryID, Valid: item.ProductCategoryID != uuid.Nil}, item.ProductCategoryName,
+			item.ShippingCost, item.Discount, item.Tax, item.Note, item.CreatedAt, item.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert rebuilt order item view: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, tables.format(queryInsertRebuiltAddress),
+		order.Address.ID, order.ID, order.Address.Street,
+		order.Address.City, order.Address.State, order.Address.ZipCode,
+		order.Address.Note, order.Address.CreatedAt, order.Address.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert rebuilt order address view: %w", err)
+	}
+
+	return refreshOrderDocumentIn(ctx, tx, tables, order.OrderID)
+}
+
+// the live tables are locked against projection writes while the swap runs, reads go on.
+// orders the projection changed or created while the shadow was built are copied over first,
+// the live row wins when its version is ahead of the rebuilt one
+const (
+	queryLockLiveOrderViews   = `LOCK TABLE orders_view, order_items_view, order_addresses_view IN EXCLUSIVE MODE;`
+	queryCreateRebuildChanged = `CREATE TEMP TABLE rebuild_changed (order_id uuid PRIMARY KEY) ON COMMIT DROP;`
+	queryInsertRebuildChanged = `
+		INSERT INTO rebuild_changed
+		SELECT l.order_id FROM orders_view l
+		LEFT JOIN orders_view_shadow s ON s.order_id = l.order_id
+		WHERE l.version > s.version OR (s.id IS NULL AND l.created_at >= $1);
+	`
+)
+
+var querySwapShadowTables = []string{
+	`DELETE FROM order_items_view_shadow WHERE order_view_id IN (
+		SELECT s.id FROM orders_view_shadow s JOIN rebuild_changed c ON c.order_id = s.order_id);`,
+	`DELETE FROM order_addresses_view_shadow WHERE order_view_id IN (
+		SELECT s.id FROM orders_view_shadow s JOIN rebuild_changed c ON c.order_id = s.order_id);`,
+	`DELETE FROM orders_view_shadow WHERE order_id IN (SELECT order_id FROM rebuild_changed);`,
+	`INSERT INTO orders_view_shadow SELECT l.* FROM orders_view l JOIN rebuild_changed c ON c.order_id = l.order_id;`,
+	`INSERT INTO order_items_view_shadow SELECT i.* FROM order_items_view i
+		JOIN orders_view l ON l.id = i.order_view_id JOIN rebuild_changed c ON c.order_id = l.order_id;`,
+	`INSERT INTO order_addresses_view_shadow SELECT a.* FROM order_addresses_view a
+		JOIN orders_view l ON l.id = a.order_view_id JOIN rebuild_changed c ON c.order_id = l.order_id;`,
+	`ALTER TABLE orders_view RENAME TO orders_view_old;`,
+	`ALTER TABLE order_items_view RENAME TO order_items_view_old;`,
+	`ALTER TABLE order_addresses_view RENAME TO order_addresses_view_old;`,
+	`ALTER TABLE orders_view_shadow RENAME TO orders_view;`,
+	`ALTER TABLE order_items_view_shadow RENAME TO order_items_view;`,
+	`ALTER TABLE order_addresses_view_shadow RENAME TO order_addresses_view;`,
+	`DROP TABLE order_items_view_old, order_addresses_view_old, orders_view_old;`,
+}
+
+// SwapShadow replace the live tables with the shadow tables in one transaction,
+// order views created after the rebuild started are carried over from the live tables
+func (r *OrderRebuildQueryRepo) SwapShadow(ctx context.Context, startedAt time.Time) error {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, queryLockLiveOrderViews)
+	if err != nil {
+		return fmt.Errorf("failed to lock order views: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, queryCreateRebuildChanged)
+	if err != nil {
+		return fmt.Errorf("failed to create rebuild changes table: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, queryInsertRebuildChanged, startedAt)
+	if err != nil {
+		return fmt.Errorf("failed to find order views changed during rebuild: %w", err)
+	}
+
+	for _, query := range querySwapShadowTables {
+		_, err = tx.ExecContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to swap shadow tables: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package queryrepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const queryGetOrderViewIDsBetween = `
+	SELECT ov.order_id FROM orders_view ov
+	WHERE ov.order_id > $1 AND ($2::uuid IS NULL OR ov.order_id <= $2) AND ov.created_at < $3
+	ORDER BY ov.order_id;
+`
+
+// GetOrderIDsBetween return the order ids of the order views after one order id up to another,
+// with no upper bound when last is nil, views newer than before are left out as their order may not be read yet
+func (r *OrderRebuildQueryRepo) GetOrderIDsBetween(ctx context.Context, after, last uuid.UUID, before time.Time) ([]uuid.UUID, error) {
+	rows, err := r.Conn.QueryContext(ctx, queryGetOrderViewIDsBetween,
+		after, uuid.NullUUID{UUID: last, Valid: last != uuid.Nil}, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order view ids: %w", err)
+	}
+	defer rows.Close()
+
+	var orderIDs []uuid.UUID
+	for rows.Next() {
+		var orderID uuid.UUID
+		if err := rows.Scan(&orderID); err != nil {
+			return nil, fmt.Errorf("failed to scan order view id: %w", err)
+		}
+		orderIDs = append(orderIDs, orderID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating order view id rows: %w", err)
+	}
+
+	return orderIDs, nil
+}
+
+// DeleteByOrderID remove the order view of an order that does not exist on the command side
+func (r *OrderRebuildQueryRepo) DeleteByOrderID(ctx context.Context, orderID uuid.UUID) error {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{queryDeleteRebuiltItems, queryDeleteRebuiltAddresses, queryDeleteRebuiltOrder} {
+		_, err = tx.ExecContext(ctx, query, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to delete order view: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package queryrepo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
+	rClient "github.com/idoyudha/eshop-order/pkg/redis"
+	"github.com/redis/go-redis/v9"
+)
+
+const orderViewKey = "order-view"
+
+// OrderRedisQueryRepo cache the order view read by id, the projection deletes it whenever the view changes
+type OrderRedisQueryRepo struct {
+	*rClient.RedisClient
+}
+
+func NewOrderRedisQueryRepo(client *rClient.RedisClient) *OrderRedisQueryRepo {
+	return &OrderRedisQueryRepo{
+		client,
+	}
+}
+
+func getOrderViewKey(orderID uuid.UUID) string {
+	return fmt.Sprintf("%s:%s", orderViewKey, orderID.String())
+}
+
+// Get return the cached order view, nil when it is not cached
+func (r *OrderRedisQueryRepo) Get(ctx context.Context, orderID uuid.UUID) (*entity.OrderView, error) {
+	value, err := r.RedisClient.Client.Get(ctx, getOrderViewKey(orderID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var order entity.OrderView
+	if err := json.Unmarshal(value, &order); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached order view: %w", err)
+	}
+
+	return &order, nil
+}
+
+// setOrderViewIfNotNewer keep the cached view when it has a higher version than the one being written
+var setOrderViewIfNotNewer = redis.NewScript(`
+local cached = redis.call('GET', KEYS[1])
+if cached and cjson.decode(cached).Version > tonumber(ARGV[2]) then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+// Set cache the order view read from the database, unless a newer version of it is cached already
+func (r *OrderRedisQueryRepo) Set(ctx context.Context, order *entity.OrderView, ttl time.Duration) error {
+	value, err := json.Marshal(order)
+	if err != nil {
+		return fmt.Errorf("failed to marshal order view: %w", err)
+	}
+
+	keys := []string{getOrderViewKey(order.OrderID)}
+	return setOrderViewIfNotNewer.Run(ctx, r.RedisClient.Client, keys, value, order.Version, ttl.Milliseconds()).Err()
+}
+
+func (r *OrderRedisQueryRepo) Delete(ctx context.Context, orderID uuid.UUID) error {
+	return r.RedisClient.Client.Del(ctx, getOrderViewKey(orderID)).Err()
+}
+
+// keys scanned and deleted at a time when the whole cache is dropped
+const orderViewScanCount = 500
+
+// DeleteAll drop every cached order view, used when the order view tables are replaced
+func (r *OrderRedisQueryRepo) DeleteAll(ctx context.Context) error {
+	iter := r.RedisClient.Client.Scan(ctx, 0, orderViewKey+":*", orderViewScanCount).Iterator()
+	keys := make([]string, 0, orderViewScanCount)
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) < orderViewScanCount {
+			continue
+		}
+		if err := r.RedisClient.Client.Del(ctx, keys...).Err(); err != nil {
+			return err
+		}
+		keys = keys[:0]
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return r.RedisClient.Client.Del(ctx, keys...).Err()
+}
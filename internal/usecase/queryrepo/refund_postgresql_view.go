@@ -0,0 +1,162 @@
+package queryrepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
+	"github.com/idoyudha/eshop-order/pkg/postgresql/postgrequery"
+)
+
+type RefundPostgreQueryRepo struct {
+	*postgrequery.PostgresQuery
+}
+
+func NewRefundPostgreQueryRepo(conn *postgrequery.PostgresQuery) *RefundPostgreQueryRepo {
+	return &RefundPostgreQueryRepo{
+		PostgresQuery: conn,
+	}
+}
+
+const (
+	queryInsertRefundView      = `INSERT INTO refunds_view (id, refund_id, order_id, user_id, return_id, amount, reason, status, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);`
+	queryInsertRefundItemsView = `INSERT INTO refund_items_view (id, refund_view_id, product_id, product_quantity, price, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7);`
+)
+
+func (r *RefundPostgreQueryRepo) Insert(ctx context.Context, refund *entity.RefundView) error {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	returnID := uuid.NullUUID{UUID: refund.ReturnID, Valid: refund.ReturnID != uuid.Nil}
+
+	// refund
+	_, err = tx.ExecContext(ctx, queryInsertRefundView,
+		refund.ID, refund.RefundID, refund.OrderID, refund.UserID, returnID,
+		refund.Amount, refund.Reason, refund.Status, refund.CreatedAt, refund.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert refund view: %w", err)
+	}
+
+	// refund items
+	for _, item := range refund.Items {
+		_, err = tx.ExecContext(ctx, queryInsertRefundItemsView,
+			item.ID, refund.ID, item.ProductID, item.ProductQuantity, item.Price,
+			item.CreatedAt, item.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert refund item view: %w", err)
+		}
+	}
+
+	err = refreshOrderDocument(ctx, tx, refund.OrderID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// the page is cut on the refunds before the items are joined, so a refund is never split across pages
+const queryGetRefundsByOrderID = `
+	SELECT
+		r.id,
+		r.refund_id,
+		r.order_id,
+		r.user_id,
+		r.return_id,
+		r.amount,
+		r.reason,
+		r.status,
+		r.created_at,
+		r.updated_at,
+		ri.id as item_id,
+		ri.product_id,
+		ri.product_quantity,
+		ri.price
+	FROM (
+		SELECT * FROM refunds_view
+		WHERE order_id = $1 AND deleted_at IS NULL
+			AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4
+	) r
+	LEFT JOIN refund_items_view ri ON r.id = ri.refund_view_id
+	ORDER BY r.created_at DESC, r.id DESC, ri.id;
+`
+
+// GetByOrderID return one keyset page of the refunds of the order, one more refund than the limit
+// is fetched to know whether there is a next page
+func (r *RefundPostgreQueryRepo) GetByOrderID(ctx context.Context, orderID uuid.UUID, page entity.Page) ([]*entity.RefundView, entity.PageCursor, error) {
+	var afterCreatedAt *time.Time
+	if !page.After.IsZero() {
+		createdAt, err := page.After.TimeValue()
+		if err != nil || page.After.Sort != entity.PAGE_SORT_CREATED_AT {
+			return nil, entity.PageCursor{}, entity.ErrInvalidPageCursor
+		}
+		afterCreatedAt = &createdAt
+	}
+
+	rows, err := r.Conn.QueryContext(ctx, queryGetRefundsByOrderID, orderID, afterCreatedAt, page.After.ID, page.Limit+1)
+	if err != nil {
+		return nil, entity.PageCursor{}, fmt.Errorf("failed to query refunds: %w", err)
+	}
+	defer rows.Close()
+
+	// keep the query order, rows of the same refund are adjacent
+	var refunds []*entity.RefundView
+	for rows.Next() {
+		var (
+			refund          entity.RefundView
+			returnID        uuid.NullUUID
+			itemID          uuid.NullUUID
+			productID       uuid.NullUUID
+			productQuantity *int64
+			price           *float64
+		)
+		err := rows.Scan(
+			&refund.ID, &refund.RefundID, &refund.OrderID, &refund.UserID, &returnID,
+			&refund.Amount, &refund.Reason, &refund.Status, &refund.CreatedAt, &refund.UpdatedAt,
+			&itemID, &productID, &productQuantity, &price,
+		)
+		if err != nil {
+			return nil, entity.PageCursor{}, fmt.Errorf("failed to scan refund: %w", err)
+		}
+		if returnID.Valid {
+			refund.ReturnID = returnID.UUID
+		}
+
+		if len(refunds) == 0 || refunds[len(refunds)-1].ID != refund.ID {
+			refunds = append(refunds, &refund)
+		}
+
+		// refund of an amount only has no items
+		if !itemID.Valid {
+			continue
+		}
+		last := refunds[len(refunds)-1]
+		last.Items = append(last.Items, entity.RefundItemView{
+			ID:              itemID.UUID,
+			RefundViewID:    last.ID,
+			ProductID:       productID.UUID,
+			ProductQuantity: *productQuantity,
+			Price:           *price,
+		})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, entity.PageCursor{}, fmt.Errorf("error iterating refund rows: %w", err)
+	}
+
+	var next entity.PageCursor
+	if len(refunds) > page.Limit {
+		refunds = refunds[:page.Limit]
+		last := refunds[len(refunds)-1]
+		next = entity.NewTimePageCursor(entity.PAGE_SORT_CREATED_AT, last.CreatedAt, last.ID)
+	}
+
+	return refunds, next, nil
+}
@@ -0,0 +1,174 @@
+package queryrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
+	"github.com/idoyudha/eshop-order/pkg/postgresql/postgrequery"
+)
+
+type ReturnPostgreQueryRepo struct {
+	*postgrequery.PostgresQuery
+}
+
+func NewReturnPostgreQueryRepo(conn *postgrequery.PostgresQuery) *ReturnPostgreQueryRepo {
+	return &ReturnPostgreQueryRepo{
+		PostgresQuery: conn,
+	}
+}
+
+const (
+	queryInsertOrderReturnView      = `INSERT INTO order_returns_view (id, return_id, order_id, user_id, status, reason, refund_amount, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);`
+	queryInsertOrderReturnItemsView = `INSERT INTO order_return_items_view (id, return_view_id, product_id, product_quantity, price, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7);`
+)
+
+func (r *ReturnPostgreQueryRepo) Insert(ctx context.Context, orderReturn *entity.OrderReturnView) error {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// return
+	_, err = tx.ExecContext(ctx, queryInsertOrderReturnView,
+		orderReturn.ID, orderReturn.ReturnID, orderReturn.OrderID, orderReturn.UserID,
+		orderReturn.Status, orderReturn.Reason, orderReturn.RefundAmount,
+		orderReturn.CreatedAt, orderReturn.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert order return view: %w", err)
+	}
+
+	// return items
+	for _, item := range orderReturn.Items {
+		_, err = tx.ExecContext(ctx, queryInsertOrderReturnItemsView,
+			item.ID, orderReturn.ID, item.ProductID, item.ProductQuantity, item.Price,
+			item.CreatedAt, item.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert order return item view: %w", err)
+		}
+	}
+
+	err = refreshOrderDocument(ctx, tx, orderReturn.OrderID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+const queryUpdateStatusByReturnID = `UPDATE order_returns_view SET status = $1, admin_note = $2, updated_at = $3 WHERE return_id = $4 RETURNING order_id;`
+
+func (r *ReturnPostgreQueryRepo) UpdateStatus(ctx context.Context, orderReturn *entity.OrderReturnView) error {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// status event only has the return id
+	var orderID uuid.UUID
+	err = tx.QueryRowContext(ctx, queryUpdateStatusByReturnID,
+		orderReturn.Status, orderReturn.AdminNote, orderReturn.UpdatedAt, orderReturn.ReturnID).Scan(&orderID)
+	if err != nil {
+		return fmt.Errorf("failed to update order return view status: %w", err)
+	}
+
+	err = refreshOrderDocument(ctx, tx, orderID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// the page is cut on the returns before the items are joined, so a return is never split across pages
+const queryGetOrderReturnsByOrderID = `
+	SELECT
+		r.id,
+		r.return_id,
+		r.order_id,
+		r.user_id,
+		r.status,
+		r.reason,
+		r.admin_note,
+		r.refund_amount,
+		r.created_at,
+		r.updated_at,
+		ri.id as item_id,
+		ri.product_id,
+		ri.product_quantity,
+		ri.price
+	FROM (
+		SELECT * FROM order_returns_view
+		WHERE order_id = $1 AND deleted_at IS NULL
+			AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4
+	) r
+	LEFT JOIN order_return_items_view ri ON r.id = ri.return_view_id
+	ORDER BY r.created_at DESC, r.id DESC, ri.id;
+`
+
+// GetByOrderID return one keyset page of the returns of the order, one more return than the limit
+// is fetched to know whether there is a next page
+func (r *ReturnPostgreQueryRepo) GetByOrderID(ctx context.Context, orderID uuid.UUID, page entity.Page) ([]*entity.OrderReturnView, entity.PageCursor, error) {
+	var afterCreatedAt *time.Time
+	if !page.After.IsZero() {
+		createdAt, err := page.After.TimeValue()
+		if err != nil || page.After.Sort != entity.PAGE_SORT_CREATED_AT {
+			return nil, entity.PageCursor{}, entity.ErrInvalidPageCursor
+		}
+		afterCreatedAt = &createdAt
+	}
+
+	rows, err := r.Conn.QueryContext(ctx, queryGetOrderReturnsByOrderID, orderID, afterCreatedAt, page.After.ID, page.Limit+1)
+	if err != nil {
+		return nil, entity.PageCursor{}, fmt.Errorf("failed to query order returns: %w", err)
+	}
+	defer rows.Close()
+
+	// keep the query order, rows of the same return are adjacent
+	var returns []*entity.OrderReturnView
+	for rows.Next() {
+		var (
+			ret          entity.OrderReturnView
+			nullableNote sql.NullString
+			item         entity.OrderReturnItemView
+		)
+		err := rows.Scan(
+			&ret.ID, &ret.ReturnID, &ret.OrderID, &ret.UserID, &ret.Status, &ret.Reason,
+			&nullableNote, &ret.RefundAmount, &ret.CreatedAt, &ret.UpdatedAt,
+			&item.ID, &item.ProductID, &item.ProductQuantity, &item.Price,
+		)
+		if err != nil {
+			return nil, entity.PageCursor{}, fmt.Errorf("failed to scan order return: %w", err)
+		}
+		if nullableNote.Valid {
+			ret.AdminNote = nullableNote.String
+		}
+
+		if len(returns) == 0 || returns[len(returns)-1].ID != ret.ID {
+			returns = append(returns, &ret)
+		}
+		last := returns[len(returns)-1]
+		item.ReturnViewID = last.ID
+		last.Items = append(last.Items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, entity.PageCursor{}, fmt.Errorf("error iterating order return rows: %w", err)
+	}
+
+	var next entity.PageCursor
+	if len(returns) > page.Limit {
+		returns = returns[:page.Limit]
+		last := returns[len(returns)-1]
+		next = entity.NewTimePageCursor(entity.PAGE_SORT_CREATED_AT, last.CreatedAt, last.ID)
+	}
+
+	return returns, next, nil
+}
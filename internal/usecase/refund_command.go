@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/constant"
+	"github.com/idoyudha/eshop-order/internal/dto"
+	"github.com/idoyudha/eshop-order/internal/entity"
+	"github.com/idoyudha/eshop-order/pkg/kafka"
+)
+
+type RefundCommandUseCase struct {
+	repoPostgresCommand      RefundPostgreCommandRepo
+	repoOrderPostgresCommand OrderPostgreCommandRepo
+	producer                 *kafka.ProducerServer
+}
+
+func NewRefundCommandUseCase(
+	repoPostgresCommand RefundPostgreCommandRepo,
+	repoOrderPostgresCommand OrderPostgreCommandRepo,
+	producer *kafka.ProducerServer,
+) *RefundCommandUseCase {
+	return &RefundCommandUseCase{
+		repoPostgresCommand,
+		repoOrderPostgresCommand,
+		producer,
+	}
+}
+
+// IssueRefund create a full or partial refund of a paid order.
+// with items, the amount is the price of the items. without items and amount, everything left is refunded.
+// the total refunded amount can not exceed what was paid, the insert checks it again with the order locked
+// so concurrent refunds of the same order can not refund more together.
+func (u *RefundCommandUseCase) IssueRefund(ctx context.Context, refund *entity.Refund) error {
+	paymentID, err := u.prepareRefund(ctx, refund)
+	if err != nil {
+		return err
+	}
+
+	err = u.repoPostgresCommand.Insert(ctx, refund)
+	if errors.Is(err, entity.ErrRefundExceedsRemaining) {
+		return fmt.Errorf("another refund of the order was issued first: %w", ErrConflict)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert refund record: %w", err)
+	}
+
+	return u.PublishRefund(refund, paymentID)
+}
+
+// PrepareRefund check and complete the refund without saving it, for a caller that saves the refund
+// together with its own change and publishes it with PublishRefund after
+func (u *RefundCommandUseCase) PrepareRefund(ctx context.Context, refund *entity.Refund) error {
+	_, err := u.prepareRefund(ctx, refund)
+	return err
+}
+
+// prepareRefund return the payment id of the order, the refund is paid back to it
+func (u *RefundCommandUseCase) prepareRefund(ctx context.Context, refund *entity.Refund) (uuid.UUID, error) {
+	order, err := u.repoOrderPostgresCommand.GetByID(ctx, refund.OrderID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get order for refund: %w", err)
+	}
+	if order.ID == uuid.Nil {
+		return uuid.Nil, fmt.Errorf("order %s: %w", refund.OrderID, ErrNotFound)
+	}
+	if order.PaymentID == uuid.Nil {
+		return uuid.Nil, fmt.Errorf("order is not paid: %w", ErrConflict)
+	}
+
+	refundedAmount, err := u.repoPostgresCommand.GetRefundedAmountByOrderID(ctx, order.ID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get refunded amount: %w", err)
+	}
+
+	refundedQuantity, err := u.repoPostgresCommand.GetRefundedQuantityByOrderID(ctx, order.ID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get refunded quantity: %w", err)
+	}
+
+	// remaining quantity of each product that is not refunded yet
+	orderItems := make(map[uuid.UUID]entity.OrderItem)
+	for _, item := range order.Items {
+		item.ProductQuantity -= refundedQuantity[item.ID]
+		orderItems[item.ProductID] = item
+	}
+
+	remainingAmount := order.PaidAmount - refundedAmount
+	fullRefund := len(refund.Items) == 0 && refund.Amount == 0
+	if fullRefund {
+		for _, item := range orderItems {
+			if item.ProductQuantity <= 0 {
+				continue
+			}
+			refund.Items = append(refund.Items, entity.RefundItem{
+				ProductID:       item.ProductID,
+				ProductQuantity: item.ProductQuantity,
+			})
+		}
+	}
+
+	err = refund.GenerateRefundID()
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to generate refund id: %w", err)
+	}
+	refund.UserID = order.UserID
+	refund.SetStatusToPending()
+
+	var itemsAmount float64
+	for i := range refund.Items {
+		orderItem, ok := orderItems[refund.Items[i].ProductID]
+		if !ok {
+			return uuid.Nil, fmt.Errorf("product %s is not in the order: %w", refund.Items[i].ProductID, ErrValidation)
+		}
+		if refund.Items[i].ProductQuantity <= 0 || refund.Items[i].ProductQuantity > orderItem.ProductQuantity {
+			return uuid.Nil, fmt.Errorf("invalid refund quantity for product %s: %w", orderItem.ProductID, ErrValidation)
+		}
+
+		err := refund.Items[i].GenerateRefundItemID()
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to generate refund item id: %w", err)
+		}
+		refund.Items[i].RefundID = refund.ID
+		refund.Items[i].OrderItemID = orderItem.ID
+		refund.Items[i].Price = orderItem.PaidUnitPrice()
+		refund.Items[i].CreatedAt = refund.CreatedAt
+		refund.Items[i].UpdatedAt = refund.UpdatedAt
+
+		itemsAmount += refund.Items[i].Price * float64(refund.Items[i].ProductQuantity)
+	}
+
+	switch {
+	case fullRefund:
+		refund.AddAmount(remainingAmount)
+	case refund.Amount == 0:
+		refund.AddAmount(itemsAmount)
+	}
+
+	if refund.Amount <= 0 {
+		return uuid.Nil, fmt.Errorf("nothing left to refund: %w", ErrValidation)
+	}
+	if refund.Amount-remainingAmount > entity.REFUND_AMOUNT_TOLERANCE {
+		return uuid.Nil, fmt.Errorf("refund amount %.2f exceeds the remaining paid amount %.2f: %w", refund.Amount, remainingAmount, ErrValidation)
+	}
+
+	return order.PaymentID, nil
+}
+
+// PublishRefund send the saved refund to the payment service and reverse the sale
+func (u *RefundCommandUseCase) PublishRefund(refund *entity.Refund, paymentID uuid.UUID) error {
+	refundMessage := dto.RefundEntityToKafkaRefundCreatedMessage(refund, paymentID)
+	err := u.producer.Publish(
+		constant.RefundCreatedTopic,
+		[]byte(refund.OrderID.String()),
+		refundMessage,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+
+	saleMessage := dto.RefundEntityToKafkaSaleReversedMessage(refund)
+	err = u.producer.Publish(
+		constant.SaleReversed,
+		[]byte(refund.OrderID.String()),
+		saleMessage,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+
+	return nil
+}
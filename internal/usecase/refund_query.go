@@ -0,0 +1,39 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
+)
+
+type RefundQueryUseCase struct {
+	repoPostgresQuery RefundPostgreQueryRepo
+}
+
+func NewRefundQueryUseCase(repoPostgresQuery RefundPostgreQueryRepo) *RefundQueryUseCase {
+	return &RefundQueryUseCase{
+		repoPostgresQuery,
+	}
+}
+
+func (u *RefundQueryUseCase) CreateRefundView(ctx context.Context, refund *entity.RefundView) error {
+	err := refund.GenerateRefundViewID()
+	if err != nil {
+		return fmt.Errorf("failed to generate refund view id: %w", err)
+	}
+
+	for i := range refund.Items {
+		err := refund.Items[i].GenerateRefundItemViewID()
+		if err != nil {
+			return fmt.Errorf("failed to generate refund item view id: %w", err)
+		}
+	}
+
+	return u.repoPostgresQuery.Insert(ctx, refund)
+}
+
+func (u *RefundQueryUseCase) GetRefundsByOrderID(ctx context.Context, orderID uuid.UUID, page entity.Page) ([]*entity.RefundView, entity.PageCursor, error) {
+	return u.repoPostgresQuery.GetByOrderID(ctx, orderID, page)
+}
@@ -0,0 +1,272 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/config"
+	"github.com/idoyudha/eshop-order/internal/constant"
+	"github.com/idoyudha/eshop-order/internal/dto"
+	"github.com/idoyudha/eshop-order/internal/entity"
+	"github.com/idoyudha/eshop-order/pkg/kafka"
+)
+
+type ReturnCommandUseCase struct {
+	repoPostgresCommand      ReturnPostgreCommandRepo
+	repoOrderPostgresCommand OrderPostgreCommandRepo
+	refund                   RefundCommand
+	producer                 *kafka.ProducerServer
+	warehouseService         config.WarehouseService
+	constant                 config.Constant
+}
+
+func NewReturnCommandUseCase(
+	repoPostgresCommand ReturnPostgreCommandRepo,
+	repoOrderPostgresCommand OrderPostgreCommandRepo,
+	refund RefundCommand,
+	producer *kafka.ProducerServer,
+	warehouseService config.WarehouseService,
+	constant config.Constant,
+) *ReturnCommandUseCase {
+	return &ReturnCommandUseCase{
+		repoPostgresCommand,
+		repoOrderPostgresCommand,
+		refund,
+		producer,
+		warehouseService,
+		constant,
+	}
+}
+
+// CreateReturn open a return request on some or all items of a delivered order.
+// return without items means every remaining item of the order is returned.
+func (u *ReturnCommandUseCase) CreateReturn(ctx context.Context, orderReturn *entity.OrderReturn) error {
+	order, err := u.repoOrderPostgresCommand.GetByID(ctx, orderReturn.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to get order for return: %w", err)
+	}
+	if order.ID == uuid.Nil || order.UserID != orderReturn.UserID {
+		return fmt.Errorf("order %s: %w", orderReturn.OrderID, ErrNotFound)
+	}
+
+	if !order.IsReturnable(u.constant.ReturnWindowDays) {
+		return fmt.Errorf("order is not delivered or the return window has passed: %w", ErrConflict)
+	}
+
+	returned, err := u.repoPostgresCommand.GetReturnedQuantityByOrderID(ctx, order.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get returned quantity: %w", err)
+	}
+
+	// remaining quantity of each product that can still be returned
+	orderItems := make(map[uuid.UUID]entity.OrderItem)
+	for _, item := range order.Items {
+		item.ProductQuantity -= returned[item.ID]
+		orderItems[item.ProductID] = item
+	}
+
+	if len(orderReturn.Items) == 0 {
+		for _, item := range orderItems {
+			if item.ProductQuantity <= 0 {
+				continue
+			}
+			orderReturn.Items = append(orderReturn.Items, entity.OrderReturnItem{
+				ProductID:       item.ProductID,
+				ProductQuantity: item.ProductQuantity,
+			})
+		}
+		if len(orderReturn.Items) == 0 {
+			return fmt.Errorf("all items are already returned: %w", ErrConflict)
+		}
+	}
+
+	err = orderReturn.GenerateOrderReturnID()
+	if err != nil {
+		return fmt.Errorf("failed to generate order return id: %w", err)
+	}
+	orderReturn.SetStatusToRequested()
+
+	for i := range orderReturn.Items {
+		orderItem, ok := orderItems[orderReturn.Items[i].ProductID]
+		if !ok {
+			return fmt.Errorf("product %s is not in the order: %w", orderReturn.Items[i].ProductID, ErrValidation)
+		}
+		if orderReturn.Items[i].ProductQuantity <= 0 || orderReturn.Items[i].ProductQuantity > orderItem.ProductQuantity {
+			return fmt.Errorf("invalid return quantity for product %s: %w", orderItem.ProductID, ErrValidation)
+		}
+
+		err := orderReturn.Items[i].GenerateOrderReturnItemID()
+		if err != nil {
+			return fmt.Errorf("failed to generate order return item id: %w", err)
+		}
+		orderReturn.Items[i].ReturnID = orderReturn.ID
+		orderReturn.Items[i].OrderItemID = orderItem.ID
+		orderReturn.Items[i].Price = orderItem.PaidUnitPrice()
+		orderReturn.Items[i].CreatedAt = orderReturn.CreatedAt
+		orderReturn.Items[i].UpdatedAt = orderReturn.UpdatedAt
+
+		orderReturn.AddRefundAmount(orderReturn.Items[i].Price * float64(orderReturn.Items[i].ProductQuantity))
+	}
+
+	err = u.repoPostgresCommand.Insert(ctx, orderReturn)
+	if err != nil {
+		return fmt.Errorf("failed to insert order return record: %w", err)
+	}
+
+	message := dto.OrderReturnEntityToKafkaReturnRequestedMessage(orderReturn)
+	err = u.producer.Publish(
+		constant.ReturnRequestedTopic,
+		[]byte(orderReturn.OrderID.String()),
+		message,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+
+	return nil
+}
+
+// ApproveReturn accept the return request, the items are moved back into the warehouse and refunded.
+// the return is approved first so a repeated approval stops there, it goes back to REQUESTED
+// when the stock move or the refund fails
+func (u *ReturnCommandUseCase) ApproveReturn(ctx context.Context, orderReturn *entity.OrderReturn, token string) error {
+	existing, err := u.getRequestedReturn(ctx, orderReturn)
+	if err != nil {
+		return err
+	}
+
+	order, err := u.repoOrderPostgresCommand.GetByID(ctx, existing.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to get order for return: %w", err)
+	}
+
+	existing.SetStatusToApproved()
+	existing.AdminNote = orderReturn.AdminNote
+	existing.UpdatedAt = orderReturn.UpdatedAt
+	err = u.setReturnStatus(ctx, existing, entity.RETURN_REQUESTED)
+	if err != nil {
+		return err
+	}
+
+	var stockRequest stockMovementRequest
+	for _, item := range existing.Items {
+		stockRequest.Items = append(stockRequest.Items, orderItemRequest{
+			ProductID: item.ProductID,
+			Quantity:  item.ProductQuantity,
+		})
+	}
+	stockRequest.ZipCode = order.Address.ZipCode
+	stockRequest.WarehouseID = pickupWarehouse(order)
+	err = createStockMoveIn(ctx, u.warehouseService.BaseURL, stockRequest, token)
+	if err != nil {
+		return u.reopenReturn(ctx, existing, fmt.Errorf("failed to create stock move in: %w", err))
+	}
+
+	refund := entity.Refund{
+		OrderID:   existing.OrderID,
+		ReturnID:  existing.ID,
+		Reason:    fmt.Sprintf("return approved: %s", existing.Reason),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	for _, item := range existing.Items {
+		refund.Items = append(refund.Items, entity.RefundItem{
+			ProductID:       item.ProductID,
+			ProductQuantity: item.ProductQuantity,
+		})
+	}
+	err = u.refund.IssueRefund(ctx, &refund)
+	if err != nil {
+		err = fmt.Errorf("failed to issue refund for return: %w", err)
+		// the items are taken out of the warehouse again so the next approval can move them in
+		if moveErr := createStockMovement(ctx, u.warehouseService.BaseURL, stockRequest, token); moveErr != nil {
+			return fmt.Errorf("%w, failed to undo stock move in: %v", err, moveErr)
+		}
+		return u.reopenReturn(ctx, existing, err)
+	}
+
+	return u.publishReturnStatus(existing, constant.ReturnApprovedTopic)
+}
+
+// reopenReturn put the approved return back to REQUESTED after the approval failed, cause is returned
+func (u *ReturnCommandUseCase) reopenReturn(ctx context.Context, orderReturn *entity.OrderReturn, cause error) error {
+	orderReturn.SetStatusToRequested()
+	orderReturn.UpdatedAt = time.Now()
+	err := u.setReturnStatus(ctx, orderReturn, entity.RETURN_APPROVED)
+	if err != nil {
+		return fmt.Errorf("%w, failed to reopen return: %v", cause, err)
+	}
+
+	return cause
+}
+
+func (u *ReturnCommandUseCase) RejectReturn(ctx context.Context, orderReturn *entity.OrderReturn) error {
+	existing, err := u.getRequestedReturn(ctx, orderReturn)
+	if err != nil {
+		return err
+	}
+
+	existing.SetStatusToRejected()
+	existing.AdminNote = orderReturn.AdminNote
+	existing.UpdatedAt = orderReturn.UpdatedAt
+	err = u.setReturnStatus(ctx, existing, entity.RETURN_REQUESTED)
+	if err != nil {
+		return err
+	}
+
+	return u.publishReturnStatus(existing, constant.ReturnRejectedTopic)
+}
+
+// getRequestedReturn load the return that still waiting for admin decision
+func (u *ReturnCommandUseCase) getRequestedReturn(ctx context.Context, orderReturn *entity.OrderReturn) (*entity.OrderReturn, error) {
+	existing, err := u.repoPostgresCommand.GetByID(ctx, orderReturn.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("return %s: %w", orderReturn.ID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order return: %w", err)
+	}
+	if existing.OrderID != orderReturn.OrderID {
+		return nil, fmt.Errorf("return %s: %w", orderReturn.ID, ErrNotFound)
+	}
+	if existing.Status != entity.RETURN_REQUESTED {
+		return nil, fmt.Errorf("return is already %s: %w", existing.Status, ErrConflict)
+	}
+
+	return existing, nil
+}
+
+// setReturnStatus save the new status of the return while it is still in the from status
+func (u *ReturnCommandUseCase) setReturnStatus(ctx context.Context, orderReturn *entity.OrderReturn, from string) error {
+	if orderReturn.UpdatedAt.IsZero() {
+		orderReturn.UpdatedAt = time.Now()
+	}
+
+	err := u.repoPostgresCommand.UpdateStatus(ctx, orderReturn, from)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("return %s is not %s anymore: %w", orderReturn.ID, from, ErrConflict)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update order return status: %w", err)
+	}
+
+	return nil
+}
+
+func (u *ReturnCommandUseCase) publishReturnStatus(orderReturn *entity.OrderReturn, topic string) error {
+	message := dto.OrderReturnEntityToKafkaReturnStatusUpdatedMessage(orderReturn)
+	err := u.producer.Publish(
+		topic,
+		[]byte(orderReturn.OrderID.String()),
+		message,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to produce kafka message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/internal/entity"
+)
+
+type ReturnQueryUseCase struct {
+	repoPostgresQuery ReturnPostgreQueryRepo
+}
+
+func NewReturnQueryUseCase(repoPostgresQuery ReturnPostgreQueryRepo) *ReturnQueryUseCase {
+	return &ReturnQueryUseCase{
+		repoPostgresQuery,
+	}
+}
+
+func (u *ReturnQueryUseCase) CreateReturnView(ctx context.Context, orderReturn *entity.OrderReturnView) error {
+	orderReturn.Status = entity.RETURN_REQUESTED
+
+	err := orderReturn.GenerateOrderReturnViewID()
+	if err != nil {
+		return fmt.Errorf("failed to generate order return view id: %w", err)
+	}
+
+	for i := range orderReturn.Items {
+		err := orderReturn.Items[i].GenerateOrderReturnItemViewID()
+		if err != nil {
+			return fmt.Errorf("failed to generate order return item view id: %w", err)
+		}
+	}
+
+	return u.repoPostgresQuery.Insert(ctx, orderReturn)
+}
+
+func (u *ReturnQueryUseCase) UpdateReturnViewStatus(ctx context.Context, orderReturn *entity.OrderReturnView) error {
+	return u.repoPostgresQuery.UpdateStatus(ctx, orderReturn)
+}
+
+func (u *ReturnQueryUseCase) GetReturnsByOrderID(ctx context.Context, orderID uuid.UUID, page entity.Page) ([]*entity.OrderReturnView, entity.PageCursor, error) {
+	return u.repoPostgresQuery.GetByOrderID(ctx, orderID, page)
+}
@@ -0,0 +1,73 @@
+package shipping
+
+import (
+	"context"
+	"time"
+
+	"github.com/idoyudha/eshop-order/config"
+	"github.com/idoyudha/eshop-order/internal/entity"
+	"github.com/idoyudha/eshop-order/pkg/logger"
+)
+
+type rateCache interface {
+	GetRates(context.Context, string, string, []string) ([]entity.ShippingRate, error)
+	SetRates(context.Context, string, string, []entity.ShippingRate, time.Duration) error
+}
+
+// CachedRateProvider cache the rates of the provider by zip pair and shipping method,
+// estimated rates from the local rate table are not cached so the next lookup tries the service again
+type CachedRateProvider struct {
+	provider rateProvider
+	cache    rateCache
+	methods  []string
+	ttl      time.Duration
+	l        logger.Interface
+}
+
+func NewCachedRateProvider(provider rateProvider, cache rateCache, shipping config.Shipping, ttlMinutes int, l logger.Interface) *CachedRateProvider {
+	methods := make([]string, 0, len(shipping.Methods))
+	for _, method := range shipping.Methods {
+		methods = append(methods, method.Method)
+	}
+
+	return &CachedRateProvider{
+		provider: provider,
+		cache:    cache,
+		methods:  methods,
+		ttl:      time.Duration(ttlMinutes) * time.Minute,
+		l:        l,
+	}
+}
+
+func (p *CachedRateProvider) GetShippingRates(ctx context.Context, fromZip, toZip string) ([]entity.ShippingRate, error) {
+	// cache is best effort, the provider is used when redis is down
+	cached, err := p.cache.GetRates(ctx, fromZip, toZip, p.methods)
+	if err != nil {
+		p.l.Error(err, "shipping - CachedRateProvider - GetShippingRates")
+	}
+	if cached != nil {
+		return cached, nil
+	}
+
+	rates, err := p.provider.GetShippingRates(ctx, fromZip, toZip)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isEstimated(rates) {
+		if err := p.cache.SetRates(ctx, fromZip, toZip, rates, p.ttl); err != nil {
+			p.l.Error(err, "shipping - CachedRateProvider - GetShippingRates")
+		}
+	}
+
+	return rates, nil
+}
+
+func isEstimated(rates []entity.ShippingRate) bool {
+	for _, rate := range rates {
+		if rate.Estimated {
+			return true
+		}
+	}
+	return false
+}
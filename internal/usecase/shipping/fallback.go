@@ -0,0 +1,54 @@
+package shipping
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	"github.com/idoyudha/eshop-order/internal/entity"
+	"github.com/idoyudha/eshop-order/pkg/logger"
+)
+
+// fallbackCount is the number of shipping rate lookups served by the fallback provider
+var fallbackCount = expvar.NewInt("shipping_rate_fallbacks")
+
+type rateProvider interface {
+	GetShippingRates(context.Context, string, string) ([]entity.ShippingRate, error)
+}
+
+// FallbackRateProvider use the primary provider, and the fallback provider when the primary
+// fails or does not answer within the timeout
+type FallbackRateProvider struct {
+	primary  rateProvider
+	fallback rateProvider
+	timeout  time.Duration
+	l        logger.Interface
+}
+
+func NewFallbackRateProvider(primary, fallback rateProvider, timeoutMs int, l logger.Interface) *FallbackRateProvider {
+	return &FallbackRateProvider{
+		primary:  primary,
+		fallback: fallback,
+		timeout:  time.Duration(timeoutMs) * time.Millisecond,
+		l:        l,
+	}
+}
+
+func (p *FallbackRateProvider) GetShippingRates(ctx context.Context, fromZip, toZip string) ([]entity.ShippingRate, error) {
+	primaryCtx := ctx
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		primaryCtx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	rates, err := p.primary.GetShippingRates(primaryCtx, fromZip, toZip)
+	if err == nil {
+		return rates, nil
+	}
+
+	p.l.Error(err, "shipping - FallbackRateProvider - GetShippingRates")
+	fallbackCount.Add(1)
+
+	return p.fallback.GetShippingRates(ctx, fromZip, toZip)
+}
@@ -0,0 +1,100 @@
+package shipping
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/idoyudha/eshop-order/config"
+	"github.com/idoyudha/eshop-order/internal/entity"
+)
+
+// RemoteRateProvider get the base shipping cost from the shipping cost service,
+// every shipping method in the config multiplies the base cost and has its own estimated days
+type RemoteRateProvider struct {
+	shippingCostService config.ShippingCostService
+	methods             []config.ShippingMethod
+}
+
+func NewRemoteRateProvider(shippingCostService config.ShippingCostService, shipping config.Shipping) *RemoteRateProvider {
+	return &RemoteRateProvider{
+		shippingCostService: shippingCostService,
+		methods:             shipping.Methods,
+	}
+}
+
+func (p *RemoteRateProvider) GetShippingRates(ctx context.Context, fromZip, toZip string) ([]entity.ShippingRate, error) {
+	baseCost, err := getShippingCost(ctx, p.shippingCostService.URL, shippingCostRequest{
+		FromZip: fromZip,
+		ToZip:   toZip,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make([]entity.ShippingRate, 0, len(p.methods))
+	for _, method := range p.methods {
+		rates = append(rates, entity.ShippingRate{
+			Method:        method.Method,
+			Cost:          baseCost * method.Multiplier,
+			EstimatedDays: method.EstimatedDays,
+		})
+	}
+
+	return rates, nil
+}
+
+type shippingCostRequest struct {
+	FromZip string `json:"from_zip"`
+	ToZip   string `json:"to_zip"`
+}
+
+type shippingCostResponse struct {
+	Code int `json:"code"`
+	Data struct {
+		ShippingCost float64 `json:"shipping_cost"`
+	}
+	Message string `json:"message"`
+}
+
+func getShippingCost(ctx context.Context, scBaseURL string, request shippingCostRequest) (float64, error) {
+	shippingCostURL := fmt.Sprintf("%s/shipping-cost", scBaseURL)
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal shipping cost request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, shippingCostURL, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create shipping cost request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to make shipping cost request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("shipping cost service returned status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read shipping cost response body: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var shippingCostResponse shippingCostResponse
+	err = json.Unmarshal(body, &shippingCostResponse)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unmarshal shipping cost response: %w", err)
+	}
+
+	return shippingCostResponse.Data.ShippingCost, nil
+}
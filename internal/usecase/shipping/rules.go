@@ -0,0 +1,90 @@
+package shipping
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/idoyudha/eshop-order/config"
+	"github.com/idoyudha/eshop-order/internal/entity"
+)
+
+const (
+	ruleWaive    = "WAIVE"
+	ruleCap      = "CAP"
+	ruleOverride = "OVERRIDE"
+)
+
+// RuleEngine apply the shipping rules in the config to the shipping cost from the rate lookup
+type RuleEngine struct {
+	rules []config.ShippingRule
+}
+
+func NewRuleEngine(shipping config.Shipping) *RuleEngine {
+	return &RuleEngine{
+		rules: shipping.Rules,
+	}
+}
+
+// ApplyShippingRules apply the first rule matching the order and record it on the order,
+// product category of the items must be set before
+func (e *RuleEngine) ApplyShippingRules(ctx context.Context, order *entity.Order) error {
+	order.ShippingRule = ""
+	subtotal := order.Subtotal()
+	for _, rule := range e.rules {
+		indexes, ok := matchShippingRule(rule, order, subtotal)
+		if !ok {
+			continue
+		}
+
+		switch rule.Action {
+		case ruleWaive:
+			order.DistributeShippingCost(indexes, 0)
+		case ruleCap:
+			var current float64
+			for _, i := range indexes {
+				current += order.Items[i].ShippingCost
+			}
+			if current <= rule.Amount {
+				continue
+			}
+			order.DistributeShippingCost(indexes, rule.Amount)
+		case ruleOverride:
+			order.DistributeShippingCost(indexes, rule.Amount)
+		default:
+			return fmt.Errorf("unknown shipping rule action %s", rule.Action)
+		}
+
+		order.ShippingRule = rule.Name
+		return nil
+	}
+
+	return nil
+}
+
+// matchShippingRule return the index of the items the rule applies to
+func matchShippingRule(rule config.ShippingRule, order *entity.Order, subtotal float64) ([]int, bool) {
+	if subtotal < rule.MinSubtotal {
+		return nil, false
+	}
+	if rule.State != "" && !strings.EqualFold(rule.State, order.Address.State) {
+		return nil, false
+	}
+	if rule.ZipPrefix != "" && !strings.HasPrefix(order.Address.ZipCode, rule.ZipPrefix) {
+		return nil, false
+	}
+
+	var indexes []int
+	for i, item := range order.Items {
+		// digital item has no shipping
+		if item.IsDigital() {
+			continue
+		}
+		if rule.CategoryID != "" && !strings.EqualFold(rule.CategoryID, item.CategoryID.String()) {
+			continue
+		}
+		indexes = append(indexes, i)
+	}
+
+	return indexes, len(indexes) > 0
+}
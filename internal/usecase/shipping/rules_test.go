@@ -0,0 +1,154 @@
+package shipping
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/config"
+	"github.com/idoyudha/eshop-order/internal/entity"
+)
+
+var (
+	testFurniture = uuid.New()
+	testClothing  = uuid.New()
+	testEbook     = uuid.New()
+)
+
+// order with a furniture, a clothing and a digital item, the subtotal is 300000 and the shipping cost 30000
+func newRuleTestOrder() *entity.Order {
+	order := &entity.Order{
+		Address: entity.OrderAddress{State: "Bali", ZipCode: "80111"},
+		Items: []entity.OrderItem{
+			{CategoryID: testFurniture, ProductType: entity.PRODUCT_PHYSICAL, Price: 100000, ProductQuantity: 2, ShippingCost: 20000},
+			{CategoryID: testClothing, ProductType: entity.PRODUCT_PHYSICAL, Price: 50000, ProductQuantity: 1, ShippingCost: 10000},
+			{CategoryID: testEbook, ProductType: entity.PRODUCT_DIGITAL, Price: 50000, ProductQuantity: 1},
+		},
+	}
+	order.TotalPrice = order.Subtotal() + 30000
+	return order
+}
+
+func shippingCosts(order *entity.Order) []float64 {
+	costs := make([]float64, len(order.Items))
+	for i, item := range order.Items {
+		costs[i] = item.ShippingCost
+	}
+	return costs
+}
+
+func TestRuleEngineApplyShippingRules(t *testing.T) {
+	tests := []struct {
+		name      string
+		rules     []config.ShippingRule
+		wantRule  string
+		wantCosts []float64
+	}{
+		{
+			name:      "no rules",
+			wantCosts: []float64{20000, 10000, 0},
+		},
+		{
+			name:      "waive",
+			rules:     []config.ShippingRule{{Name: "free", Action: ruleWaive, MinSubtotal: 250000}},
+			wantRule:  "free",
+			wantCosts: []float64{0, 0, 0},
+		},
+		{
+			name:      "subtotal below minimum",
+			rules:     []config.ShippingRule{{Name: "free", Action: ruleWaive, MinSubtotal: 500000}},
+			wantCosts: []float64{20000, 10000, 0},
+		},
+		{
+			name:      "cap is split by the current cost",
+			rules:     []config.ShippingRule{{Name: "cap", Action: ruleCap, Amount: 15000}},
+			wantRule:  "cap",
+			wantCosts: []float64{10000, 5000, 0},
+		},
+		{
+			name: "cap under the current cost falls through to the next rule",
+			rules: []config.ShippingRule{
+				{Name: "cap", Action: ruleCap, Amount: 50000},
+				{Name: "flat", Action: ruleOverride, Amount: 9000},
+			},
+			wantRule:  "flat",
+			wantCosts: []float64{6000, 3000, 0},
+		},
+		{
+			name: "first matching rule wins",
+			rules: []config.ShippingRule{
+				{Name: "java", Action: ruleWaive, State: "Jawa Barat"},
+				{Name: "bali", Action: ruleOverride, State: "bali", Amount: 3000},
+				{Name: "free", Action: ruleWaive},
+			},
+			wantRule:  "bali",
+			wantCosts: []float64{2000, 1000, 0},
+		},
+		{
+			name:      "category rule only touches the category items",
+			rules:     []config.ShippingRule{{Name: "clothing", Action: ruleWaive, CategoryID: testClothing.String()}},
+			wantRule:  "clothing",
+			wantCosts: []float64{20000, 0, 0},
+		},
+		{
+			name:      "zip prefix mismatch",
+			rules:     []config.ShippingRule{{Name: "jakarta", Action: ruleWaive, ZipPrefix: "10"}},
+			wantCosts: []float64{20000, 10000, 0},
+		},
+		{
+			name:      "category with only digital items",
+			rules:     []config.ShippingRule{{Name: "ebook", Action: ruleOverride, Amount: 5000, CategoryID: testEbook.String()}},
+			wantCosts: []float64{20000, 10000, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := newRuleTestOrder()
+			order.ShippingRule = "stale"
+			before := order.TotalPrice
+
+			engine := NewRuleEngine(config.Shipping{Rules: tt.rules})
+			if err := engine.ApplyShippingRules(context.Background(), order); err != nil {
+				t.Fatalf("ApplyShippingRules() error = %v", err)
+			}
+
+			if order.ShippingRule != tt.wantRule {
+				t.Errorf("ShippingRule = %q, want %q", order.ShippingRule, tt.wantRule)
+			}
+			costs := shippingCosts(order)
+			var total float64
+			for i := range costs {
+				if math.Abs(costs[i]-tt.wantCosts[i]) > 0.001 {
+					t.Errorf("item %d shipping cost = %v, want %v", i, costs[i], tt.wantCosts[i])
+				}
+				total += costs[i]
+			}
+			// the total price follows the shipping cost change
+			if want := before - 30000 + total; math.Abs(order.TotalPrice-want) > 0.001 {
+				t.Errorf("TotalPrice = %v, want %v", order.TotalPrice, want)
+			}
+		})
+	}
+}
+
+func TestRuleEngineCapRemainderOnLastItem(t *testing.T) {
+	order := newRuleTestOrder()
+	engine := NewRuleEngine(config.Shipping{Rules: []config.ShippingRule{{Name: "cap", Action: ruleCap, Amount: 10000}}})
+	if err := engine.ApplyShippingRules(context.Background(), order); err != nil {
+		t.Fatalf("ApplyShippingRules() error = %v", err)
+	}
+
+	// 10000 split 2:1 does not round evenly, the last item takes the remainder so nothing is lost
+	costs := shippingCosts(order)
+	if costs[0] != 6666.67 || costs[1] != 3333.33 {
+		t.Errorf("shipping costs = %v, want [6666.67 3333.33 0]", costs)
+	}
+}
+
+func TestRuleEngineUnknownAction(t *testing.T) {
+	engine := NewRuleEngine(config.Shipping{Rules: []config.ShippingRule{{Name: "half", Action: "HALF"}}})
+	if err := engine.ApplyShippingRules(context.Background(), newRuleTestOrder()); err == nil {
+		t.Error("ApplyShippingRules() error = nil, want unknown action error")
+	}
+}
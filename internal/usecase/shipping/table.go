@@ -0,0 +1,55 @@
+package shipping
+
+import (
+	"context"
+	"strings"
+
+	"github.com/idoyudha/eshop-order/config"
+	"github.com/idoyudha/eshop-order/internal/entity"
+)
+
+// TableRateProvider price the shipping from the local rate table, the base cost is taken
+// from the zone with the longest zip prefix matching the customer zip code
+type TableRateProvider struct {
+	defaultCost float64
+	zones       []config.ShippingZone
+	methods     []config.ShippingMethod
+}
+
+func NewTableRateProvider(table config.ShippingRateTable, shipping config.Shipping) *TableRateProvider {
+	return &TableRateProvider{
+		defaultCost: table.DefaultCost,
+		zones:       table.Zones,
+		methods:     shipping.Methods,
+	}
+}
+
+// GetShippingRates return estimated rates, the table does not know the warehouse distance
+func (p *TableRateProvider) GetShippingRates(ctx context.Context, fromZip, toZip string) ([]entity.ShippingRate, error) {
+	baseCost := p.baseCost(fromZip)
+
+	rates := make([]entity.ShippingRate, 0, len(p.methods))
+	for _, method := range p.methods {
+		rates = append(rates, entity.ShippingRate{
+			Method:        method.Method,
+			Cost:          baseCost * method.Multiplier,
+			EstimatedDays: method.EstimatedDays,
+			Estimated:     true,
+		})
+	}
+
+	return rates, nil
+}
+
+func (p *TableRateProvider) baseCost(zipCode string) float64 {
+	cost := p.defaultCost
+	longest := 0
+	for _, zone := range p.zones {
+		if strings.HasPrefix(zipCode, zone.ZipPrefix) && len(zone.ZipPrefix) > longest {
+			cost = zone.BaseCost
+			longest = len(zone.ZipPrefix)
+		}
+	}
+
+	return cost
+}
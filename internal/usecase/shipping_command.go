@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+)
+
+type ShippingCommandUseCase struct {
+	repoRedis ShippingRedisRepo
+}
+
+func NewShippingCommandUseCase(repoRedis ShippingRedisRepo) *ShippingCommandUseCase {
+	return &ShippingCommandUseCase{
+		repoRedis,
+	}
+}
+
+// InvalidateShippingCostCache remove the cached shipping cost of the zip pair when carrier rates change,
+// empty zip invalidates every zip
+func (u *ShippingCommandUseCase) InvalidateShippingCostCache(ctx context.Context, fromZip, toZip string) (int64, error) {
+	deleted, err := u.repoRedis.DeleteRates(ctx, fromZip, toZip)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete cached shipping cost: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,64 @@
+package tax
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/config"
+	"github.com/idoyudha/eshop-order/internal/entity"
+)
+
+// TableTaxCalculator look up the tax rate from the rate table in the config
+type TableTaxCalculator struct {
+	defaultRate float64
+	rules       []config.TaxRule
+}
+
+func NewTableTaxCalculator(cfg config.Tax) *TableTaxCalculator {
+	return &TableTaxCalculator{
+		defaultRate: cfg.DefaultRate,
+		rules:       cfg.Rules,
+	}
+}
+
+// TaxRate return the rate of the most specific rule matching the address and product category,
+// category is more specific than zip prefix, and zip prefix is more specific than state
+func (c *TableTaxCalculator) TaxRate(ctx context.Context, address entity.OrderAddress, categoryID uuid.UUID) (float64, error) {
+	rate := c.defaultRate
+	bestScore := -1
+	for _, rule := range c.rules {
+		score, ok := matchRule(rule, address, categoryID)
+		if ok && score > bestScore {
+			rate = rule.Rate
+			bestScore = score
+		}
+	}
+
+	return rate, nil
+}
+
+func matchRule(rule config.TaxRule, address entity.OrderAddress, categoryID uuid.UUID) (int, bool) {
+	score := 0
+	if rule.State != "" {
+		if !strings.EqualFold(rule.State, address.State) {
+			return 0, false
+		}
+		score++
+	}
+	if rule.ZipPrefix != "" {
+		if !strings.HasPrefix(address.ZipCode, rule.ZipPrefix) {
+			return 0, false
+		}
+		// longer prefix is more specific
+		score += 10 * len(rule.ZipPrefix)
+	}
+	if rule.CategoryID != "" {
+		if !strings.EqualFold(rule.CategoryID, categoryID.String()) {
+			return 0, false
+		}
+		score += 1000
+	}
+
+	return score, true
+}
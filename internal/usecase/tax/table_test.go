@@ -0,0 +1,73 @@
+package tax
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/idoyudha/eshop-order/config"
+	"github.com/idoyudha/eshop-order/internal/entity"
+)
+
+func TestTableTaxCalculatorTaxRate(t *testing.T) {
+	food := uuid.New()
+	books := uuid.New()
+
+	calculator := NewTableTaxCalculator(config.Tax{
+		DefaultRate: 0.11,
+		Rules: []config.TaxRule{
+			{State: "Bali", Rate: 0.12},
+			{ZipPrefix: "80", Rate: 0.13},
+			{ZipPrefix: "801", Rate: 0.14},
+			{State: "Bali", ZipPrefix: "801", Rate: 0.15},
+			{CategoryID: food.String(), Rate: 0.05},
+			{CategoryID: books.String(), ZipPrefix: "80", Rate: 0.02},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		address  entity.OrderAddress
+		category uuid.UUID
+		want     float64
+	}{
+		{"no rule matches", entity.OrderAddress{State: "Jawa Barat", ZipCode: "40111"}, uuid.Nil, 0.11},
+		{"state", entity.OrderAddress{State: "Bali", ZipCode: "99999"}, uuid.Nil, 0.12},
+		{"state is case insensitive", entity.OrderAddress{State: "BALI", ZipCode: "99999"}, uuid.Nil, 0.12},
+		{"zip prefix beats state", entity.OrderAddress{State: "Bali", ZipCode: "80200"}, uuid.Nil, 0.13},
+		{"longer zip prefix beats shorter", entity.OrderAddress{State: "Jawa Barat", ZipCode: "80111"}, uuid.Nil, 0.14},
+		{"state and zip prefix beats zip prefix", entity.OrderAddress{State: "Bali", ZipCode: "80111"}, uuid.Nil, 0.15},
+		{"category beats address", entity.OrderAddress{State: "Bali", ZipCode: "80111"}, food, 0.05},
+		{"category and zip prefix beats category", entity.OrderAddress{ZipCode: "80111"}, books, 0.02},
+		{"category rule needs its zip prefix", entity.OrderAddress{ZipCode: "40111"}, books, 0.11},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calculator.TaxRate(context.Background(), tt.address, tt.category)
+			if err != nil {
+				t.Fatalf("TaxRate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("TaxRate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableTaxCalculatorFirstRuleWinsTie(t *testing.T) {
+	calculator := NewTableTaxCalculator(config.Tax{
+		DefaultRate: 0.11,
+		Rules: []config.TaxRule{
+			{State: "Bali", Rate: 0.12},
+			{State: "bali", Rate: 0.2},
+		},
+	})
+
+	got, err := calculator.TaxRate(context.Background(), entity.OrderAddress{State: "Bali"}, uuid.Nil)
+	if err != nil {
+		t.Fatalf("TaxRate() error = %v", err)
+	}
+	if got != 0.12 {
+		t.Errorf("TaxRate() = %v, want 0.12", got)
+	}
+}
@@ -0,0 +1,4 @@
+ALTER TYPE "order_status" ADD VALUE IF NOT EXISTS 'DELIVERY_FAILED';
+ALTER TYPE "order_status" ADD VALUE IF NOT EXISTS 'RETURNING';
+
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "delivery_attempts" integer NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "updated_by" varchar;
@@ -0,0 +1,40 @@
+CREATE TYPE "return_status" AS ENUM (
+  'REQUESTED',
+  'APPROVED',
+  'REJECTED'
+);
+
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "delivered_at" timestamp;
+ALTER TABLE "order_items" ADD COLUMN IF NOT EXISTS "price" float NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS "order_returns" (
+  "id" uuid PRIMARY KEY,
+  "order_id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "status" return_status NOT NULL,
+  "reason" text NOT NULL,
+  "admin_note" text,
+  "refund_amount" float NOT NULL,
+  "created_at" timestamp NOT NULL,
+  "updated_at" timestamp NOT NULL,
+  "deleted_at" timestamp
+);
+
+CREATE TABLE IF NOT EXISTS "order_return_items" (
+  "id" uuid PRIMARY KEY,
+  "return_id" uuid NOT NULL,
+  "order_item_id" uuid NOT NULL,
+  "product_id" uuid NOT NULL,
+  "product_quantity" integer NOT NULL,
+  "price" float NOT NULL,
+  "created_at" timestamp NOT NULL,
+  "updated_at" timestamp NOT NULL,
+  "deleted_at" timestamp
+);
+
+CREATE INDEX ON "order_returns" ("order_id");
+CREATE INDEX ON "order_return_items" ("return_id");
+
+ALTER TABLE "order_returns" ADD FOREIGN KEY ("order_id") REFERENCES "orders" ("id");
+ALTER TABLE "order_return_items" ADD FOREIGN KEY ("return_id") REFERENCES "order_returns" ("id");
+ALTER TABLE "order_return_items" ADD FOREIGN KEY ("order_item_id") REFERENCES "order_items" ("id");
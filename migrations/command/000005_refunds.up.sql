@@ -0,0 +1,36 @@
+CREATE TYPE "refund_status" AS ENUM (
+  'PENDING'
+);
+
+CREATE TABLE IF NOT EXISTS "refunds" (
+  "id" uuid PRIMARY KEY,
+  "order_id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "return_id" uuid,
+  "amount" float NOT NULL,
+  "reason" text NOT NULL,
+  "status" refund_status NOT NULL,
+  "created_at" timestamp NOT NULL,
+  "updated_at" timestamp NOT NULL,
+  "deleted_at" timestamp
+);
+
+CREATE TABLE IF NOT EXISTS "refund_items" (
+  "id" uuid PRIMARY KEY,
+  "refund_id" uuid NOT NULL,
+  "order_item_id" uuid NOT NULL,
+  "product_id" uuid NOT NULL,
+  "product_quantity" integer NOT NULL,
+  "price" float NOT NULL,
+  "created_at" timestamp NOT NULL,
+  "updated_at" timestamp NOT NULL,
+  "deleted_at" timestamp
+);
+
+CREATE INDEX ON "refunds" ("order_id");
+CREATE INDEX ON "refund_items" ("refund_id");
+
+ALTER TABLE "refunds" ADD FOREIGN KEY ("order_id") REFERENCES "orders" ("id");
+ALTER TABLE "refunds" ADD FOREIGN KEY ("return_id") REFERENCES "order_returns" ("id");
+ALTER TABLE "refund_items" ADD FOREIGN KEY ("refund_id") REFERENCES "refunds" ("id");
+ALTER TABLE "refund_items" ADD FOREIGN KEY ("order_item_id") REFERENCES "order_items" ("id");
@@ -0,0 +1,6 @@
+ALTER TYPE "order_status" ADD VALUE IF NOT EXISTS 'CANCELLED';
+
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "shipped_at" timestamp;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "paid_amount" float NOT NULL DEFAULT 0;
+
+UPDATE "orders" SET "paid_amount" = "total_price" WHERE "payment_id" IS NOT NULL AND "status" <> 'REJECTED';
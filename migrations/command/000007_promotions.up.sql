@@ -0,0 +1,47 @@
+CREATE TYPE "promotion_type" AS ENUM (
+  'PERCENTAGE',
+  'FIXED',
+  'FREE_SHIPPING',
+  'BUY_X_GET_Y'
+);
+
+CREATE TABLE IF NOT EXISTS "promotions" (
+  "id" uuid PRIMARY KEY,
+  "code" varchar NOT NULL UNIQUE,
+  "type" promotion_type NOT NULL,
+  "value" float NOT NULL DEFAULT 0,
+  "max_discount" float NOT NULL DEFAULT 0,
+  "min_spend" float NOT NULL DEFAULT 0,
+  "product_id" uuid,
+  "buy_quantity" integer NOT NULL DEFAULT 0,
+  "get_quantity" integer NOT NULL DEFAULT 0,
+  "start_at" timestamp NOT NULL,
+  "end_at" timestamp NOT NULL,
+  "usage_limit" integer NOT NULL DEFAULT 0,
+  "usage_limit_per_user" integer NOT NULL DEFAULT 0,
+  "usage_count" integer NOT NULL DEFAULT 0,
+  "created_at" timestamp NOT NULL,
+  "updated_at" timestamp NOT NULL,
+  "deleted_at" timestamp
+);
+
+CREATE TABLE IF NOT EXISTS "promotion_usages" (
+  "id" uuid PRIMARY KEY,
+  "promotion_id" uuid NOT NULL,
+  "order_id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "created_at" timestamp NOT NULL
+);
+
+CREATE INDEX ON "promotion_usages" ("promotion_id", "user_id");
+
+ALTER TABLE "promotion_usages" ADD FOREIGN KEY ("promotion_id") REFERENCES "promotions" ("id");
+ALTER TABLE "promotion_usages" ADD FOREIGN KEY ("order_id") REFERENCES "orders" ("id");
+
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "promotion_id" uuid;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "promotion_code" varchar;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "discount_amount" float NOT NULL DEFAULT 0;
+ALTER TABLE "order_items" ADD COLUMN IF NOT EXISTS "discount" float NOT NULL DEFAULT 0;
+ALTER TABLE "order_items" ADD COLUMN IF NOT EXISTS "shipping_discount" float NOT NULL DEFAULT 0;
+
+ALTER TABLE "orders" ADD FOREIGN KEY ("promotion_id") REFERENCES "promotions" ("id");
@@ -0,0 +1,3 @@
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "tax_amount" float NOT NULL DEFAULT 0;
+ALTER TABLE "order_items" ADD COLUMN IF NOT EXISTS "category_id" uuid;
+ALTER TABLE "order_items" ADD COLUMN IF NOT EXISTS "tax" float NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "paid_at" timestamp;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "sale_reported_at" timestamp;
+
+-- sales of orders paid before this migration were already reported
+UPDATE "orders" SET "paid_at" = "updated_at", "sale_reported_at" = "updated_at" WHERE "paid_amount" > 0;
@@ -0,0 +1 @@
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "estimated_shipping" boolean NOT NULL DEFAULT false;
@@ -0,0 +1,11 @@
+ALTER TYPE "order_status" ADD VALUE IF NOT EXISTS 'READY_FOR_PICKUP';
+ALTER TYPE "order_status" ADD VALUE IF NOT EXISTS 'PICKED_UP';
+
+CREATE TYPE "fulfilment_type" AS ENUM (
+  'DELIVERY',
+  'PICKUP'
+);
+
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "fulfilment_type" fulfilment_type NOT NULL DEFAULT 'DELIVERY';
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "pickup_warehouse_id" uuid;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS "pickup_code" varchar(10) NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TYPE "order_status" ADD VALUE IF NOT EXISTS 'FULFILLED';
+ALTER TYPE "fulfilment_type" ADD VALUE IF NOT EXISTS 'DIGITAL';
+
+ALTER TABLE "order_items" ADD COLUMN IF NOT EXISTS "product_type" varchar(20) NOT NULL DEFAULT 'PHYSICAL';
@@ -0,0 +1 @@
+CREATE INDEX ON "promotions" ("created_at" DESC, "id" DESC);
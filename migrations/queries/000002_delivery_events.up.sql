@@ -0,0 +1,2 @@
+ALTER TYPE "order_status" ADD VALUE IF NOT EXISTS 'DELIVERY_FAILED';
+ALTER TYPE "order_status" ADD VALUE IF NOT EXISTS 'RETURNING';
@@ -0,0 +1,36 @@
+CREATE TYPE "return_status" AS ENUM (
+  'REQUESTED',
+  'APPROVED',
+  'REJECTED'
+);
+
+CREATE TABLE IF NOT EXISTS "order_returns_view" (
+  "id" uuid PRIMARY KEY,
+  "return_id" uuid NOT NULL,
+  "order_id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "status" return_status NOT NULL,
+  "reason" text NOT NULL,
+  "admin_note" text,
+  "refund_amount" float NOT NULL,
+  "created_at" timestamp NOT NULL,
+  "updated_at" timestamp NOT NULL,
+  "deleted_at" timestamp
+);
+
+CREATE TABLE IF NOT EXISTS "order_return_items_view" (
+  "id" uuid PRIMARY KEY,
+  "return_view_id" uuid NOT NULL,
+  "product_id" uuid NOT NULL,
+  "product_quantity" integer NOT NULL,
+  "price" float NOT NULL,
+  "created_at" timestamp NOT NULL,
+  "updated_at" timestamp NOT NULL,
+  "deleted_at" timestamp
+);
+
+CREATE UNIQUE INDEX ON "order_returns_view" ("return_id");
+CREATE INDEX ON "order_returns_view" ("order_id");
+CREATE INDEX ON "order_return_items_view" ("return_view_id");
+
+ALTER TABLE "order_return_items_view" ADD FOREIGN KEY ("return_view_id") REFERENCES "order_returns_view" ("id");
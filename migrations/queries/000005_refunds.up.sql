@@ -0,0 +1,34 @@
+CREATE TYPE "refund_status" AS ENUM (
+  'PENDING'
+);
+
+CREATE TABLE IF NOT EXISTS "refunds_view" (
+  "id" uuid PRIMARY KEY,
+  "refund_id" uuid NOT NULL,
+  "order_id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "return_id" uuid,
+  "amount" float NOT NULL,
+  "reason" text NOT NULL,
+  "status" refund_status NOT NULL,
+  "created_at" timestamp NOT NULL,
+  "updated_at" timestamp NOT NULL,
+  "deleted_at" timestamp
+);
+
+CREATE TABLE IF NOT EXISTS "refund_items_view" (
+  "id" uuid PRIMARY KEY,
+  "refund_view_id" uuid NOT NULL,
+  "product_id" uuid NOT NULL,
+  "product_quantity" integer NOT NULL,
+  "price" float NOT NULL,
+  "created_at" timestamp NOT NULL,
+  "updated_at" timestamp NOT NULL,
+  "deleted_at" timestamp
+);
+
+CREATE UNIQUE INDEX ON "refunds_view" ("refund_id");
+CREATE INDEX ON "refunds_view" ("order_id");
+CREATE INDEX ON "refund_items_view" ("refund_view_id");
+
+ALTER TABLE "refund_items_view" ADD FOREIGN KEY ("refund_view_id") REFERENCES "refunds_view" ("id");
@@ -0,0 +1,3 @@
+ALTER TABLE "orders_view" ADD COLUMN IF NOT EXISTS "promotion_code" varchar;
+ALTER TABLE "orders_view" ADD COLUMN IF NOT EXISTS "discount_amount" float NOT NULL DEFAULT 0;
+ALTER TABLE "order_items_view" ADD COLUMN IF NOT EXISTS "discount" float NOT NULL DEFAULT 0;
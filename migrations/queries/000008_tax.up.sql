@@ -0,0 +1,2 @@
+ALTER TABLE "orders_view" ADD COLUMN IF NOT EXISTS "tax_amount" float NOT NULL DEFAULT 0;
+ALTER TABLE "order_items_view" ADD COLUMN IF NOT EXISTS "tax" float NOT NULL DEFAULT 0;
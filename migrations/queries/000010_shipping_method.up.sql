@@ -0,0 +1,2 @@
+ALTER TABLE "orders_view" ADD COLUMN IF NOT EXISTS "shipping_method" varchar(20) NOT NULL DEFAULT 'STANDARD';
+ALTER TABLE "orders_view" ADD COLUMN IF NOT EXISTS "shipping_estimated_days" int NOT NULL DEFAULT 0;
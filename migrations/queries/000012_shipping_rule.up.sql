@@ -0,0 +1 @@
+ALTER TABLE "orders_view" ADD COLUMN IF NOT EXISTS "shipping_rule" varchar(100) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TYPE "order_status" ADD VALUE IF NOT EXISTS 'FULFILLED';
+ALTER TYPE "fulfilment_type" ADD VALUE IF NOT EXISTS 'DIGITAL';
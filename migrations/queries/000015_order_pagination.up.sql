@@ -0,0 +1,3 @@
+CREATE INDEX ON "orders_view" ("created_at" DESC, "id" DESC);
+CREATE INDEX ON "orders_view" ("user_id", "created_at" DESC, "id" DESC);
+CREATE INDEX ON "orders_view" ("status", "created_at" DESC, "id" DESC);
@@ -0,0 +1,5 @@
+CREATE INDEX ON "orders_view" ("updated_at" DESC, "id" DESC);
+CREATE INDEX ON "orders_view" ("total_price" DESC, "id" DESC);
+CREATE INDEX ON "order_items_view" ("product_id");
+CREATE INDEX ON "order_addresses_view" (lower("state"));
+CREATE INDEX ON "order_addresses_view" (lower("city"));
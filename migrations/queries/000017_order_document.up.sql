@@ -0,0 +1,71 @@
+ALTER TABLE "orders_view" ADD COLUMN IF NOT EXISTS "document" jsonb NOT NULL DEFAULT '{}';
+
+-- documents of the existing orders, later kept up to date by the projection
+UPDATE orders_view ov SET document = jsonb_build_object(
+  'id', ov.id,
+  'order_id', ov.order_id,
+  'user_id', ov.user_id,
+  'status', ov.status,
+  'total_price', ov.total_price,
+  'promotion_code', COALESCE(ov.promotion_code, ''),
+  'discount_amount', ov.discount_amount,
+  'tax_amount', ov.tax_amount,
+  'payment', jsonb_build_object(
+    'id', ov.payment_id,
+    'status', COALESCE(ov.payment_status::text, ''),
+    'image_url', COALESCE(ov.payment_image_url, ''),
+    'admin_note', COALESCE(ov.payment_admin_note, '')
+  ),
+  'shipment', jsonb_build_object(
+    'method', ov.shipping_method,
+    'estimated_days', ov.shipping_estimated_days,
+    'estimated', ov.estimated_shipping,
+    'rule', ov.shipping_rule,
+    'fulfilment_type', ov.fulfilment_type,
+    'pickup_warehouse_id', ov.pickup_warehouse_id,
+    'pickup_code', ov.pickup_code
+  ),
+  'address', COALESCE((
+    SELECT jsonb_build_object(
+      'id', oa.id,
+      'street', oa.street,
+      'city', oa.city,
+      'state', oa.state,
+      'zip_code', oa.zip_code,
+      'note', COALESCE(oa.note, '')
+    )
+    FROM order_addresses_view oa
+    WHERE oa.order_view_id = ov.id
+    LIMIT 1
+  ), '{}'::jsonb),
+  'items', COALESCE((
+    SELECT jsonb_agg(jsonb_build_object(
+      'id', oi.id,
+      'product_id', oi.product_id,
+      'product_name', oi.product_name,
+      'product_price', oi.product_price::float,
+      'product_quantity', oi.product_quantity,
+      'product_image_url', COALESCE(oi.product_image_url, ''),
+      'product_description', oi.product_description,
+      'product_category_id', oi.product_category_id,
+      'product_category_name', oi.product_category_name,
+      'shipping_cost', oi.shipping_cost,
+      'discount', oi.discount,
+      'tax', oi.tax,
+      'note', COALESCE(oi.note, '')
+    ) ORDER BY oi.created_at, oi.id)
+    FROM order_items_view oi
+    WHERE oi.order_view_id = ov.id AND oi.deleted_at IS NULL
+  ), '[]'::jsonb),
+  'timeline', jsonb_build_object(
+    'created_at', ov.created_at AT TIME ZONE 'UTC',
+    'updated_at', ov.updated_at AT TIME ZONE 'UTC',
+    'return_count', (SELECT count(*) FROM order_returns_view r WHERE r.order_id = ov.order_id AND r.deleted_at IS NULL),
+    'refund_count', (SELECT count(*) FROM refunds_view f WHERE f.order_id = ov.order_id AND f.deleted_at IS NULL),
+    'refunded_amount', (SELECT COALESCE(sum(f.amount), 0) FROM refunds_view f WHERE f.order_id = ov.order_id AND f.deleted_at IS NULL)
+  )
+);
+
+CREATE INDEX ON "orders_view" USING GIN ("document" jsonb_path_ops);
+CREATE INDEX ON "orders_view" (lower("document"->'address'->>'state'));
+CREATE INDEX ON "orders_view" (lower("document"->'address'->>'city'));
@@ -0,0 +1,15 @@
+ALTER TABLE "orders_view" ADD COLUMN IF NOT EXISTS "search" tsvector NOT NULL DEFAULT '';
+
+-- search vectors of the existing orders, later kept up to date by the projection
+UPDATE orders_view ov SET search =
+  setweight(to_tsvector('simple', ov.order_id::text), 'A') ||
+  setweight(to_tsvector('simple', COALESCE((SELECT string_agg(i->>'product_name', ' ') FROM jsonb_array_elements(ov.document->'items') i), '')), 'A') ||
+  setweight(to_tsvector('simple', concat_ws(' ',
+    ov.document->'address'->>'street',
+    ov.document->'address'->>'city',
+    ov.document->'address'->>'state',
+    ov.document->'address'->>'zip_code'
+  )), 'B') ||
+  setweight(to_tsvector('simple', COALESCE((SELECT string_agg(i->>'product_description', ' ') FROM jsonb_array_elements(ov.document->'items') i), '')), 'C');
+
+CREATE INDEX ON "orders_view" USING GIN ("search");
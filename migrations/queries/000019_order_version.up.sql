@@ -0,0 +1,3 @@
+ALTER TABLE "orders_view" ADD COLUMN IF NOT EXISTS "version" integer NOT NULL DEFAULT 1;
+
+UPDATE orders_view SET document = document || jsonb_build_object('version', version);
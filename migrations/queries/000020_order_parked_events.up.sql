@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS "order_parked_events_view" (
+  "id" uuid PRIMARY KEY,
+  "order_id" uuid NOT NULL,
+  "kind" varchar(20) NOT NULL,
+  "version" integer NOT NULL,
+  "payload" jsonb NOT NULL,
+  "created_at" timestamp NOT NULL
+);
+
+CREATE INDEX ON "order_parked_events_view" ("order_id", "version", "created_at");
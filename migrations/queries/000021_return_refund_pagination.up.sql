@@ -0,0 +1,2 @@
+CREATE INDEX ON "order_returns_view" ("order_id", "created_at" DESC, "id" DESC);
+CREATE INDEX ON "refunds_view" ("order_id", "created_at" DESC, "id" DESC);
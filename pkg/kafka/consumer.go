@@ -51,6 +51,16 @@ func NewKafkaConsumer(kafkaCfg config.Kafka) (*ConsumerServer, error) {
 		constant.OrderCreatedTopic,
 		constant.PaymentUpdatedTopic,
 		constant.OrderStatusUpdatedTopic,
+		kafkaCfg.DeliveryTopic,
+		constant.ReturnRequestedTopic,
+		constant.ReturnApprovedTopic,
+		constant.ReturnRejectedTopic,
+		constant.RefundCreatedTopic,
+		constant.OrderItemsCancelledTopic,
+		constant.OrderUpdatedTopic,
+		constant.OrderCreatedRepairTopic,
+		constant.OrderStatusUpdatedRepairTopic,
+		constant.OrderUpdatedRepairTopic,
 	}
 
 	log.Printf("attempting to subscribe to topics: %v", topics)
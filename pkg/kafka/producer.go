@@ -1,15 +1,18 @@
 package kafka
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/idoyudha/eshop-order/config"
 )
 
 type ProducerServer struct {
-	Producer *kafka.Producer
+	Producer        *kafka.Producer
+	deliveryTimeout time.Duration
 }
 
 func NewKafkaProducer(kafkaCfg config.Kafka) (*ProducerServer, error) {
@@ -37,7 +40,8 @@ func NewKafkaProducer(kafkaCfg config.Kafka) (*ProducerServer, error) {
 	}()
 
 	return &ProducerServer{
-		Producer: p,
+		Producer:        p,
+		deliveryTimeout: time.Duration(kafkaCfg.DeliveryTimeoutMs) * time.Millisecond,
 	}, nil
 }
 
@@ -56,3 +60,43 @@ func (s *ProducerServer) Publish(topic string, key []byte, message interface{})
 		Value:          messageBytes,
 	}, nil)
 }
+
+// PublishSync wait until the broker acknowledges the message, use it when the event must not be lost.
+// the wait ends with the context or the delivery timeout, the message may still be delivered after it
+func (s *ProducerServer) PublishSync(ctx context.Context, topic string, key []byte, message interface{}) error {
+	messageBytes, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal kafka message: %w", err)
+	}
+
+	deliveryChan := make(chan kafka.Event, 1)
+	err = s.Producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            key,
+		Value:          messageBytes,
+	}, deliveryChan)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.deliveryTimeout)
+	defer cancel()
+
+	// buffered, the delivery report of an abandoned wait does not block the producer
+	var e kafka.Event
+	select {
+	case e = <-deliveryChan:
+	case <-ctx.Done():
+		return fmt.Errorf("kafka message not acknowledged: %w", ctx.Err())
+	}
+
+	msg, ok := e.(*kafka.Message)
+	if !ok {
+		return fmt.Errorf("unexpected kafka delivery event: %v", e)
+	}
+	if msg.TopicPartition.Error != nil {
+		return fmt.Errorf("failed to deliver kafka message: %w", msg.TopicPartition.Error)
+	}
+
+	return nil
+}